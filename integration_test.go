@@ -0,0 +1,786 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/cache"
+	"github.com/tobey0x/lagbaja/internal/config"
+	"github.com/tobey0x/lagbaja/internal/handler"
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
+	"github.com/tobey0x/lagbaja/internal/review"
+	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// Scripted model replies for the fixture PDFs
+const (
+	photosynthesisDeck = `{"flashcards": [
+  {"question": "What is photosynthesis?", "answer": "The process by which green plants convert light energy into chemical energy", "topic": "Photosynthesis", "evidence": "Photosynthesis is the process by which green plants convert light energy into chemical energy.", "sourcePages": [1]},
+  {"question": "Where does photosynthesis take place?", "answer": "In the chloroplasts", "topic": "Plant Cells", "evidence": "It takes place in the chloroplasts", "sourcePages": [1]},
+  {"question": "What are the products of photosynthesis?", "answer": "Glucose and oxygen", "topic": "Photosynthesis", "evidence": "turns carbon dioxide and water into glucose and oxygen", "sourcePages": [1]}
+]}`
+
+	mixedDeck = `{"flashcards": [
+  {"type": "cloze", "cloze": "Photosynthesis takes place in the {{c1::chloroplasts}}.", "question": "", "answer": "", "topic": "Plant Cells", "evidence": "It takes place in the chloroplasts", "sourcePages": [1]},
+  {"type": "mcq", "question": "Which gas does photosynthesis release?", "options": ["Carbon dioxide", "Oxygen", "Nitrogen"], "correctIndex": 1, "rationales": ["It is taken in, not released", "Correct", "Plants don't produce nitrogen"], "answer": "", "topic": "Photosynthesis", "evidence": "turns carbon dioxide and water into glucose and oxygen", "sourcePages": [1]},
+  {"type": "true_false", "question": "Photosynthesis produces glucose.", "isTrue": true, "explanation": "Glucose stores the captured energy.", "answer": "", "topic": "Photosynthesis", "evidence": "into glucose and oxygen", "sourcePages": [1]},
+  {"type": "reverse", "question": "Chlorophyll", "answer": "The green pigment that absorbs light", "topic": "Plant Cells", "evidence": "the chloroplasts, which contain the pigment chlorophyll", "sourcePages": [1]}
+]}`
+
+	combinedDeck = `{"flashcards": [
+  {"question": "What is photosynthesis?", "answer": "The conversion of light energy into chemical energy", "topic": "Photosynthesis", "source": "photosynthesis.pdf", "evidence": "convert light energy into chemical energy", "sourcePages": [1]},
+  {"question": "What does the mitochondrion produce?", "answer": "ATP", "topic": "Cellular Respiration", "source": "cell_biology.pdf", "evidence": "It produces ATP, the main energy currency of the cell.", "sourcePages": [1]}
+]}`
+
+	// citedDeck cites the second page of cell_biology.pdf under the wrong
+	// page number and quotes one passage that isn't in the PDF at all.
+	citedDeck = `{"flashcards": [
+  {"question": "What does the mitochondrion produce?", "answer": "ATP", "topic": "Cellular Respiration", "evidence": "It produces ATP, the main energy currency of the cell.", "sourcePages": [1]},
+  {"question": "Where is DNA stored?", "answer": "In the nucleus", "topic": "Genetics", "evidence": "The nucleus stores the genetic material ... as DNA", "sourcePages": [1]},
+  {"question": "What do lysosomes do?", "answer": "Break down waste", "topic": "Organelles", "evidence": "Lysosomes digest worn-out organelles.", "sourcePages": [2]}
+]}`
+)
+
+// testServer serves the full router with a FakeProvider in place of a
+// real model.
+type testServer struct {
+	*httptest.Server
+	provider *service.FakeProvider
+	handler  *handler.A2AHandler
+}
+
+func newTestServer(t *testing.T, responses ...service.FakeResponse) *testServer {
+	t.Helper()
+
+	cfg := &config.Config{
+		AgentName:        "Lagbaja Test",
+		AgentDescription: "Test agent",
+		AgentURL:         "http://localhost/a2a",
+		AgentVersion:     "0.0.0",
+	}
+	provider := service.NewFakeProvider(responses...)
+	// Fixture PDFs are served from loopback, which downloads otherwise refuse
+	pdfService := service.NewPDFService()
+	pdfService.AllowedNetworks, _ = netguard.ParseNetworks("127.0.0.1")
+	flashcardService := service.NewFlashcardService(pdfService, provider)
+	flashcardService.Cache = cache.NewLRU(100, time.Hour)
+	decks := store.NewMemoryDeckStore()
+	reviews := review.NewService(decks, store.NewMemoryReviewStore(), review.NewFSRS())
+	reviews.Marker = flashcardService
+	a2aHandler := handler.NewA2AHandler(flashcardService, handler.WithDeckStore(decks), handler.WithReviewService(reviews))
+
+	server := httptest.NewServer(newRouter(cfg, flashcardService, a2aHandler, decks, reviews))
+	t.Cleanup(func() {
+		server.Close()
+		a2aHandler.Shutdown(context.Background())
+	})
+	return &testServer{Server: server, provider: provider, handler: a2aHandler}
+}
+
+// rpc posts a JSON-RPC request to /a2a and returns the raw response body.
+func (s *testServer) rpc(t *testing.T, method string, params interface{}) []byte {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "req-001",
+		"method":  method,
+		"params":  params,
+	})
+	resp, err := http.Post(s.URL+"/a2a", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /a2a failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return data
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func userMessage(parts ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"kind":      "message",
+		"role":      "user",
+		"messageId": "msg-001",
+		"taskId":    "task-001",
+		"contextId": "ctx-001",
+		"parts":     parts,
+	}
+}
+
+func filePart(t *testing.T, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"kind": "file",
+		"file": map[string]interface{}{
+			"name":     name,
+			"mimeType": "application/pdf",
+			"bytes":    base64.StdEncoding.EncodeToString(readFixture(t, name)),
+		},
+	}
+}
+
+func textPart(text string) map[string]interface{} {
+	return map[string]interface{}{"kind": "text", "text": text}
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// normalize replaces generated IDs and timestamps so responses can be
+// compared byte for byte.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := field.(string); ok && (key == "timestamp" || key == "createdAt" || key == "updatedAt") {
+				v[key] = "<" + key + ">"
+				continue
+			}
+			v[key] = normalize(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	case string:
+		return uuidPattern.ReplaceAllString(v, "<uuid>")
+	}
+	return value
+}
+
+// assertGolden compares the normalized JSON in got with
+// testdata/golden/<name>.json.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	var value interface{}
+	if err := json.Unmarshal(got, &value); err != nil {
+		t.Fatalf("Response is not JSON: %v\n%s", err, got)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(normalize(value)); err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	normalized := buf.Bytes()
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(normalized, want) {
+		t.Errorf("Response does not match %s\ngot:\n%s\nwant:\n%s", path, normalized, want)
+	}
+}
+
+func TestIntegration_MessageSend_FilePart(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(textPart("Make flashcards from this"), filePart(t, "photosynthesis.pdf")),
+	})
+	assertGolden(t, "message_send_file_part", resp)
+
+	requests := server.provider.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 model request, got %d", len(requests))
+	}
+	if !strings.Contains(requests[0].Prompt, "chloroplasts, which contain the pigment chlorophyll") {
+		t.Errorf("Expected the extracted PDF text in the prompt, got:\n%s", requests[0].Prompt)
+	}
+}
+
+func TestIntegration_MessageSend_MultipleFiles(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: combinedDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(
+			textPart("One deck from both"),
+			filePart(t, "photosynthesis.pdf"),
+			filePart(t, "cell_biology.pdf"),
+		),
+		"configuration": map[string]interface{}{"acceptedOutputModes": []string{"application/json"}},
+	})
+	assertGolden(t, "message_send_multiple_files", resp)
+
+	prompt := server.provider.Requests()[0].Prompt
+	for _, want := range []string{"=== Document: photosynthesis.pdf ===", "=== Document: cell_biology.pdf ===", "Ribosomes translate"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt", want)
+		}
+	}
+}
+
+func TestIntegration_MessageSend_PDFURL(t *testing.T) {
+	pdf := readFixture(t, "photosynthesis.pdf")
+	pdfServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf)
+	}))
+	defer pdfServer.Close()
+
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(textPart("Generate flashcards from " + pdfServer.URL + "/photosynthesis.pdf")),
+		"configuration": map[string]interface{}{
+			"acceptedOutputModes": []string{"text/markdown"},
+		},
+	})
+
+	// The fixture server's port changes on every run
+	resp = bytes.ReplaceAll(resp, []byte(pdfServer.URL), []byte("http://fixtures"))
+	assertGolden(t, "message_send_pdf_url", resp)
+}
+
+func TestIntegration_MessageSend_PrivatePDFURL(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(textPart("Generate flashcards from http://169.254.169.254/latest/meta-data/notes.pdf")),
+	})
+
+	if !bytes.Contains(resp, []byte("private network address")) {
+		t.Errorf("Expected the private address to be refused, got %s", resp)
+	}
+	if len(server.provider.Requests()) != 0 {
+		t.Error("Expected no model request for a refused URL")
+	}
+}
+
+func TestIntegration_MessageSend_ModelError(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Err: errors.New("quota exceeded")})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(textPart("Photosynthesis converts light energy into chemical energy.")),
+	})
+	assertGolden(t, "message_send_model_error", resp)
+}
+
+func TestIntegration_MessageSend_InvalidFile(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(map[string]interface{}{
+			"kind": "file",
+			"file": map[string]interface{}{
+				"name":  "notes.pdf",
+				"bytes": base64.StdEncoding.EncodeToString([]byte("plain text, not a PDF")),
+			},
+		}),
+	})
+	assertGolden(t, "message_send_invalid_file", resp)
+
+	if len(server.provider.Requests()) != 0 {
+		t.Error("Expected no model request for an invalid file")
+	}
+}
+
+func TestIntegration_MessageSend_NonBlocking(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck, Latency: 50 * time.Millisecond})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message":       userMessage(filePart(t, "photosynthesis.pdf")),
+		"configuration": map[string]interface{}{"blocking": false},
+	})
+	assertGolden(t, "message_send_non_blocking", resp)
+
+	if err := server.handler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to drain workers: %v", err)
+	}
+
+	resp = server.rpc(t, "tasks/get", map[string]interface{}{"id": "task-001", "historyLength": 1})
+	assertGolden(t, "tasks_get_completed", resp)
+}
+
+func TestIntegration_MessageSend_GenerationOptions(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	msg := userMessage(filePart(t, "photosynthesis.pdf"))
+	msg["metadata"] = map[string]interface{}{
+		"generationOptions": map[string]interface{}{
+			"cardCount":     2,
+			"difficulty":    "Hard",
+			"excludeTopics": []string{"Plant Cells"},
+		},
+	}
+	// A data part adds to the metadata options
+	msg["parts"] = append(msg["parts"].([]map[string]interface{}), map[string]interface{}{
+		"kind": "data",
+		"data": map[string]interface{}{
+			"generationOptions": map[string]interface{}{"focusTopics": []string{"chlorophyll"}},
+		},
+	})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{"message": msg})
+	assertGolden(t, "message_send_generation_options", resp)
+
+	prompt := server.provider.Requests()[0].Prompt
+	for _, want := range []string{"exactly 2 high-quality flashcards", "Difficulty: hard", "Leave out anything about these topics: Plant Cells"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestIntegration_MessageSend_CardTypes(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: mixedDeck})
+
+	msg := userMessage(filePart(t, "photosynthesis.pdf"))
+	msg["metadata"] = map[string]interface{}{
+		"generationOptions": map[string]interface{}{
+			"cardTypes": []string{"cloze", "mcq", "true_false", "reverse"},
+		},
+	}
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{"message": msg})
+	assertGolden(t, "message_send_card_types", resp)
+}
+
+func TestIntegration_MessageSend_Citations(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: citedDeck})
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{
+		"message": userMessage(filePart(t, "cell_biology.pdf")),
+		"configuration": map[string]interface{}{
+			"acceptedOutputModes": []string{"text/markdown", "application/json"},
+		},
+	})
+	assertGolden(t, "message_send_citations", resp)
+
+	prompt := server.provider.Requests()[0].Prompt
+	for _, want := range []string{"[Page 1]\n\nThe mitochondrion", "[Page 2]\n\nThe nucleus"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestIntegration_MessageSend_InvalidGenerationOptions(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	msg := userMessage(textPart("Photosynthesis turns light into chemical energy"))
+	msg["metadata"] = map[string]interface{}{
+		"generationOptions": map[string]interface{}{"difficulty": "impossible"},
+	}
+
+	resp := server.rpc(t, "message/send", map[string]interface{}{"message": msg})
+	assertGolden(t, "message_send_invalid_generation_options", resp)
+	if len(server.provider.Requests()) != 0 {
+		t.Error("Expected no model request for invalid options")
+	}
+}
+
+func TestIntegration_Upload(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("pdf", "photosynthesis.pdf")
+	part.Write(readFixture(t, "photosynthesis.pdf"))
+	writer.Close()
+
+	resp, err := http.Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST /upload failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	assertGolden(t, "upload", data)
+}
+
+func TestIntegration_ExportAPKG(t *testing.T) {
+	server := newTestServer(t)
+
+	set := `{"title": "Cell Biology", "flashcards": [{"question": "What does the mitochondrion produce?", "answer": "ATP", "topic": "Cellular Respiration"}]}`
+	resp, err := http.Post(server.URL+"/export/apkg", "application/json", strings.NewReader(set))
+	if err != nil {
+		t.Fatalf("POST /export/apkg failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/apkg" {
+		t.Errorf("Expected Content-Type %q, got %q", "application/apkg", got)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="cell-biology.apkg"` {
+		t.Errorf("Expected the deck filename, got %q", got)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected a zip package, got: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	if strings.Join(names, ",") != "collection.anki2,media" {
+		t.Errorf("Expected collection.anki2 and media, got %v", names)
+	}
+
+	resp, err = http.Post(server.URL+"/export/apkg", "application/json", strings.NewReader(`{"flashcards": []}`))
+	if err != nil {
+		t.Fatalf("POST /export/apkg failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an empty set, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestIntegration_Upload_NotAPDF(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("pdf", "notes.txt")
+	part.Write([]byte("plain text, not a PDF"))
+	writer.Close()
+
+	resp, err := http.Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST /upload failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("Expected an error status for a non-PDF upload")
+	}
+	if len(server.provider.Requests()) != 0 {
+		t.Error("Expected no model request for an invalid upload")
+	}
+}
+
+func TestIntegration_Upload_GenerationOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     map[string]string
+		wantStatus int
+	}{
+		{"Valid", map[string]string{"cardCount": "1", "language": "French", "focusTopics": "light, glucose"}, http.StatusOK},
+		{"Bad card count", map[string]string{"cardCount": "many"}, http.StatusBadRequest},
+		{"Bad difficulty", map[string]string{"difficulty": "impossible"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			for key, value := range tt.fields {
+				writer.WriteField(key, value)
+			}
+			part, _ := writer.CreateFormFile("pdf", "photosynthesis.pdf")
+			part.Write(readFixture(t, "photosynthesis.pdf"))
+			writer.Close()
+
+			resp, err := http.Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+			if err != nil {
+				t.Fatalf("POST /upload failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(server.provider.Requests()) != 0 {
+					t.Error("Expected no model request for invalid options")
+				}
+				return
+			}
+
+			var set models.FlashcardSet
+			if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if set.TotalCards != 1 {
+				t.Errorf("Expected 1 card, got %d", set.TotalCards)
+			}
+			if set.Options == nil || strings.Join(set.Options.FocusTopics, "|") != "light|glucose" {
+				t.Errorf("Expected the focus topics on the set, got %+v", set.Options)
+			}
+			if len(set.UnmetConstraints) != 1 || !strings.Contains(set.UnmetConstraints[0], "glucose") {
+				t.Errorf("Expected the uncovered focus topic to be reported, got %v", set.UnmetConstraints)
+			}
+			if !strings.Contains(server.provider.Requests()[0].Prompt, "in French") {
+				t.Error("Expected the language in the prompt")
+			}
+		})
+	}
+}
+
+func TestIntegration_Upload_Cache(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	upload := func(fields map[string]string) (*http.Response, models.FlashcardSet) {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for key, value := range fields {
+			writer.WriteField(key, value)
+		}
+		part, _ := writer.CreateFormFile("pdf", "photosynthesis.pdf")
+		part.Write(readFixture(t, "photosynthesis.pdf"))
+		writer.Close()
+
+		resp, err := http.Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatalf("POST /upload failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var set models.FlashcardSet
+		if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp, set
+	}
+
+	steps := []struct {
+		name     string
+		fields   map[string]string
+		want     string
+		requests int
+	}{
+		{"First upload", nil, "MISS", 1},
+		{"Same PDF", nil, "HIT", 1},
+		{"No cache", map[string]string{"noCache": "true"}, "MISS", 2},
+		{"Different options", map[string]string{"cardCount": "2"}, "MISS", 3},
+	}
+
+	var deckIDs []string
+	for _, step := range steps {
+		resp, set := upload(step.fields)
+		if got := resp.Header.Get("X-Cache"); got != step.want {
+			t.Errorf("%s: Expected X-Cache %q, got %q", step.name, step.want, got)
+		}
+		if set.Cached != (step.want == "HIT") {
+			t.Errorf("%s: Expected cached %v, got %v", step.name, step.want == "HIT", set.Cached)
+		}
+		if got := len(server.provider.Requests()); got != step.requests {
+			t.Errorf("%s: Expected %d model requests, got %d", step.name, step.requests, got)
+		}
+		deckIDs = append(deckIDs, set.DeckID)
+	}
+
+	// A cached set is still saved as a deck of its own
+	if deckIDs[0] == "" || deckIDs[0] == deckIDs[1] {
+		t.Errorf("Expected each upload to be saved as a new deck, got %v", deckIDs)
+	}
+}
+
+func TestIntegration_Upload_Formats(t *testing.T) {
+	tests := []struct {
+		name            string
+		fields          map[string]string
+		wantStatus      int
+		wantContentType string
+		wantFilename    string
+		wantPrefix      string
+	}{
+		{"CSV", map[string]string{"format": "csv"}, http.StatusOK, "text/csv; charset=utf-8", "study-flashcards.csv",
+			"question,answer,topic,type,source,pages,evidence\r\nWhat is photosynthesis?,The process by which green plants convert light energy into chemical energy,Photosynthesis,basic,,1,"},
+		{"Anki TSV", map[string]string{"format": "TSV"}, http.StatusOK, "text/tab-separated-values; charset=utf-8", "study-flashcards.tsv",
+			"#separator:tab\n#html:true\n#deck:Study Flashcards\n#notetype column:1\n#tags column:4\nBasic\tWhat is photosynthesis?\t"},
+		{"Quizlet", map[string]string{"format": "quizlet", "termSeparator": "comma", "cardSeparator": ";"}, http.StatusOK, "text/plain; charset=utf-8", "study-flashcards.txt",
+			"What is photosynthesis?,The process by which green plants convert light energy into chemical energy;Where does photosynthesis take place?,In the chloroplasts;"},
+		{"JSON Lines", map[string]string{"format": "jsonl"}, http.StatusOK, "application/jsonl; charset=utf-8", "study-flashcards.jsonl",
+			`{"id":"`},
+		{"Unknown format", map[string]string{"format": "docx"}, http.StatusBadRequest, "", "", "invalid format \"docx\""},
+		{"Same separators", map[string]string{"format": "quizlet", "termSeparator": ";", "cardSeparator": ";"}, http.StatusBadRequest, "", "", "termSeparator and cardSeparator must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			for key, value := range tt.fields {
+				writer.WriteField(key, value)
+			}
+			part, _ := writer.CreateFormFile("pdf", "photosynthesis.pdf")
+			part.Write(readFixture(t, "photosynthesis.pdf"))
+			writer.Close()
+
+			resp, err := http.Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+			if err != nil {
+				t.Fatalf("POST /upload failed: %v", err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, data)
+			}
+			if !strings.HasPrefix(string(data), tt.wantPrefix) {
+				t.Errorf("Expected body starting with %q, got %q", tt.wantPrefix, data)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(server.provider.Requests()) != 0 {
+					t.Error("Expected no model request for an invalid format")
+				}
+				return
+			}
+
+			if got := resp.Header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.wantContentType, got)
+			}
+			if got := resp.Header.Get("Content-Disposition"); got != fmt.Sprintf("attachment; filename=%q", tt.wantFilename) {
+				t.Errorf("Expected filename %q, got %q", tt.wantFilename, got)
+			}
+		})
+	}
+}
+
+func TestIntegration_Decks(t *testing.T) {
+	server := newTestServer(t, service.FakeResponse{Text: photosynthesisDeck})
+
+	request := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set(handler.OwnerHeader, "alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Uploads are saved to the uploader's library
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("pdf", "photosynthesis.pdf")
+	part.Write(readFixture(t, "photosynthesis.pdf"))
+	writer.Close()
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/upload?format=csv", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set(handler.OwnerHeader, "alice")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /upload failed: %v", err)
+	}
+	resp.Body.Close()
+	deckID := resp.Header.Get(deckIDHeader)
+	if resp.StatusCode != http.StatusOK || deckID == "" {
+		t.Fatalf("Expected the upload to be saved, got status %d and deck %q", resp.StatusCode, deckID)
+	}
+
+	var summaries []models.DeckSummary
+	json.NewDecoder(request(http.MethodGet, "/decks", "").Body).Decode(&summaries)
+	if len(summaries) != 1 || summaries[0].ID != deckID || summaries[0].TotalCards != 3 {
+		t.Fatalf("Expected the uploaded deck in the library, got %+v", summaries)
+	}
+
+	var deck models.Deck
+	json.NewDecoder(request(http.MethodGet, "/decks/"+deckID, "").Body).Decode(&deck)
+	cardID := deck.Set.Flashcards[1].ID
+	if deck.Set.DeckID != deckID || cardID == "" {
+		t.Fatalf("Expected the deck with card IDs, got %+v", deck)
+	}
+
+	resp = request(http.MethodPatch, "/decks/"+deckID+"/cards/"+cardID, `{"answer": "Chloroplasts"}`)
+	json.NewDecoder(resp.Body).Decode(&deck)
+	if resp.StatusCode != http.StatusOK || deck.Set.Flashcards[1].Answer != "Chloroplasts" {
+		t.Errorf("Expected the patched card, got %d %+v", resp.StatusCode, deck.Set.Flashcards[1])
+	}
+
+	var duplicate models.Deck
+	resp = request(http.MethodPost, "/decks/"+deckID+"/duplicate", "")
+	json.NewDecoder(resp.Body).Decode(&duplicate)
+	if resp.StatusCode != http.StatusCreated || duplicate.Set.Flashcards[1].Answer != "Chloroplasts" {
+		t.Errorf("Expected a copy of the patched deck, got %d %+v", resp.StatusCode, duplicate.Set)
+	}
+
+	// Cards in the library are queued for review
+	var queue models.ReviewQueue
+	json.NewDecoder(request(http.MethodGet, "/review/next?deckId="+deckID, "").Body).Decode(&queue)
+	if queue.New != 3 || len(queue.Cards) != 3 || queue.Cards[1].Card.ID != cardID {
+		t.Errorf("Expected the deck's three new cards, got %+v", queue)
+	}
+
+	var state models.ReviewState
+	resp = request(http.MethodPost, "/review/"+cardID, `{"grade": 3}`)
+	json.NewDecoder(resp.Body).Decode(&state)
+	if resp.StatusCode != http.StatusOK || state.DeckID != deckID || state.Reps != 1 || !state.Due.After(state.LastReview) {
+		t.Errorf("Expected the card to be scheduled, got %d %+v", resp.StatusCode, state)
+	}
+	json.NewDecoder(request(http.MethodGet, "/review/next?deckId="+deckID, "").Body).Decode(&queue)
+	if queue.New != 2 || queue.Due != 0 {
+		t.Errorf("Expected the reviewed card to leave the queue, got %+v", queue)
+	}
+
+	// An answer matching the patched card is marked without the model
+	var result models.AnswerResult
+	calls := len(server.provider.Requests())
+	resp = request(http.MethodPost, "/review/"+cardID+"/answer", `{"answer": "chloroplasts"}`)
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK || result.Method != models.MarkedExact || result.Grade != 3 || result.State.Reps != 2 {
+		t.Errorf("Expected an exact answer graded Good, got %d %+v", resp.StatusCode, result)
+	}
+	if len(server.provider.Requests()) != calls {
+		t.Error("Expected no model call for an exact answer")
+	}
+
+	if resp := request(http.MethodDelete, "/decks/"+deckID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	json.NewDecoder(request(http.MethodGet, "/decks", "").Body).Decode(&summaries)
+	if len(summaries) != 1 || summaries[0].ID != duplicate.ID {
+		t.Errorf("Expected only the copy to be left, got %+v", summaries)
+	}
+}
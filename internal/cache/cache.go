@@ -0,0 +1,89 @@
+// Package cache stores the results of expensive work, such as text
+// extraction and flashcard generation, under content-addressed keys.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Cache maps keys to values. Implementations are safe for concurrent use.
+// A cache that fails to store or load a value treats it as a miss rather
+// than an error, since the work can always be redone.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// New builds the cache tiers that are enabled: an in-memory LRU of size
+// entries when size is positive, then a disk cache in dir when dir is set.
+// Entries expire after ttl, or never if ttl is zero. It returns nil when
+// neither tier is enabled.
+func New(size int, dir string, ttl time.Duration) (Cache, error) {
+	var tiers []Cache
+	if size > 0 {
+		tiers = append(tiers, NewLRU(size, ttl))
+	}
+	if dir != "" {
+		disk, err := NewDisk(dir, ttl)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, disk)
+	}
+
+	switch len(tiers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return tiers[0], nil
+	default:
+		return NewTiered(tiers...), nil
+	}
+}
+
+// Key hashes parts into a key, separating them so that different splits
+// of the same bytes hash differently.
+func Key(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Hash is the hex SHA-256 of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Tiered looks keys up in each tier in turn, from fastest to slowest, and
+// copies a value found in a slower tier into the faster ones.
+type Tiered struct {
+	tiers []Cache
+}
+
+func NewTiered(tiers ...Cache) *Tiered {
+	return &Tiered{tiers: tiers}
+}
+
+func (t *Tiered) Get(key string) ([]byte, bool) {
+	for i, tier := range t.tiers {
+		if value, ok := tier.Get(key); ok {
+			for _, faster := range t.tiers[:i] {
+				faster.Set(key, value)
+			}
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (t *Tiered) Set(key string, value []byte) {
+	for _, tier := range t.tiers {
+		tier.Set(key, value)
+	}
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLRU_Evicts(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+
+	// Reading a makes b the least recently used
+	if value, ok := c.Get("a"); !ok || string(value) != "1" {
+		t.Errorf("Expected %q, got %q %v", "1", value, ok)
+	}
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+
+	c.Set("a", []byte("updated"))
+	if value, _ := c.Get("a"); string(value) != "updated" || c.Len() != 2 {
+		t.Errorf("Expected a to be replaced in place, got %q with %d entries", value, c.Len())
+	}
+}
+
+func TestLRU_Expires(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	c := NewLRU(10, time.Hour)
+	c.Now = func() time.Time { return now }
+	c.Set("a", []byte("1"))
+
+	now = now.Add(59 * time.Minute)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected the entry before its TTL")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected the entry to expire after its TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected the expired entry to be removed, got %d entries", c.Len())
+	}
+}
+
+func TestLRU_CopiesValues(t *testing.T) {
+	c := NewLRU(1, 0)
+	value := []byte("abc")
+	c.Set("a", value)
+	value[0] = 'x'
+
+	got, _ := c.Get("a")
+	got[1] = 'y'
+	if again, _ := c.Get("a"); string(again) != "abc" {
+		t.Errorf("Expected the cached value to be unchanged, got %q", again)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	key := Key("set", "abc")
+
+	d, err := NewDisk(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get(key); ok {
+		t.Error("Expected a miss on an empty cache")
+	}
+	d.Set(key, []byte(`{"title":"Biology"}`))
+
+	reopened, err := NewDisk(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := reopened.Get(key); !ok || string(value) != `{"title":"Biology"}` {
+		t.Errorf("Expected the entry after reopening, got %q %v", value, ok)
+	}
+
+	// Keys that could escape the directory are refused
+	for _, bad := range []string{"../../etc/passwd", "ab", "ABCDEF", "abc/def"} {
+		d.Set(bad, []byte("x"))
+		if _, ok := d.Get(bad); ok {
+			t.Errorf("Expected key %q to be refused", bad)
+		}
+	}
+}
+
+func TestDisk_Expires(t *testing.T) {
+	dir := t.TempDir()
+	d, _ := NewDisk(dir, time.Hour)
+	fresh, stale := Key("fresh"), Key("stale")
+	d.Set(fresh, []byte("1"))
+	d.Set(stale, []byte("2"))
+
+	old := time.Now().Add(-2 * time.Hour)
+	stalePath := filepath.Join(dir, stale[:2], stale)
+	if err := os.Chtimes(stalePath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.Get(stale); ok {
+		t.Error("Expected the stale entry to expire")
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale file to be removed, got %v", err)
+	}
+	if _, ok := d.Get(fresh); !ok {
+		t.Error("Expected the fresh entry to be kept")
+	}
+
+	// Opening the cache prunes expired entries
+	d.Set(stale, []byte("2"))
+	os.Chtimes(stalePath, old, old)
+	NewDisk(dir, time.Hour)
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale file to be pruned, got %v", err)
+	}
+}
+
+func TestTiered(t *testing.T) {
+	memory := NewLRU(10, 0)
+	disk, _ := NewDisk(t.TempDir(), 0)
+	c := NewTiered(memory, disk)
+	key := Key("set", "abc")
+
+	disk.Set(key, []byte("from disk"))
+	if value, ok := c.Get(key); !ok || string(value) != "from disk" {
+		t.Errorf("Expected the disk entry, got %q %v", value, ok)
+	}
+	if value, ok := memory.Get(key); !ok || string(value) != "from disk" {
+		t.Errorf("Expected the disk entry to be copied into memory, got %q %v", value, ok)
+	}
+
+	other := Key("other")
+	c.Set(other, []byte("both"))
+	if _, ok := disk.Get(other); !ok {
+		t.Error("Expected Set to write every tier")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		dir  string
+		want string
+	}{
+		{"Disabled", 0, "", "<nil>"},
+		{"Memory only", 10, "", "*cache.LRU"},
+		{"Disk only", 0, t.TempDir(), "*cache.Disk"},
+		{"Both", 10, t.TempDir(), "*cache.Tiered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.size, tt.dir, time.Hour)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got := typeName(c); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("ab", "c") == Key("a", "bc") {
+		t.Error("Expected the parts to be kept apart")
+	}
+	if Key("a") != Key("a") || len(Key("a")) != 64 {
+		t.Errorf("Expected a stable SHA-256 hex key, got %q", Key("a"))
+	}
+}
+
+func typeName(c Cache) string {
+	switch c.(type) {
+	case nil:
+		return "<nil>"
+	case *LRU:
+		return "*cache.LRU"
+	case *Disk:
+		return "*cache.Disk"
+	case *Tiered:
+		return "*cache.Tiered"
+	default:
+		return "unknown"
+	}
+}
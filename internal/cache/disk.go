@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Disk keeps entries as files in a directory, so they survive restarts. An
+// entry's age is its file's modification time. Expired entries are
+// removed when they are looked up and when the cache is opened.
+type Disk struct {
+	dir string
+	ttl time.Duration
+
+	// Now returns the current time; tests replace it to expire entries.
+	Now func() time.Time
+}
+
+// NewDisk opens a cache in dir, creating it if needed, whose entries
+// expire after ttl, or never if ttl is zero.
+func NewDisk(dir string, ttl time.Duration) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	d := &Disk{dir: dir, ttl: ttl, Now: time.Now}
+	d.Prune()
+	return d, nil
+}
+
+func (d *Disk) Get(key string) ([]byte, bool) {
+	path, ok := d.path(key)
+	if !ok {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error reading cache entry %s: %v", key, err)
+		}
+		return nil, false
+	}
+	if d.expired(info) {
+		os.Remove(path)
+		return nil, false
+	}
+
+	value, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Error reading cache entry %s: %v", key, err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (d *Disk) Set(key string, value []byte) {
+	path, ok := d.path(key)
+	if !ok {
+		return
+	}
+	if err := d.write(path, value); err != nil {
+		log.Printf("Error writing cache entry %s: %v", key, err)
+	}
+}
+
+// Prune removes expired entries.
+func (d *Disk) Prune() {
+	if d.ttl <= 0 {
+		return
+	}
+	filepath.WalkDir(d.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && d.expired(info) {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// write replaces the entry at path atomically, so readers never see a
+// partly written value.
+func (d *Disk) write(path string, value []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *Disk) expired(info fs.FileInfo) bool {
+	return d.ttl > 0 && !d.Now().Before(info.ModTime().Add(d.ttl))
+}
+
+// path is the file of key, spread over subdirectories named by its first
+// two characters. Keys other than lowercase hex are refused so they can't
+// name a file outside the directory.
+func (d *Disk) path(key string) (string, bool) {
+	if len(key) < 3 {
+		return "", false
+	}
+	for _, r := range key {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", false
+		}
+	}
+	return filepath.Join(d.dir, key[:2], key), true
+}
@@ -1,18 +1,77 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	Port       string
-	APIKey     string
-	MaxPDFSize int64
+	Port             string
+	APIKey           string
+	LLMProvider      string
+	LLMModel         string
+	LLMBaseURL       string
+	LLMPullModel     bool
+	MaxPDFSize       int64
+	PDFTimeout       time.Duration
+	PDFNetworks      string
+	ChunkTokens      int
+	Concurrency      int
+	WorkerCount      int
+	WorkerQueueSize  int
+	AgentName        string
+	AgentDescription string
+	AgentURL         string
+	AgentVersion     string
+	PushMaxAttempts  int
+	PushRetryBackoff time.Duration
+	PushNetworks     string
+	DeckDBPath       string
+	ReviewScheduler  string
+	CacheSize        int
+	CacheDir         string
+	CacheTTL         time.Duration
 }
 
 func Load() *Config {
+	port := getEnv("PORT", "8080")
+	provider := getEnv("LLM_PROVIDER", "gemini")
+
+	// GEMINI_API_KEY is a Google key, so it must never be sent to another
+	// provider's endpoint.
+	apiKey := getEnv("LLM_API_KEY", "")
+	if apiKey == "" && strings.EqualFold(provider, "gemini") {
+		apiKey = getEnv("GEMINI_API_KEY", "")
+	}
+
 	return &Config{
-		Port:       getEnv("PORT", "8080"),
-		APIKey:     getEnv("GEMINI_API_KEY", ""),
-		MaxPDFSize: 10 * 1024 * 1024, // 10MB
+		Port:             port,
+		APIKey:           apiKey,
+		LLMProvider:      provider,
+		LLMModel:         getEnv("LLM_MODEL", ""),
+		LLMBaseURL:       getEnv("LLM_BASE_URL", ""),
+		LLMPullModel:     getEnvBool("LLM_PULL_MODEL", false),
+		MaxPDFSize:       int64(getEnvInt("MAX_PDF_SIZE", 10*1024*1024)), // 10MB
+		PDFTimeout:       getEnvDuration("PDF_DOWNLOAD_TIMEOUT", 30*time.Second),
+		PDFNetworks:      getEnv("PDF_ALLOWED_NETWORKS", ""),
+		ChunkTokens:      getEnvInt("CHUNK_TOKENS", 8000),
+		Concurrency:      getEnvInt("GENERATION_CONCURRENCY", 4),
+		WorkerCount:      getEnvInt("WORKER_COUNT", 4),
+		WorkerQueueSize:  getEnvInt("WORKER_QUEUE_SIZE", 100),
+		AgentName:        getEnv("AGENT_NAME", "Lagbaja Flashcard Generator"),
+		AgentDescription: getEnv("AGENT_DESCRIPTION", "Generates study flashcards from PDF documents and text using AI."),
+		AgentURL:         getEnv("AGENT_URL", "http://localhost:"+port+"/a2a"),
+		AgentVersion:     getEnv("AGENT_VERSION", "1.0.0"),
+		PushMaxAttempts:  getEnvInt("PUSH_MAX_ATTEMPTS", 3),
+		PushRetryBackoff: getEnvDuration("PUSH_RETRY_BACKOFF", time.Second),
+		PushNetworks:     getEnv("PUSH_ALLOWED_NETWORKS", ""),
+		DeckDBPath:       getEnv("DECK_DB_PATH", "lagbaja.db"),
+		ReviewScheduler:  getEnv("REVIEW_SCHEDULER", "fsrs"),
+		CacheSize:        getEnvInt("CACHE_SIZE", 100),
+		CacheDir:         getEnv("CACHE_DIR", ""),
+		CacheTTL:         getEnvDuration("CACHE_TTL", 24*time.Hour),
 	}
 }
 
@@ -22,3 +81,30 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,568 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// APKGMimeType is the content type of an Anki deck package.
+const APKGMimeType = "application/apkg"
+
+// Note model IDs. They are fixed so that importing several decks reuses
+// the same note types instead of creating a copy each time.
+const (
+	basicModelID   int64 = 1718030401001
+	reverseModelID int64 = 1718030401002
+	clozeModelID   int64 = 1718030401003
+)
+
+// Anki note model types
+const (
+	modelStandard = 0
+	modelCloze    = 1
+)
+
+// fieldSeparator joins the fields of a note.
+const fieldSeparator = "\x1f"
+
+// unverifiedTag marks cards whose evidence wasn't found in the source.
+const unverifiedTag = "unverified"
+
+var (
+	clozeNumberPattern = regexp.MustCompile(`\{\{c(\d+)::`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ankiSchema creates the tables of an Anki 2.1 collection (schema 11).
+const ankiSchema = `
+CREATE TABLE col (
+    id integer primary key,
+    crt integer not null,
+    mod integer not null,
+    scm integer not null,
+    ver integer not null,
+    dty integer not null,
+    usn integer not null,
+    ls integer not null,
+    conf text not null,
+    models text not null,
+    decks text not null,
+    dconf text not null,
+    tags text not null
+);
+CREATE TABLE notes (
+    id integer primary key,
+    guid text not null,
+    mid integer not null,
+    mod integer not null,
+    usn integer not null,
+    tags text not null,
+    flds text not null,
+    sfld integer not null,
+    csum integer not null,
+    flags integer not null,
+    data text not null
+);
+CREATE TABLE cards (
+    id integer primary key,
+    nid integer not null,
+    did integer not null,
+    ord integer not null,
+    mod integer not null,
+    usn integer not null,
+    type integer not null,
+    queue integer not null,
+    due integer not null,
+    ivl integer not null,
+    factor integer not null,
+    reps integer not null,
+    lapses integer not null,
+    left integer not null,
+    odue integer not null,
+    odid integer not null,
+    flags integer not null,
+    data text not null
+);
+CREATE TABLE revlog (
+    id integer primary key,
+    cid integer not null,
+    usn integer not null,
+    ease integer not null,
+    ivl integer not null,
+    lastIvl integer not null,
+    factor integer not null,
+    time integer not null,
+    type integer not null
+);
+CREATE TABLE graves (
+    usn integer not null,
+    oid integer not null,
+    type integer not null
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// ankiCSS styles every note model.
+const ankiCSS = `.card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }
+.cloze { font-weight: bold; color: blue; }
+.source { margin-top: 1em; font-size: 14px; color: #666; }
+.options { display: inline-block; text-align: left; }`
+
+// sourceTemplate shows a note's Source field, when it has one, below the
+// answer.
+const sourceTemplate = `{{#Source}}<div class="source">{{Source}}</div>{{/Source}}`
+
+// ankiNote is one note and the ords of the cards Anki makes from it.
+type ankiNote struct {
+	modelID int64
+	fields  []string
+	tags    []string
+	ords    []int
+}
+
+// WriteAPKG writes set to w as an Anki deck package: a zip holding a
+// collection.anki2 SQLite database with one deck named after the set's
+// title. Basic, multiple choice and true/false cards become Basic notes,
+// reverse cards Basic (and reversed card) notes and cloze cards Cloze
+// notes. Topics become tags.
+func WriteAPKG(w io.Writer, set *models.FlashcardSet) error {
+	return writeAPKG(w, set, time.Now())
+}
+
+func writeAPKG(w io.Writer, set *models.FlashcardSet, now time.Time) error {
+	dir, err := os.MkdirTemp("", "lagbaja-apkg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(path, set, now); err != nil {
+		return fmt.Errorf("failed to write Anki collection: %w", err)
+	}
+	collection, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"collection.anki2", collection},
+		// The deck has no media files
+		{"media", []byte("{}")},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeCollection creates the SQLite collection at path.
+func writeCollection(path string, set *models.FlashcardSet, now time.Time) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(ankiSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deckID := ankiDeckID(deckName(set))
+	col, err := collectionConfig(set, deckID, now)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Unix(), now.UnixMilli(), now.UnixMilli(), col.conf, col.models, col.decks, col.dconf); err != nil {
+		return err
+	}
+
+	// IDs are creation times in milliseconds, counting up from now
+	noteID := now.UnixMilli()
+	cardID := now.UnixMilli()
+	for position, card := range set.Flashcards {
+		note := newAnkiNote(card)
+		sortField := stripHTML(note.fields[0])
+		if _, err := tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, noteGUID(note), note.modelID, now.Unix(), formatTags(note.tags),
+			strings.Join(note.fields, fieldSeparator), sortField, fieldChecksum(sortField)); err != nil {
+			return err
+		}
+		for _, ord := range note.ords {
+			// New cards (type and queue 0) are due in deck order
+			if _, err := tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+				cardID, noteID, deckID, ord, now.Unix(), position+1); err != nil {
+				return err
+			}
+			cardID++
+		}
+		noteID++
+	}
+
+	return tx.Commit()
+}
+
+// newAnkiNote maps a flashcard onto a note model's fields.
+func newAnkiNote(card models.Flashcard) ankiNote {
+	note := ankiNote{modelID: basicModelID, ords: []int{0}}
+	if card.Topic != "" {
+		note.tags = append(note.tags, tag(card.Topic))
+	}
+	if card.Grounded != nil && !*card.Grounded {
+		note.tags = append(note.tags, unverifiedTag)
+	}
+	source := sourceField(card)
+
+	switch card.CardType() {
+	case models.CardTypeReverse:
+		note.modelID = reverseModelID
+		note.ords = []int{0, 1}
+		note.fields = []string{htmlText(card.Question), htmlText(card.Answer), source}
+	case models.CardTypeCloze:
+		if card.Cloze != "" {
+			note.modelID = clozeModelID
+			note.ords = clozeOrds(card.Cloze)
+			note.fields = []string{htmlText(card.Cloze), htmlText(card.Explanation), source}
+			break
+		}
+		note.fields = []string{htmlText(card.Question), htmlText(card.Answer), source}
+	case models.CardTypeMCQ:
+		note.fields = []string{mcqFront(card), mcqBack(card), source}
+	case models.CardTypeTrueFalse:
+		back := htmlText(card.Answer)
+		if card.Explanation != "" {
+			back += "<br><br>" + htmlText(card.Explanation)
+		}
+		note.fields = []string{"True or false: " + htmlText(card.Question), back, source}
+	default:
+		note.fields = []string{htmlText(card.Question), htmlText(card.Answer), source}
+	}
+	return note
+}
+
+func mcqFront(card models.Flashcard) string {
+	var builder strings.Builder
+	builder.WriteString(htmlText(card.Question))
+	builder.WriteString(`<br><ol type="A" class="options">`)
+	for _, option := range card.Options {
+		builder.WriteString("<li>" + htmlText(option) + "</li>")
+	}
+	builder.WriteString("</ol>")
+	return builder.String()
+}
+
+func mcqBack(card models.Flashcard) string {
+	back := htmlText(card.Answer)
+	if card.CorrectIndex != nil {
+		back = fmt.Sprintf("%c) %s", 'A'+rune(*card.CorrectIndex), back)
+	}
+	if len(card.Rationales) == 0 {
+		return back
+	}
+
+	var builder strings.Builder
+	builder.WriteString(back)
+	builder.WriteString(`<br><ol type="A" class="options">`)
+	for _, rationale := range card.Rationales {
+		builder.WriteString("<li>" + htmlText(rationale) + "</li>")
+	}
+	builder.WriteString("</ol>")
+	return builder.String()
+}
+
+// sourceField cites the card's document, pages and evidence.
+func sourceField(card models.Flashcard) string {
+	var parts []string
+	if citation := card.Citation(); citation != "" {
+		parts = append(parts, htmlText(citation))
+	}
+	if card.Evidence != "" {
+		parts = append(parts, "<i>&ldquo;"+htmlText(card.Evidence)+"&rdquo;</i>")
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// htmlText escapes text for a note field, keeping its line breaks.
+func htmlText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+func stripHTML(field string) string {
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(field, " "))
+}
+
+// clozeOrds are the ords of the cards a cloze note makes: N-1 for each
+// distinct cN deletion, in order, so c1 and c3 make cards 0 and 2 and no
+// empty card for c2.
+func clozeOrds(text string) []int {
+	seen := make(map[int]bool)
+	var ords []int
+	for _, match := range clozeNumberPattern.FindAllStringSubmatch(text, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || seen[number] {
+			continue
+		}
+		seen[number] = true
+		ords = append(ords, number-1)
+	}
+	if len(ords) == 0 {
+		return []int{0}
+	}
+	sort.Ints(ords)
+	return ords
+}
+
+// formatTags renders tags the way Anki stores them: space separated with
+// a leading and trailing space.
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// noteGUID derives the note's GUID from its content, so importing the same
+// deck again updates its notes instead of duplicating them.
+func noteGUID(note ankiNote) string {
+	sum := sha1.Sum([]byte(strconv.FormatInt(note.modelID, 10) + fieldSeparator + note.fields[0]))
+	return base64.RawStdEncoding.EncodeToString(sum[:])[:10]
+}
+
+// fieldChecksum is the first 8 hex digits of the SHA-1 of the sort field,
+// which Anki uses to find duplicates.
+func fieldChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	checksum, _ := strconv.ParseInt(fmt.Sprintf("%x", sum[:4]), 16, 64)
+	return checksum
+}
+
+// ankiDeckID derives a stable deck ID from its name, so decks with the same
+// title are imported into the same Anki deck.
+func ankiDeckID(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	// Keep IDs well clear of the built-in Default deck (1)
+	return int64(hash.Sum64()>>24) + 1<<32
+}
+
+// collectionJSON holds the JSON columns of the col table.
+type collectionJSON struct {
+	conf, models, decks, dconf string
+}
+
+func collectionConfig(set *models.FlashcardSet, deckID int64, now time.Time) (collectionJSON, error) {
+	conf := map[string]interface{}{
+		"nextPos":       len(set.Flashcards) + 1,
+		"estTimes":      true,
+		"activeDecks":   []int64{deckID},
+		"sortType":      "noteFld",
+		"timeLim":       0,
+		"sortBackwards": false,
+		"addToCur":      true,
+		"curDeck":       deckID,
+		"newBury":       true,
+		"newSpread":     0,
+		"dueCounts":     true,
+		"curModel":      strconv.FormatInt(basicModelID, 10),
+		"collapseTime":  1200,
+	}
+
+	noteModels := map[string]interface{}{}
+	for _, model := range []map[string]interface{}{
+		standardModel(basicModelID, "Lagbaja Basic", deckID, now, []ankiTemplate{
+			{"Card 1", "{{Front}}", "{{FrontSide}}<hr id=answer>{{Back}}" + sourceTemplate},
+		}),
+		standardModel(reverseModelID, "Lagbaja Basic (and reversed card)", deckID, now, []ankiTemplate{
+			{"Card 1", "{{Front}}", "{{FrontSide}}<hr id=answer>{{Back}}" + sourceTemplate},
+			{"Card 2", "{{Back}}", "{{FrontSide}}<hr id=answer>{{Front}}" + sourceTemplate},
+		}),
+		noteModel(clozeModelID, "Lagbaja Cloze", modelCloze, deckID, now,
+			[]string{"Text", "Back Extra", "Source"},
+			[]ankiTemplate{{"Cloze", "{{cloze:Text}}", "{{cloze:Text}}<br>{{Back Extra}}" + sourceTemplate}}),
+	} {
+		noteModels[strconv.FormatInt(model["id"].(int64), 10)] = model
+	}
+
+	decks := map[string]interface{}{
+		"1":                           ankiDeck(1, "Default", now),
+		strconv.FormatInt(deckID, 10): ankiDeck(deckID, deckName(set), now),
+	}
+
+	dconf := map[string]interface{}{
+		"1": map[string]interface{}{
+			"id":       1,
+			"name":     "Default",
+			"mod":      0,
+			"usn":      0,
+			"maxTaken": 60,
+			"autoplay": true,
+			"timer":    0,
+			"replayq":  true,
+			"dyn":      false,
+			"new": map[string]interface{}{
+				"bury":          false,
+				"delays":        []int{1, 10},
+				"initialFactor": 2500,
+				"ints":          []int{1, 4, 0},
+				"order":         1,
+				"perDay":        20,
+			},
+			"lapse": map[string]interface{}{
+				"delays":      []int{10},
+				"leechAction": 1,
+				"leechFails":  8,
+				"minInt":      1,
+				"mult":        0,
+			},
+			"rev": map[string]interface{}{
+				"bury":       false,
+				"ease4":      1.3,
+				"ivlFct":     1,
+				"maxIvl":     36500,
+				"perDay":     200,
+				"hardFactor": 1.2,
+			},
+		},
+	}
+
+	var out collectionJSON
+	for _, column := range []struct {
+		target *string
+		value  interface{}
+	}{
+		{&out.conf, conf},
+		{&out.models, noteModels},
+		{&out.decks, decks},
+		{&out.dconf, dconf},
+	} {
+		data, err := json.Marshal(column.value)
+		if err != nil {
+			return out, err
+		}
+		*column.target = string(data)
+	}
+	return out, nil
+}
+
+// ankiTemplate is a card template: its name and question and answer
+// formats.
+type ankiTemplate struct {
+	name, qfmt, afmt string
+}
+
+// standardModel is a Front/Back/Source note model with one card per
+// template.
+func standardModel(id int64, name string, deckID int64, now time.Time, templates []ankiTemplate) map[string]interface{} {
+	model := noteModel(id, name, modelStandard, deckID, now, []string{"Front", "Back", "Source"}, templates)
+
+	// req tells Anki which fields each card needs: card 1 the Front, card
+	// 2 the Back
+	var req []interface{}
+	for ord := range templates {
+		req = append(req, []interface{}{ord, "any", []int{ord}})
+	}
+	model["req"] = req
+	return model
+}
+
+func noteModel(id int64, name string, modelType int, deckID int64, now time.Time, fields []string, templates []ankiTemplate) map[string]interface{} {
+	flds := make([]map[string]interface{}, len(fields))
+	for i, field := range fields {
+		flds[i] = map[string]interface{}{
+			"name":   field,
+			"ord":    i,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   20,
+			"media":  []string{},
+		}
+	}
+	tmpls := make([]map[string]interface{}, len(templates))
+	for i, template := range templates {
+		tmpls[i] = map[string]interface{}{
+			"name":  template.name,
+			"ord":   i,
+			"qfmt":  template.qfmt,
+			"afmt":  template.afmt,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}
+	}
+
+	return map[string]interface{}{
+		"id":        id,
+		"name":      name,
+		"type":      modelType,
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"sortf":     0,
+		"did":       deckID,
+		"flds":      flds,
+		"tmpls":     tmpls,
+		"css":       ankiCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []int{},
+	}
+}
+
+func ankiDeck(id int64, name string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"name":      name,
+		"desc":      "",
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"collapsed": false,
+		"newToday":  []int{0, 0},
+		"revToday":  []int{0, 0},
+		"lrnToday":  []int{0, 0},
+		"timeToday": []int{0, 0},
+		"dyn":       0,
+		"conf":      1,
+		"extendNew": 10,
+		"extendRev": 50,
+	}
+}
@@ -0,0 +1,250 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+var testSet = &models.FlashcardSet{
+	Title: "Cell Biology",
+	Flashcards: []models.Flashcard{
+		{Question: "What does the mitochondrion produce?", Answer: "ATP", Topic: "Cellular Respiration", Evidence: "It produces ATP", SourcePages: []int{1}, Grounded: boolPtr(true)},
+		{Type: models.CardTypeReverse, Question: "Ribosome", Answer: "Translates mRNA into proteins", Topic: "Organelles"},
+		{Type: models.CardTypeCloze, Cloze: "The {{c1::nucleus}} stores {{c2::DNA}}.", Question: "The [...] stores [...].", Answer: "nucleus; DNA", Topic: "Genetics"},
+		{Type: models.CardTypeMCQ, Question: "Which organelle makes ATP?", Options: []string{"Nucleus", "Mitochondrion"}, CorrectIndex: intPtr(1), Answer: "Mitochondrion", Topic: "Organelles"},
+		{Type: models.CardTypeTrueFalse, Question: "Lysosomes <digest> waste.", IsTrue: boolPtr(true), Answer: "True", Topic: "Organelles", Grounded: boolPtr(false)},
+	},
+	TotalCards: 5,
+}
+
+// openAPKG writes set as a package and opens its collection.
+func openAPKG(t *testing.T, set *models.FlashcardSet) *sql.DB {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := writeAPKG(&buf, set, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Package is not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	if string(files["media"]) != "{}" {
+		t.Errorf("Expected an empty media map, got %q", files["media"])
+	}
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, files["collection.anki2"], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open collection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWriteAPKG(t *testing.T) {
+	db := openAPKG(t, testSet)
+
+	var decksJSON, modelsJSON string
+	var version int
+	if err := db.QueryRow("SELECT ver, decks, models FROM col").Scan(&version, &decksJSON, &modelsJSON); err != nil {
+		t.Fatalf("Failed to read col: %v", err)
+	}
+	if version != 11 {
+		t.Errorf("Expected schema version 11, got %d", version)
+	}
+
+	var decks map[string]struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	json.Unmarshal([]byte(decksJSON), &decks)
+	deckID := ankiDeckID("Cell Biology")
+	if deck := decks[jsonID(deckID)]; deck.Name != "Cell Biology" {
+		t.Errorf("Expected a deck named %q, got %+v", "Cell Biology", decks)
+	}
+
+	var noteModels map[string]struct {
+		Name string `json:"name"`
+		Type int    `json:"type"`
+	}
+	json.Unmarshal([]byte(modelsJSON), &noteModels)
+	if model := noteModels[jsonID(clozeModelID)]; model.Type != modelCloze {
+		t.Errorf("Expected a cloze note model, got %+v", model)
+	}
+
+	tests := []struct {
+		name      string
+		modelID   int64
+		wantCards int
+		wantTags  string
+		wantField string
+	}{
+		{"Basic", basicModelID, 1, " Cellular_Respiration ", "What does the mitochondrion produce?\x1fATP\x1fpage 1<br><i>&ldquo;It produces ATP&rdquo;</i>"},
+		{"Reverse", reverseModelID, 2, " Organelles ", "Ribosome\x1fTranslates mRNA into proteins\x1f"},
+		{"Cloze", clozeModelID, 2, " Genetics ", "The {{c1::nucleus}} stores {{c2::DNA}}.\x1f\x1f"},
+		{"Multiple choice", basicModelID, 1, " Organelles ", `Which organelle makes ATP?<br><ol type="A" class="options"><li>Nucleus</li><li>Mitochondrion</li></ol>` + "\x1fB) Mitochondrion\x1f"},
+		{"True/false", basicModelID, 1, " Organelles unverified ", "True or false: Lysosomes &lt;digest&gt; waste.\x1fTrue\x1f"},
+	}
+
+	rows, err := db.Query("SELECT n.mid, n.tags, n.flds, COUNT(c.id), MIN(c.did) FROM notes n JOIN cards c ON c.nid = n.id GROUP BY n.id ORDER BY n.id")
+	if err != nil {
+		t.Fatalf("Failed to query notes: %v", err)
+	}
+	defer rows.Close()
+
+	i := 0
+	for ; rows.Next(); i++ {
+		var modelID, did int64
+		var tags, fields string
+		var cards int
+		if err := rows.Scan(&modelID, &tags, &fields, &cards, &did); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(tests) {
+			continue
+		}
+		tt := tests[i]
+		if modelID != tt.modelID {
+			t.Errorf("%s: expected model %d, got %d", tt.name, tt.modelID, modelID)
+		}
+		if cards != tt.wantCards {
+			t.Errorf("%s: expected %d cards, got %d", tt.name, tt.wantCards, cards)
+		}
+		if tags != tt.wantTags {
+			t.Errorf("%s: expected tags %q, got %q", tt.name, tt.wantTags, tags)
+		}
+		if fields != tt.wantField {
+			t.Errorf("%s: expected fields %q, got %q", tt.name, tt.wantField, fields)
+		}
+		if did != deckID {
+			t.Errorf("%s: expected cards in deck %d, got %d", tt.name, deckID, did)
+		}
+	}
+	if i != len(tests) {
+		t.Errorf("Expected %d notes, got %d", len(tests), i)
+	}
+}
+
+func TestWriteAPKG_ClozeOrds(t *testing.T) {
+	set := &models.FlashcardSet{
+		Title: "Genetics",
+		Flashcards: []models.Flashcard{
+			{Type: models.CardTypeCloze, Cloze: "The {{c3::nucleus}} stores {{c1::DNA}} and the {{c3::nucleolus}}."},
+		},
+	}
+	db := openAPKG(t, set)
+
+	rows, err := db.Query("SELECT ord FROM cards ORDER BY ord")
+	if err != nil {
+		t.Fatalf("Failed to query cards: %v", err)
+	}
+	defer rows.Close()
+
+	var ords []int
+	for rows.Next() {
+		var ord int
+		if err := rows.Scan(&ord); err != nil {
+			t.Fatal(err)
+		}
+		ords = append(ords, ord)
+	}
+	if fmt.Sprint(ords) != "[0 2]" {
+		t.Errorf("Expected cards for c1 and c3 (ords [0 2]), got %v", ords)
+	}
+}
+
+func TestClozeOrds(t *testing.T) {
+	tests := []struct {
+		cloze string
+		want  string
+	}{
+		{"The {{c1::nucleus}} stores {{c2::DNA}}.", "[0 1]"},
+		{"{{c2::Mitochondria}} make {{c2::ATP}} from {{c5::glucose}}.", "[1 4]"},
+		{"{{c10::a}} {{c2::b}}", "[1 9]"},
+		{"No deletions", "[0]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(clozeOrds(tt.cloze)); got != tt.want {
+			t.Errorf("Expected ords %s for %q, got %s", tt.want, tt.cloze, got)
+		}
+	}
+}
+
+func TestWriteAPKG_StableGUIDs(t *testing.T) {
+	guids := func() []string {
+		rows, err := openAPKG(t, testSet).Query("SELECT guid FROM notes ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var guids []string
+		for rows.Next() {
+			var guid string
+			rows.Scan(&guid)
+			guids = append(guids, guid)
+		}
+		return guids
+	}
+
+	first, second := guids(), guids()
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("Expected the same GUIDs on every export, got %v and %v", first, second)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Cell Biology", "cell-biology.apkg"},
+		{"  Flashcards from PDF: Chapter 3!  ", "flashcards-from-pdf-chapter-3.apkg"},
+		{"", "lagbaja-flashcards.apkg"},
+		{"???", "flashcards.apkg"},
+	}
+
+	for _, tt := range tests {
+		if got := Filename(&models.FlashcardSet{Title: tt.title}, "apkg"); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func jsonID(id int64) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
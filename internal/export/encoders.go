@@ -0,0 +1,152 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// Quizlet's default separators: a tab between term and definition and a
+// new line between cards.
+const (
+	DefaultTermSeparator = "\t"
+	DefaultCardSeparator = "\n"
+)
+
+// ankiNoteTypes names Anki's built-in note types for the TSV notetype
+// column.
+var ankiNoteTypes = map[int64]string{
+	basicModelID:   "Basic",
+	reverseModelID: "Basic (and reversed card)",
+	clozeModelID:   "Cloze",
+}
+
+// WriteCSV writes set as RFC 4180 CSV with a header row. Pages are
+// separated by spaces.
+func WriteCSV(w io.Writer, set *models.FlashcardSet) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+
+	if err := writer.Write([]string{"question", "answer", "topic", "type", "source", "pages", "evidence"}); err != nil {
+		return err
+	}
+	for _, card := range set.Flashcards {
+		pages := make([]string, len(card.SourcePages))
+		for i, page := range card.SourcePages {
+			pages[i] = strconv.Itoa(page)
+		}
+		if err := writer.Write([]string{
+			card.Question, card.Answer, card.Topic, card.CardType(), card.Source, strings.Join(pages, " "), card.Evidence,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteAnkiTSV writes set as a tab-separated file for Anki's text import.
+// Header lines tell Anki the separator, the deck, and which columns hold
+// the note type and tags, so basic, reverse and cloze cards import as
+// Anki's built-in note types. Fields are HTML with the text escaped.
+func WriteAnkiTSV(w io.Writer, set *models.FlashcardSet) error {
+	var builder strings.Builder
+	builder.WriteString("#separator:tab\n#html:true\n")
+	builder.WriteString(fmt.Sprintf("#deck:%s\n", tsvField(deckName(set))))
+	builder.WriteString("#notetype column:1\n#tags column:4\n")
+
+	for _, card := range set.Flashcards {
+		note := newAnkiNote(card)
+		back := note.fields[1]
+		if source := note.fields[2]; source != "" {
+			if back != "" {
+				back += "<br><br>"
+			}
+			back += source
+		}
+		builder.WriteString(strings.Join([]string{
+			ankiNoteTypes[note.modelID],
+			tsvField(note.fields[0]),
+			tsvField(back),
+			tsvField(strings.Join(note.tags, " ")),
+		}, "\t"))
+		builder.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// tsvField keeps a field on one line and in one column.
+func tsvField(field string) string {
+	return strings.NewReplacer("\t", " ", "\r", "", "\n", "<br>").Replace(field)
+}
+
+// QuizletEncoder returns an encoder for Quizlet's import box that puts
+// termSeparator between each term and its definition and cardSeparator
+// between cards. Quizlet has no way to escape a separator, so separators
+// inside a card are replaced with spaces.
+func QuizletEncoder(termSeparator, cardSeparator string) Encoder {
+	return func(w io.Writer, set *models.FlashcardSet) error {
+		replacer := strings.NewReplacer(termSeparator, " ", cardSeparator, " ")
+		if cardSeparator == "\n" {
+			replacer = strings.NewReplacer(termSeparator, " ", "\r\n", " ", "\n", " ")
+		}
+
+		cards := make([]string, len(set.Flashcards))
+		for i, card := range set.Flashcards {
+			cards[i] = replacer.Replace(plainFront(card)) + termSeparator + replacer.Replace(plainBack(card))
+		}
+		_, err := io.WriteString(w, strings.Join(cards, cardSeparator)+cardSeparator)
+		return err
+	}
+}
+
+// plainFront is the question side of a card as plain text.
+func plainFront(card models.Flashcard) string {
+	switch card.CardType() {
+	case models.CardTypeMCQ:
+		options := make([]string, len(card.Options))
+		for i, option := range card.Options {
+			options[i] = fmt.Sprintf("%c) %s", 'A'+rune(i), option)
+		}
+		return card.Question + " " + strings.Join(options, " ")
+	case models.CardTypeTrueFalse:
+		return "True or false: " + card.Question
+	default:
+		return card.Question
+	}
+}
+
+// plainBack is the answer side of a card as plain text.
+func plainBack(card models.Flashcard) string {
+	switch card.CardType() {
+	case models.CardTypeMCQ:
+		if card.CorrectIndex != nil {
+			return fmt.Sprintf("%c) %s", 'A'+rune(*card.CorrectIndex), card.Answer)
+		}
+	case models.CardTypeTrueFalse:
+		if card.Explanation != "" {
+			return card.Answer + ". " + card.Explanation
+		}
+	}
+	return card.Answer
+}
+
+// WriteJSONL writes one flashcard JSON object per line.
+func WriteJSONL(w io.Writer, set *models.FlashcardSet) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, card := range set.Flashcards {
+		if err := encoder.Encode(card); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func encode(t *testing.T, encoder Encoder, set *models.FlashcardSet) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := encoder(&buf, set); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return buf.String()
+}
+
+func TestWriteCSV(t *testing.T) {
+	set := &models.FlashcardSet{Flashcards: []models.Flashcard{
+		{Question: "What is ATP?", Answer: "Energy, \"currency\"\nof the cell", Topic: "Energy", Source: "notes.pdf", SourcePages: []int{2, 3}, Evidence: "ATP stores energy"},
+		{Type: models.CardTypeReverse, Question: "Ribosome", Answer: "Makes proteins"},
+	}}
+
+	want := "question,answer,topic,type,source,pages,evidence\r\n" +
+		"What is ATP?,\"Energy, \"\"currency\"\"\r\nof the cell\",Energy,basic,notes.pdf,2 3,ATP stores energy\r\n" +
+		"Ribosome,Makes proteins,,reverse,,,\r\n"
+	if got := encode(t, WriteCSV, set); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestWriteAnkiTSV(t *testing.T) {
+	lines := strings.Split(encode(t, WriteAnkiTSV, testSet), "\n")
+
+	want := []string{
+		"#separator:tab",
+		"#html:true",
+		"#deck:Cell Biology",
+		"#notetype column:1",
+		"#tags column:4",
+		"Basic\tWhat does the mitochondrion produce?\tATP<br><br>page 1<br><i>&ldquo;It produces ATP&rdquo;</i>\tCellular_Respiration",
+		"Basic (and reversed card)\tRibosome\tTranslates mRNA into proteins\tOrganelles",
+		"Cloze\tThe {{c1::nucleus}} stores {{c2::DNA}}.\t\tGenetics",
+		"Basic\tWhich organelle makes ATP?<br><ol type=\"A\" class=\"options\"><li>Nucleus</li><li>Mitochondrion</li></ol>\tB) Mitochondrion\tOrganelles",
+		"Basic\tTrue or false: Lysosomes &lt;digest&gt; waste.\tTrue\tOrganelles unverified",
+		"",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %d: %q", len(want), len(lines), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], lines[i])
+		}
+	}
+}
+
+func TestWriteAnkiTSV_KeepsFieldsOnOneLine(t *testing.T) {
+	set := &models.FlashcardSet{Title: "Tabs\tand\nlines", Flashcards: []models.Flashcard{
+		{Question: "Line one\nline two", Answer: "a\tb"},
+	}}
+
+	got := encode(t, WriteAnkiTSV, set)
+	if !strings.Contains(got, "#deck:Tabs and<br>lines\n") {
+		t.Errorf("Expected the deck name on one line, got %q", got)
+	}
+	if !strings.HasSuffix(got, "Basic\tLine one<br>line two\ta b\t\n") {
+		t.Errorf("Expected the card on one line, got %q", got)
+	}
+}
+
+func TestQuizletEncoder(t *testing.T) {
+	set := &models.FlashcardSet{Flashcards: []models.Flashcard{
+		{Question: "What is ATP?", Answer: "Energy\ncurrency; of the cell"},
+		{Type: models.CardTypeMCQ, Question: "Which makes ATP?", Options: []string{"Nucleus", "Mitochondrion"}, CorrectIndex: intPtr(1), Answer: "Mitochondrion"},
+		{Type: models.CardTypeTrueFalse, Question: "Cells\tdivide.", Answer: "True", Explanation: "By mitosis."},
+	}}
+
+	tests := []struct {
+		name          string
+		termSeparator string
+		cardSeparator string
+		want          string
+	}{
+		{"Defaults", DefaultTermSeparator, DefaultCardSeparator,
+			"What is ATP?\tEnergy currency; of the cell\n" +
+				"Which makes ATP? A) Nucleus B) Mitochondrion\tB) Mitochondrion\n" +
+				"True or false: Cells divide.\tTrue. By mitosis.\n"},
+		{"Custom", " - ", ";",
+			"What is ATP? - Energy\ncurrency  of the cell;" +
+				"Which makes ATP? A) Nucleus B) Mitochondrion - B) Mitochondrion;" +
+				"True or false: Cells\tdivide. - True. By mitosis.;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(t, QuizletEncoder(tt.termSeparator, tt.cardSeparator), set); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(encode(t, WriteJSONL, testSet), "\n"), "\n")
+	if len(lines) != len(testSet.Flashcards) {
+		t.Fatalf("Expected %d lines, got %d", len(testSet.Flashcards), len(lines))
+	}
+
+	for i, line := range lines {
+		var card models.Flashcard
+		if err := json.Unmarshal([]byte(line), &card); err != nil {
+			t.Fatalf("Line %d is not JSON: %v", i+1, err)
+		}
+		if card.Question != testSet.Flashcards[i].Question {
+			t.Errorf("Expected %q, got %q", testSet.Flashcards[i].Question, card.Question)
+		}
+	}
+	if !strings.Contains(lines[4], "<digest>") {
+		t.Errorf("Expected HTML left unescaped, got %q", lines[4])
+	}
+}
+
+func TestByMimeType(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"text/csv", "csv"},
+		{"TEXT/TAB-SEPARATED-VALUES", "tsv"},
+		{"text/tsv", "tsv"},
+		{"text/x-quizlet", "quizlet"},
+		{"application/x-ndjson", "jsonl"},
+		{"application/apkg", "apkg"},
+		{"application/json", ""},
+	}
+
+	for _, tt := range tests {
+		format, _ := ByMimeType(tt.mimeType)
+		if format.Name != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, format.Name)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if format, ok := Lookup(" CSV "); !ok || format.MimeType != CSVMimeType {
+		t.Errorf("Expected the csv format, got %+v", format)
+	}
+	if _, ok := Lookup("docx"); ok {
+		t.Error("Expected no docx format")
+	}
+	if got := strings.Join(Names(), ","); got != "csv,tsv,quizlet,jsonl,apkg" {
+		t.Errorf("Expected %q, got %q", "csv,tsv,quizlet,jsonl,apkg", got)
+	}
+}
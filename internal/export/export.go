@@ -0,0 +1,47 @@
+// Package export encodes flashcard sets in formats other study tools can
+// import.
+package export
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// defaultDeckName is used when a set has no title.
+const defaultDeckName = "Lagbaja Flashcards"
+
+// Filename is a download filename for set with the given extension, such
+// as "photosynthesis-basics.apkg".
+func Filename(set *models.FlashcardSet, extension string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(deckName(set)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			dash = false
+		} else if !dash && builder.Len() > 0 {
+			builder.WriteRune('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(builder.String(), "-")
+	if name == "" {
+		name = "flashcards"
+	}
+	return name + "." + extension
+}
+
+// deckName is the name of the deck set is exported as.
+func deckName(set *models.FlashcardSet) string {
+	if title := strings.TrimSpace(set.Title); title != "" {
+		return title
+	}
+	return defaultDeckName
+}
+
+// tag turns a topic into a tag, which can't contain spaces.
+func tag(topic string) string {
+	return strings.Join(strings.Fields(topic), "_")
+}
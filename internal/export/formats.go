@@ -0,0 +1,86 @@
+package export
+
+import (
+	"io"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// Encoder writes a flashcard set in one export format.
+type Encoder func(w io.Writer, set *models.FlashcardSet) error
+
+// Format is an export format a set can be downloaded in.
+type Format struct {
+	// Name selects the format, e.g. in /upload?format=csv.
+	Name string
+	// MimeType identifies the format in A2A output modes and parts.
+	MimeType string
+	// ContentType is sent with HTTP downloads.
+	ContentType string
+	// Extension is the extension of download filenames.
+	Extension string
+	// Binary formats are sent to A2A clients as file parts, the others
+	// as text parts.
+	Binary bool
+	Encode Encoder
+}
+
+// Mime types of the export formats
+const (
+	CSVMimeType     = "text/csv"
+	TSVMimeType     = "text/tab-separated-values"
+	QuizletMimeType = "text/x-quizlet"
+	JSONLMimeType   = "application/jsonl"
+)
+
+// Formats lists every export format.
+var Formats = []Format{
+	{Name: "csv", MimeType: CSVMimeType, ContentType: "text/csv; charset=utf-8", Extension: "csv", Encode: WriteCSV},
+	{Name: "tsv", MimeType: TSVMimeType, ContentType: "text/tab-separated-values; charset=utf-8", Extension: "tsv", Encode: WriteAnkiTSV},
+	{Name: "quizlet", MimeType: QuizletMimeType, ContentType: "text/plain; charset=utf-8", Extension: "txt", Encode: QuizletEncoder(DefaultTermSeparator, DefaultCardSeparator)},
+	{Name: "jsonl", MimeType: JSONLMimeType, ContentType: "application/jsonl; charset=utf-8", Extension: "jsonl", Encode: WriteJSONL},
+	{Name: "apkg", MimeType: APKGMimeType, ContentType: APKGMimeType, Extension: "apkg", Binary: true, Encode: WriteAPKG},
+}
+
+// mimeAliases are other spellings accepted for a format's mime type.
+var mimeAliases = map[string]string{
+	"application/x-ndjson": JSONLMimeType,
+	"application/x-jsonl":  JSONLMimeType,
+	"text/tsv":             TSVMimeType,
+}
+
+// Lookup returns the format with the given name, ignoring case.
+func Lookup(name string) (Format, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// ByMimeType returns the format with the given mime type or one of its
+// aliases, ignoring case.
+func ByMimeType(mimeType string) (Format, bool) {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if canonical, ok := mimeAliases[mimeType]; ok {
+		mimeType = canonical
+	}
+	for _, format := range Formats {
+		if format.MimeType == mimeType {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Names lists the format names, for error messages.
+func Names() []string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = format.Name
+	}
+	return names
+}
@@ -1,16 +1,23 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
 	// "strings"
 	"time"
 
 	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/push"
+	"github.com/tobey0x/lagbaja/internal/review"
 	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+	"github.com/tobey0x/lagbaja/internal/worker"
 	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
 
 	"github.com/google/uuid"
@@ -18,22 +25,144 @@ import (
 
 type A2AHandler struct {
 	flashcardService *service.FlashcardService
+	taskStore        store.TaskStore
+	conversations    store.ConversationStore
+	decks            store.DeckStore
+	reviews          *review.Service
+	workers          *worker.Pool
+	notifier         *push.Notifier
+	methods          map[string]methodHandler
+
+	// mu guards running and serializes task state changes against cancellation
+	mu      sync.Mutex
+	running map[string]*runningTask
+}
+
+type methodHandler func(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest)
+
+// Option configures optional A2AHandler dependencies.
+type Option func(*A2AHandler)
+
+// WithTaskStore replaces the default in-memory task store.
+func WithTaskStore(taskStore store.TaskStore) Option {
+	return func(h *A2AHandler) {
+		h.taskStore = taskStore
+	}
+}
+
+// WithConversationStore replaces the default in-memory conversation store.
+func WithConversationStore(conversations store.ConversationStore) Option {
+	return func(h *A2AHandler) {
+		h.conversations = conversations
+	}
+}
+
+// WithDeckStore replaces the default in-memory deck library that
+// generated sets are saved to.
+func WithDeckStore(decks store.DeckStore) Option {
+	return func(h *A2AHandler) {
+		h.decks = decks
+	}
+}
+
+// WithReviewService sets the review service that quiz answers are marked
+// and scheduled with. By default answers are scheduled with FSRS in memory.
+func WithReviewService(reviews *review.Service) Option {
+	return func(h *A2AHandler) {
+		h.reviews = reviews
+	}
 }
 
-func NewA2AHandler(flashcardService *service.FlashcardService) *A2AHandler {
-	return &A2AHandler{
+// WithWorkerPool sets the pool that runs non-blocking message/send requests.
+func WithWorkerPool(pool *worker.Pool) Option {
+	return func(h *A2AHandler) {
+		h.workers = pool
+	}
+}
+
+// WithNotifier sets the push notification notifier and the webhook configs
+// it delivers to.
+func WithNotifier(notifier *push.Notifier) Option {
+	return func(h *A2AHandler) {
+		h.notifier = notifier
+	}
+}
+
+func NewA2AHandler(flashcardService *service.FlashcardService, opts ...Option) *A2AHandler {
+	h := &A2AHandler{
 		flashcardService: flashcardService,
+		taskStore:        store.NewMemoryTaskStore(),
+		conversations:    store.NewMemoryConversationStore(),
+		decks:            store.NewMemoryDeckStore(),
+		running:          make(map[string]*runningTask),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.workers == nil {
+		h.workers = worker.NewPool(defaultWorkers, defaultQueueSize)
 	}
+	if h.reviews == nil {
+		h.reviews = review.NewService(h.decks, store.NewMemoryReviewStore(), review.NewFSRS())
+		h.reviews.Marker = flashcardService
+	}
+	if h.notifier == nil {
+		h.notifier = push.NewNotifier(push.NewMemoryConfigStore(), defaultPushAttempts, defaultPushBackoff)
+	}
+	h.registerMethods()
+	return h
 }
 
+// registerMethods builds the JSON-RPC dispatch table. The agent card derives
+// its capabilities from it, so new methods only need to be added here.
+func (h *A2AHandler) registerMethods() {
+	h.methods = map[string]methodHandler{
+		"message/send":   h.handleMessageSend,
+		"message/stream": h.handleMessageStream,
+		"tasks/get":      h.handleTasksGet,
+		"tasks/cancel":   h.handleTasksCancel,
+
+		"tasks/pushNotificationConfig/set":    h.handlePushConfigSet,
+		"tasks/pushNotificationConfig/get":    h.handlePushConfigGet,
+		"tasks/pushNotificationConfig/list":   h.handlePushConfigList,
+		"tasks/pushNotificationConfig/delete": h.handlePushConfigDelete,
+	}
+}
+
+// SupportsMethod reports whether ServeHTTP dispatches the named method.
+func (h *A2AHandler) SupportsMethod(method string) bool {
+	_, ok := h.methods[method]
+	return ok
+}
+
+// flashcardArtifactName names the artifact carrying the generated set.
+const flashcardArtifactName = "flashcardSet"
+
+const (
+	defaultWorkers      = 4
+	defaultQueueSize    = 100
+	defaultPushAttempts = 3
+	defaultPushBackoff  = time.Second
+)
+
 func (h *A2AHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.sendError(w, "unknown", models.InvalidRequest, "Only POST method is supported", "")
 		return
 	}
 
+	// Inline PDFs are checked once decoded, so bound what is read first
+	if limit := h.maxBodySize(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+
 	var req models.JSONRPCRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.sendError(w, "", models.InvalidRequest, "Request too large", fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
 		h.sendError(w, "", models.ParseError, "Parse error", err.Error())
 		return
 	}
@@ -43,15 +172,15 @@ func (h *A2AHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switch req.Method {
-	case "message/send":
-		h.handleMessageSend(w, req)
-	default:
+	handle, ok := h.methods[req.Method]
+	if !ok {
 		h.sendError(w, req.ID, models.MethodNotFound, "Method not found", fmt.Sprintf("Method %s not supported", req.Method))
+		return
 	}
+	handle(w, r, req)
 }
 
-func (h *A2AHandler) handleMessageSend(w http.ResponseWriter, req models.JSONRPCRequest) {
+func (h *A2AHandler) handleMessageSend(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
 	// Extract and validate message
 	msg, err := h.extractMessage(req.Params)
 	if err != nil {
@@ -62,20 +191,80 @@ func (h *A2AHandler) handleMessageSend(w http.ResponseWriter, req models.JSONRPC
 
 	// Extract user input
 	userInput := h.extractUserInput(msg)
-	if userInput == "" {
-		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "No text content found in message")
+	if userInput == "" && !hasFileParts(msg) && !hasQuizAnswer(msg) {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "No text or file content found in message")
+		return
+	}
+	if _, err := extractGenerationOptions(msg); err != nil {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+	if _, err := extractQuizAnswer(msg); err != nil {
+		h.sendAppError(w, req.ID, err)
 		return
 	}
 
+	config := h.extractConfiguration(req.Params)
+	modes, err := negotiateOutputModes(config)
+	if err != nil {
+		h.sendError(w, req.ID, models.ContentTypeNotSupportedError, "Incompatible content types", err.Error())
+		return
+	}
+
+	if !config.IsBlocking() {
+		h.handleAsyncMessageSend(w, r, req, userInput, msg, config, modes)
+		return
+	}
+
+	task, err := h.newTask(msg, RequestOwner(r))
+	if err != nil {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+	h.registerPushConfig(task.ID, config)
+	h.saveTask(task)
+	ctx := h.startTask(withOwner(r.Context(), RequestOwner(r)), task.ID)
+
 	// Process request
-	result, err := h.processRequest(userInput, msg)
+	result, err := h.processRequest(ctx, userInput, msg, modes, nil)
+	final := h.finishTask(ctx, task, result, err)
+	if final.Status.State == models.StateFailed {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+
+	h.sendSuccess(w, req.ID, final)
+}
+
+// handleAsyncMessageSend replies with a submitted task straight away and
+// generates the flashcards on the worker pool. Clients poll tasks/get for
+// the final state.
+func (h *A2AHandler) handleAsyncMessageSend(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest, userInput string, msg *models.Message, config *models.Configuration, modes []string) {
+	task, err := h.newTask(msg, RequestOwner(r))
 	if err != nil {
-		appErr := err.(*apperrors.AppError)
-		h.sendError(w, req.ID, appErr.Code, appErr.Message, appErr.Error())
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+	h.registerPushConfig(task.ID, config)
+	h.saveTask(task)
+
+	// The worker mutates task, so reply with a snapshot of the submitted state
+	submitted := *task
+
+	// Background tasks outlive the request, so they only stop on tasks/cancel
+	ctx := h.startTask(withOwner(context.Background(), RequestOwner(r)), task.ID)
+
+	err = h.workers.Submit(func() {
+		h.runTask(ctx, task, userInput, msg, modes)
+	})
+	if err != nil {
+		log.Printf("Error queueing task %s: %v", task.ID, err)
+		h.finishTask(ctx, task, nil, apperrors.NewAppError(models.InternalError, "Server is busy, please retry later", err))
+		h.sendError(w, req.ID, models.InternalError, "Server busy", err.Error())
 		return
 	}
 
-	h.sendSuccess(w, req.ID, result)
+	h.sendSuccess(w, req.ID, &submitted)
 }
 
 func (h *A2AHandler) extractMessage(params map[string]interface{}) (*models.Message, error) {
@@ -101,6 +290,21 @@ func (h *A2AHandler) extractMessage(params map[string]interface{}) (*models.Mess
 	return &msg, nil
 }
 
+// extractConfiguration returns the optional params.configuration, or nil
+// when the client didn't send one.
+func (h *A2AHandler) extractConfiguration(params map[string]interface{}) *models.Configuration {
+	configData, ok := params["configuration"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var config models.Configuration
+	if err := decodeParams(configData, &config); err != nil {
+		return nil
+	}
+	return &config
+}
+
 func (h *A2AHandler) extractUserInput(msg *models.Message) string {
 	for _, part := range msg.Parts {
 		if part.Kind == models.KindText {
@@ -110,83 +314,144 @@ func (h *A2AHandler) extractUserInput(msg *models.Message) string {
 	return ""
 }
 
-func (h *A2AHandler) processRequest(input string, userMsg *models.Message) (*models.TaskResult, error) {
+func (h *A2AHandler) processRequest(ctx context.Context, input string, userMsg *models.Message, modes []string, progress service.ProgressFunc) (*models.TaskResult, error) {
 	var flashcards *models.FlashcardSet
-	var err error
 
-	// Check if input contains PDF URL
+	// A quiz answer is marked rather than turned into flashcards
+	quiz, err := extractQuizAnswer(userMsg)
+	if err != nil {
+		return nil, err
+	}
+	if quiz != nil {
+		return h.markAnswer(ctx, quiz, userMsg, modes)
+	}
+
+	conversation, err := h.loadConversation(ctx, userMsg.ContextID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := extractGenerationOptions(userMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect attached PDFs, plus any PDF URL in the text
+	files, err := h.extractFiles(userMsg)
+	if err != nil {
+		return nil, err
+	}
 	if pdfURL := h.flashcardService.ExtractPDFURL(input); pdfURL != "" {
 		log.Printf("Processing PDF from URL: %s", pdfURL)
-		flashcards, err = h.flashcardService.GenerateFromURL(pdfURL)
+		files = append([]service.PDFFile{{URI: pdfURL}}, files...)
+	}
+
+	if len(files) > 0 {
+		log.Printf("Processing %d PDF file(s)", len(files))
+		flashcards, err = h.flashcardService.GenerateFromFiles(ctx, files, opts, progress)
+	} else if conversation.Deck != nil {
+		// Follow-up in an existing conversation refines the last deck
+		log.Printf("Refining flashcards in context %s", conversation.ContextID)
+		flashcards, err = h.flashcardService.RefineFlashcards(ctx, conversation, input, opts, progress)
 	} else {
-		// Check if message contains a data part with PDF content
-		pdfData := h.extractPDFData(userMsg)
-		if pdfData != nil {
-			log.Printf("Processing uploaded PDF (%d bytes)", len(pdfData))
-			flashcards, err = h.flashcardService.GenerateFromPDFData(pdfData)
-		} else {
-			log.Printf("Generating flashcards from text input")
-			flashcards, err = h.flashcardService.GenerateFromText(input)
-		}
+		log.Printf("Generating flashcards from text input")
+		flashcards, err = h.flashcardService.GenerateFromText(ctx, input, opts, progress)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	h.saveDeck(ctx, flashcards)
+
 	// Build response
-	return h.buildTaskResult(flashcards, userMsg), nil
+	result := h.buildTaskResult(flashcards, userMsg, modes)
+	h.updateConversation(conversation, result, flashcards)
+	return result, nil
 }
 
-func (h *A2AHandler) extractPDFData(msg *models.Message) []byte {
-	for _, part := range msg.Parts {
-		if part.Kind == models.KindData {
-			// Check if data is a base64 encoded PDF or raw bytes
-			if dataMap, ok := part.Data.(map[string]interface{}); ok {
-				// Handle base64 encoded PDF
-				if contentType, ok := dataMap["contentType"].(string); ok {
-					if contentType == "application/pdf" {
-						if base64Data, ok := dataMap["data"].(string); ok {
-							// Decode base64 to bytes
-							// For now, return the string as bytes
-							// In production, properly decode base64
-							return []byte(base64Data)
-						}
-					}
-				}
-				// Handle direct byte array
-				if pdfBytes, ok := dataMap["pdf"].([]byte); ok {
-					return pdfBytes
-				}
-			}
-			// Handle if data is already bytes
-			if bytes, ok := part.Data.([]byte); ok {
-				return bytes
-			}
+// loadConversation returns the stored conversation for contextID, or an
+// empty one owned by the request owner if this is the first message in the
+// context. A context started by another user can't be continued.
+func (h *A2AHandler) loadConversation(ctx context.Context, contextID string) (*models.Conversation, error) {
+	owner := ownerFrom(ctx)
+	if contextID == "" {
+		return &models.Conversation{Owner: owner}, nil
+	}
+
+	conversation, err := h.conversations.Get(contextID)
+	if err != nil {
+		if !errors.Is(err, store.ErrConversationNotFound) {
+			log.Printf("Error loading conversation %s: %v", contextID, err)
 		}
+		return &models.Conversation{ContextID: contextID, Owner: owner}, nil
+	}
+	if conversation.Owner != owner {
+		return nil, apperrors.NewAppError(models.InvalidParams, fmt.Sprintf("context %s belongs to another user", contextID), nil)
+	}
+	return conversation, nil
+}
+
+// saveDeck saves flashcards to the request owner's deck library, replacing
+// the deck a refined set came from. The set is still returned if it can't
+// be saved.
+func (h *A2AHandler) saveDeck(ctx context.Context, flashcards *models.FlashcardSet) {
+	if err := store.SaveSet(h.decks, ownerFrom(ctx), flashcards); err != nil {
+		log.Printf("Error saving deck: %v", err)
 	}
-	return nil
 }
 
-func (h *A2AHandler) buildTaskResult(flashcards *models.FlashcardSet, userMsg *models.Message) *models.TaskResult {
+// updateConversation records the exchange in result and makes flashcards
+// the deck that the next follow-up refines.
+func (h *A2AHandler) updateConversation(conversation *models.Conversation, result *models.TaskResult, flashcards *models.FlashcardSet) {
+	conversation.ContextID = result.ContextID
+	conversation.History = append(conversation.History, result.History...)
+	conversation.Deck = flashcards
+	conversation.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	if err := h.conversations.Save(conversation); err != nil {
+		log.Printf("Error saving conversation %s: %v", conversation.ContextID, err)
+	}
+}
+
+// buildTaskResult builds the completed task for flashcards, rendering the
+// artifact in each output mode. Nil modes use the defaults.
+func (h *A2AHandler) buildTaskResult(flashcards *models.FlashcardSet, userMsg *models.Message, modes []string) *models.TaskResult {
+	if modes == nil {
+		modes = defaultOutputModeSet
+	}
+
 	// Use the incoming taskId or generate a new UUID
 	taskID := userMsg.TaskID
 	if taskID == "" {
 		taskID = uuid.New().String()
 	}
+	userMsg.TaskID = taskID
+
+	// Continue the client's conversation or start a new one
+	contextID := userMsg.ContextID
+	if contextID == "" {
+		contextID = uuid.New().String()
+	}
+	userMsg.ContextID = contextID
 
 	// Generate messageId as full UUID
 	messageID := uuid.New().String()
-	
-	// Format flashcards as markdown text
-	responseText := h.flashcardService.FormatAsText(flashcards)
-	
+
+	// Format flashcards as markdown text, or a summary for clients that
+	// only take structured output
+	responseText := summaryText(flashcards)
+	if hasMode(modes, ModeMarkdown) {
+		responseText = h.flashcardService.FormatAsText(flashcards)
+	}
+
 	// Build agent message
 	responseMsg := models.Message{
 		Kind:      models.KindMessage,
 		Role:      models.RoleAgent,
 		MessageID: messageID,
 		TaskID:    taskID,
+		ContextID: contextID,
 		Parts: []models.MessagePart{
 			{
 				Kind: models.KindText,
@@ -195,30 +460,27 @@ func (h *A2AHandler) buildTaskResult(flashcards *models.FlashcardSet, userMsg *m
 		},
 	}
 
-	// Build artifact with same content
+	// Build artifact with the set in every accepted output mode
 	artifacts := []models.Artifact{
 		{
-			ArtifactID: uuid.New().String(),
-			Name:       "Flashcard Set",
-			Parts: []models.MessagePart{
-				{
-					Kind: models.KindText,
-					Text: responseText,
-				},
-			},
+			ArtifactID:  uuid.New().String(),
+			Name:        flashcardArtifactName,
+			Description: flashcards.Title,
+			Parts:       h.flashcardParts(flashcards, modes),
 		},
 	}
 
 	return &models.TaskResult{
 		ID:        taskID,
-		ContextID: uuid.New().String(),  // Full UUID for context
+		ContextID: contextID,
 		Status: models.Status{
 			State:     models.StateCompleted,
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
-			Message:   responseMsg,
+			Message:   &responseMsg,
 		},
 		Artifacts: artifacts,
-		Kind: "task",
+		History:   []models.Message{*userMsg, responseMsg},
+		Kind:      models.KindTask,
 	}
 }
 
@@ -231,6 +493,17 @@ func (h *A2AHandler) sendSuccess(w http.ResponseWriter, id string, result interf
 	h.writeResponse(w, response, http.StatusOK)
 }
 
+// sendAppError reports err with its AppError code, falling back to an
+// internal error for anything else.
+func (h *A2AHandler) sendAppError(w http.ResponseWriter, id string, err error) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		h.sendError(w, id, models.InternalError, "Internal error", err.Error())
+		return
+	}
+	h.sendError(w, id, appErr.Code, appErr.Message, appErr.Error())
+}
+
 func (h *A2AHandler) sendError(w http.ResponseWriter, id string, code int, message, data string) {
 	response := models.JSONRPCResponse{
 		JSONRPC: "2.0",
@@ -250,4 +523,4 @@ func (h *A2AHandler) writeResponse(w http.ResponseWriter, response models.JSONRP
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("Error encoding response: %v", err)
 	}
-}
\ No newline at end of file
+}
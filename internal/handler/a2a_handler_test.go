@@ -1,19 +1,39 @@
 package handler
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
 	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
 )
 
+// testDeck is the scripted model reply used by newTestFlashcardService.
+const testDeck = "Q: What is 2+2?\nA: 4\nT: Math\n\nQ: What is 3x3?\nA: 9\nT: Math\n\n"
+
+// newTestFlashcardService returns a service backed by a FakeProvider that
+// always replies with testDeck.
+func newTestFlashcardService(t *testing.T, pdfService *service.PDFService) *service.FlashcardService {
+	t.Helper()
+	return service.NewFlashcardService(pdfService, service.NewFakeProvider(service.FakeResponse{Text: testDeck}))
+}
+
 func TestA2AHandler_ServeHTTP_MethodValidation(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	tests := []struct {
@@ -53,7 +73,7 @@ func TestA2AHandler_ServeHTTP_MethodValidation(t *testing.T) {
 
 func TestA2AHandler_ServeHTTP_JSONRPCValidation(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	tests := []struct {
@@ -109,7 +129,7 @@ func TestA2AHandler_ServeHTTP_JSONRPCValidation(t *testing.T) {
 
 func TestA2AHandler_ServeHTTP_ValidRequest(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	requestBody := models.JSONRPCRequest{
@@ -163,7 +183,7 @@ func TestA2AHandler_ServeHTTP_ValidRequest(t *testing.T) {
 
 func TestA2AHandler_ExtractMessage(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	tests := []struct {
@@ -222,9 +242,30 @@ func TestA2AHandler_ExtractMessage(t *testing.T) {
 	}
 }
 
+func TestA2AHandler_ServeHTTP_BodyLimit(t *testing.T) {
+	pdfService := service.NewPDFService()
+	pdfService.MaxSize = 64
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	body := `{"jsonrpc":"2.0","id":"req-1","method":"message/send","params":{"message":{"kind":"message","role":"user","messageId":"msg-001",
+		"parts":[{"kind":"file","file":{"bytes":"` + strings.Repeat("A", int(handler.maxBodySize())) + `"}}]}}}`
+	req := httptest.NewRequest(http.MethodPost, "/a2a", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var response models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != models.InvalidRequest || response.Error.Message != "Request too large" {
+		t.Errorf("Expected a request too large error, got %+v", response.Error)
+	}
+}
+
 func TestA2AHandler_ExtractUserInput(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	tests := []struct {
@@ -273,9 +314,138 @@ func TestA2AHandler_ExtractUserInput(t *testing.T) {
 	}
 }
 
+func TestA2AHandler_ExtractFiles(t *testing.T) {
+	pdfService := service.NewPDFService()
+	pdfService.MaxSize = 64
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	pdf := "%PDF-1.4 test"
+	encoded := base64.StdEncoding.EncodeToString([]byte(pdf))
+	oversized := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), 100))
+	// Files within the size limit, but too large together
+	large := append([]byte("%PDF-1.4 "), bytes.Repeat([]byte("x"), 51)...)
+	var largeParts []models.MessagePart
+	var largeFiles []service.PDFFile
+	for i := 0; i < maxInlinePDFs; i++ {
+		largeParts = append(largeParts, models.MessagePart{Kind: "file", File: &models.FileContent{Bytes: base64.StdEncoding.EncodeToString(large)}})
+		largeFiles = append(largeFiles, service.PDFFile{Data: large})
+	}
+
+	tests := []struct {
+		name      string
+		parts     []models.MessagePart
+		wantFiles []service.PDFFile
+		wantCode  int
+	}{
+		{
+			name: "File part with bytes",
+			parts: []models.MessagePart{
+				{Kind: "file", File: &models.FileContent{Name: "notes.pdf", MimeType: "application/pdf", Bytes: encoded}},
+			},
+			wantFiles: []service.PDFFile{{Name: "notes.pdf", Data: []byte(pdf)}},
+		},
+		{
+			name: "File part with uri",
+			parts: []models.MessagePart{
+				{Kind: "file", File: &models.FileContent{Name: "paper.pdf", URI: "https://example.com/paper.pdf"}},
+			},
+			wantFiles: []service.PDFFile{{Name: "paper.pdf", URI: "https://example.com/paper.pdf"}},
+		},
+		{
+			name: "Multiple files",
+			parts: []models.MessagePart{
+				{Kind: "text", Text: "Make one deck"},
+				{Kind: "file", File: &models.FileContent{Name: "a.pdf", Bytes: encoded}},
+				{Kind: "file", File: &models.FileContent{Name: "b.pdf", URI: "http://example.com/b.pdf"}},
+			},
+			wantFiles: []service.PDFFile{
+				{Name: "a.pdf", Data: []byte(pdf)},
+				{Name: "b.pdf", URI: "http://example.com/b.pdf"},
+			},
+		},
+		{
+			name: "Legacy data part",
+			parts: []models.MessagePart{
+				{Kind: "data", Data: map[string]interface{}{"contentType": "application/pdf", "data": encoded}},
+			},
+			wantFiles: []service.PDFFile{{Data: []byte(pdf)}},
+		},
+		{
+			name:  "Unrelated data part",
+			parts: []models.MessagePart{{Kind: "data", Data: map[string]interface{}{"key": "value"}}},
+		},
+		{
+			name:     "Invalid base64",
+			parts:    []models.MessagePart{{Kind: "file", File: &models.FileContent{Bytes: "not base64!"}}},
+			wantCode: models.InvalidParams,
+		},
+		{
+			name:     "Too large",
+			parts:    []models.MessagePart{{Kind: "file", File: &models.FileContent{Bytes: oversized}}},
+			wantCode: models.InvalidParams,
+		},
+		{
+			name:      "Combined within the limit",
+			parts:     largeParts,
+			wantFiles: largeFiles,
+		},
+		{
+			name:     "Combined too large",
+			parts:    append(largeParts, largeParts[0]),
+			wantCode: models.InvalidParams,
+		},
+		{
+			name:     "Unsupported mime type",
+			parts:    []models.MessagePart{{Kind: "file", File: &models.FileContent{MimeType: "image/png", Bytes: encoded}}},
+			wantCode: models.ContentTypeNotSupportedError,
+		},
+		{
+			name:     "Both bytes and uri",
+			parts:    []models.MessagePart{{Kind: "file", File: &models.FileContent{Bytes: encoded, URI: "https://example.com/a.pdf"}}},
+			wantCode: models.InvalidParams,
+		},
+		{
+			name:     "Non-http uri",
+			parts:    []models.MessagePart{{Kind: "file", File: &models.FileContent{URI: "file:///etc/passwd"}}},
+			wantCode: models.InvalidParams,
+		},
+		{
+			name:     "Missing file",
+			parts:    []models.MessagePart{{Kind: "file"}},
+			wantCode: models.InvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := handler.extractFiles(&models.Message{Parts: tt.parts})
+			if tt.wantCode != 0 {
+				var appErr *apperrors.AppError
+				if !errors.As(err, &appErr) || appErr.Code != tt.wantCode {
+					t.Errorf("Expected error code %d, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(files) != len(tt.wantFiles) {
+				t.Fatalf("Expected %d files, got %d", len(tt.wantFiles), len(files))
+			}
+			for i, want := range tt.wantFiles {
+				got := files[i]
+				if got.Name != want.Name || got.URI != want.URI || !bytes.Equal(got.Data, want.Data) {
+					t.Errorf("File %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
 func TestA2AHandler_BuildTaskResult(t *testing.T) {
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, "test-api-key")
+	flashcardService := newTestFlashcardService(t, pdfService)
 	handler := NewA2AHandler(flashcardService)
 
 	flashcards := &models.FlashcardSet{
@@ -298,7 +468,7 @@ func TestA2AHandler_BuildTaskResult(t *testing.T) {
 		},
 	}
 
-	result := handler.buildTaskResult(flashcards, userMsg)
+	result := handler.buildTaskResult(flashcards, userMsg, nil)
 
 	if result.ID != "task-001" {
 		t.Errorf("Expected task ID task-001, got %s", result.ID)
@@ -324,3 +494,862 @@ func TestA2AHandler_BuildTaskResult(t *testing.T) {
 		t.Errorf("Expected artifact name flashcardSet, got %s", result.Artifacts[0].Name)
 	}
 }
+
+func TestA2AHandler_TasksGet(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	task := &models.TaskResult{
+		ID:        "task-001",
+		ContextID: "ctx-001",
+		Status:    models.Status{State: models.StateCompleted},
+		History: []models.Message{
+			{Kind: "message", Role: models.RoleUser, MessageID: "msg-001"},
+			{Kind: "message", Role: models.RoleAgent, MessageID: "msg-002"},
+		},
+		Kind:  "task",
+		Owner: "alice",
+	}
+	if err := taskStore.Save(task); err != nil {
+		t.Fatalf("Failed to save task: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		owner           string
+		params          string
+		expectedError   int
+		expectedHistory int
+	}{
+		{
+			name:            "Existing task returns full history",
+			owner:           "alice",
+			params:          `{"id":"task-001"}`,
+			expectedHistory: 2,
+		},
+		{
+			name:            "historyLength trims to most recent messages",
+			owner:           "alice",
+			params:          `{"id":"task-001","historyLength":1}`,
+			expectedHistory: 1,
+		},
+		{
+			name:          "Another user's task returns task not found",
+			owner:         "bob",
+			params:        `{"id":"task-001"}`,
+			expectedError: models.TaskNotFoundError,
+		},
+		{
+			name:          "Task without the owner header returns task not found",
+			params:        `{"id":"task-001"}`,
+			expectedError: models.TaskNotFoundError,
+		},
+		{
+			name:          "Unknown task returns task not found",
+			params:        `{"id":"missing"}`,
+			expectedError: models.TaskNotFoundError,
+		},
+		{
+			name:          "Missing id returns invalid params",
+			params:        `{}`,
+			expectedError: models.InvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"jsonrpc":"2.0","id":"req-1","method":"tasks/get","params":` + tt.params + `}`
+			req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewBufferString(body))
+			req.Header.Set(OwnerHeader, tt.owner)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			var response struct {
+				Result *models.TaskResult `json:"result"`
+				Error  *models.RPCError   `json:"error"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if tt.expectedError != 0 {
+				if response.Error == nil || response.Error.Code != tt.expectedError {
+					t.Fatalf("Expected error code %d, got %+v", tt.expectedError, response.Error)
+				}
+				return
+			}
+
+			if response.Error != nil {
+				t.Fatalf("Expected no error but got: %+v", response.Error)
+			}
+			if response.Result.ID != "task-001" {
+				t.Errorf("Expected task ID task-001, got %s", response.Result.ID)
+			}
+			if len(response.Result.History) != tt.expectedHistory {
+				t.Errorf("Expected %d messages in history, got %d", tt.expectedHistory, len(response.Result.History))
+			}
+		})
+	}
+}
+
+func TestA2AHandler_MessageSend_NonBlocking(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	body := `{"jsonrpc":"2.0","id":"req-1","method":"message/send","params":{
+		"message":{"kind":"message","role":"user","messageId":"msg-001","taskId":"task-async",
+			"parts":[{"kind":"text","text":"Generate flashcards about basic mathematics."}]},
+		"configuration":{"blocking":false}}}`
+	req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewBufferString(body))
+	req.Header.Set(OwnerHeader, "alice")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	var response struct {
+		Result *models.TaskResult `json:"result"`
+		Error  *models.RPCError   `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error != nil {
+		t.Fatalf("Expected no error but got: %+v", response.Error)
+	}
+	if response.Result.ID != "task-async" {
+		t.Errorf("Expected task ID task-async, got %s", response.Result.ID)
+	}
+	if response.Result.Status.State != models.StateSubmitted {
+		t.Errorf("Expected state submitted, got %s", response.Result.Status.State)
+	}
+
+	if err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to drain workers: %v", err)
+	}
+
+	// The task completes in the background and keeps the context ID and
+	// owner it was submitted with
+	task, err := taskStore.Get("task-async")
+	if err != nil {
+		t.Fatalf("Expected task in store, got: %v", err)
+	}
+	if task.Status.State != models.StateCompleted {
+		t.Errorf("Expected state completed, got %s", task.Status.State)
+	}
+	if len(task.Artifacts) != 1 {
+		t.Errorf("Expected 1 artifact, got %d", len(task.Artifacts))
+	}
+	if task.ContextID != response.Result.ContextID {
+		t.Errorf("Expected context ID %s, got %s", response.Result.ContextID, task.ContextID)
+	}
+	if task.Owner != "alice" {
+		t.Errorf("Expected owner alice, got %q", task.Owner)
+	}
+}
+
+func TestA2AHandler_MessageStream(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	body := `{"jsonrpc":"2.0","id":"req-1","method":"message/stream","params":{
+		"message":{"kind":"message","role":"user","messageId":"msg-001","taskId":"task-stream",
+			"parts":[{"kind":"text","text":"Generate flashcards about basic mathematics."}]}}}`
+	req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	var events []map[string]interface{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event models.JSONRPCResponse
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.ID != "req-1" {
+			t.Errorf("Expected event ID req-1, got %s", event.ID)
+		}
+		events = append(events, event.Result.(map[string]interface{}))
+	}
+
+	if len(events) < 2 {
+		t.Fatalf("Expected at least 2 events, got %d", len(events))
+	}
+	if kind := events[0]["kind"]; kind != models.KindTask {
+		t.Errorf("Expected first event to be a task, got %v", kind)
+	}
+
+	last := events[len(events)-1]
+	if last["kind"] != models.KindStatusUpdate || last["final"] != true {
+		t.Errorf("Expected final status-update as last event, got %v", last)
+	}
+	if state := last["status"].(map[string]interface{})["state"]; state != models.StateCompleted {
+		t.Errorf("Expected final state completed, got %v", state)
+	}
+
+	var cards []map[string]interface{}
+	for _, event := range events {
+		if event["kind"] == models.KindArtifactUpdate {
+			cards = append(cards, event)
+		}
+	}
+	if len(cards) != 2 {
+		t.Fatalf("Expected 2 artifact-update events, got %d", len(cards))
+	}
+	if cards[0]["append"] == true || cards[0]["lastChunk"] == true {
+		t.Errorf("Expected first card to start the artifact, got %v", cards[0])
+	}
+	if cards[1]["append"] != true || cards[1]["lastChunk"] != true {
+		t.Errorf("Expected last card to append and close the artifact, got %v", cards[1])
+	}
+	for _, event := range events[:len(events)-1] {
+		if event["final"] == true {
+			t.Error("Expected only the last event to be final")
+		}
+	}
+}
+
+func TestA2AHandler_TasksCancel(t *testing.T) {
+	pdfService := service.NewPDFService()
+	pdfService.AllowedNetworks, _ = netguard.ParseNetworks("127.0.0.1")
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	// The PDF server hangs until the download is aborted
+	downloadStarted := make(chan struct{})
+	downloadAborted := make(chan struct{})
+	pdfServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(downloadStarted)
+		<-r.Context().Done()
+		close(downloadAborted)
+	}))
+	defer pdfServer.Close()
+
+	taskStore.Save(&models.TaskResult{
+		ID:     "task-done",
+		Status: models.Status{State: models.StateCompleted},
+		Kind:   models.KindTask,
+		Owner:  "alice",
+	})
+
+	callAs := func(owner, method, params string) (*models.TaskResult, *models.RPCError) {
+		body := `{"jsonrpc":"2.0","id":"req-1","method":"` + method + `","params":` + params + `}`
+		req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewBufferString(body))
+		req.Header.Set(OwnerHeader, owner)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var response struct {
+			Result *models.TaskResult `json:"result"`
+			Error  *models.RPCError   `json:"error"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Result, response.Error
+	}
+	call := func(method, params string) (*models.TaskResult, *models.RPCError) {
+		return callAs("alice", method, params)
+	}
+
+	_, rpcErr := call("message/send", `{
+		"message":{"kind":"message","role":"user","messageId":"msg-001","taskId":"task-slow",
+			"parts":[{"kind":"text","text":"Generate flashcards from `+pdfServer.URL+`/slow.pdf"}]},
+		"configuration":{"blocking":false}}`)
+	if rpcErr != nil {
+		t.Fatalf("Expected no error but got: %+v", rpcErr)
+	}
+
+	select {
+	case <-downloadStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Download never started")
+	}
+
+	// Another user can't cancel the task
+	if _, rpcErr := callAs("bob", "tasks/cancel", `{"id":"task-slow"}`); rpcErr == nil || rpcErr.Code != models.TaskNotFoundError {
+		t.Errorf("Expected another user's cancel to report the task not found, got %+v", rpcErr)
+	}
+	if stored, _ := taskStore.Get("task-slow"); models.IsTerminalState(stored.Status.State) {
+		t.Errorf("Expected the task to keep running, got state %s", stored.Status.State)
+	}
+
+	task, rpcErr := call("tasks/cancel", `{"id":"task-slow"}`)
+	if rpcErr != nil {
+		t.Fatalf("Expected no error but got: %+v", rpcErr)
+	}
+	if task.Status.State != models.StateCanceled {
+		t.Errorf("Expected state canceled, got %s", task.Status.State)
+	}
+
+	select {
+	case <-downloadAborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected cancel to abort the in-flight download")
+	}
+
+	if err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to drain workers: %v", err)
+	}
+	stored, _ := taskStore.Get("task-slow")
+	if stored.Status.State != models.StateCanceled {
+		t.Errorf("Expected stored state canceled, got %s", stored.Status.State)
+	}
+
+	tests := []struct {
+		name          string
+		params        string
+		expectedError int
+	}{
+		{"Already canceled task", `{"id":"task-slow"}`, models.TaskNotCancelableError},
+		{"Completed task", `{"id":"task-done"}`, models.TaskNotCancelableError},
+		{"Unknown task", `{"id":"missing"}`, models.TaskNotFoundError},
+		{"Missing id", `{}`, models.InvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rpcErr := call("tasks/cancel", tt.params)
+			if rpcErr == nil || rpcErr.Code != tt.expectedError {
+				t.Errorf("Expected error code %d, got %+v", tt.expectedError, rpcErr)
+			}
+		})
+	}
+}
+
+func TestA2AHandler_PushNotificationConfig(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	taskStore.Save(&models.TaskResult{
+		ID:     "task-001",
+		Status: models.Status{State: models.StateWorking},
+		Kind:   models.KindTask,
+		Owner:  "alice",
+	})
+
+	callAs := func(owner, method, params string) (json.RawMessage, *models.RPCError) {
+		body := `{"jsonrpc":"2.0","id":"req-1","method":"` + method + `","params":` + params + `}`
+		req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewBufferString(body))
+		req.Header.Set(OwnerHeader, owner)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var response struct {
+			Result json.RawMessage  `json:"result"`
+			Error  *models.RPCError `json:"error"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response.Result, response.Error
+	}
+	call := func(method, params string) (json.RawMessage, *models.RPCError) {
+		return callAs("alice", method, params)
+	}
+
+	result, rpcErr := call("tasks/pushNotificationConfig/set",
+		`{"taskId":"task-001","pushNotificationConfig":{"id":"cfg-001","url":"https://example.com/hook","token":"abc"}}`)
+	if rpcErr != nil {
+		t.Fatalf("Expected no error but got: %+v", rpcErr)
+	}
+	var set models.TaskPushNotificationConfig
+	json.Unmarshal(result, &set)
+	if set.TaskID != "task-001" || set.PushNotificationConfig.Token != "abc" {
+		t.Errorf("Unexpected set result: %+v", set)
+	}
+
+	result, rpcErr = call("tasks/pushNotificationConfig/get", `{"id":"task-001","pushNotificationConfigId":"cfg-001"}`)
+	if rpcErr != nil {
+		t.Fatalf("Expected no error but got: %+v", rpcErr)
+	}
+	var got models.TaskPushNotificationConfig
+	json.Unmarshal(result, &got)
+	if got.PushNotificationConfig.URL != "https://example.com/hook" {
+		t.Errorf("Unexpected get result: %+v", got)
+	}
+
+	result, _ = call("tasks/pushNotificationConfig/list", `{"id":"task-001"}`)
+	var list []models.TaskPushNotificationConfig
+	json.Unmarshal(result, &list)
+	if len(list) != 1 {
+		t.Errorf("Expected 1 config, got %d", len(list))
+	}
+
+	// Another user can't see, change or delete the task's configs
+	for _, tt := range []struct{ method, params string }{
+		{"tasks/pushNotificationConfig/set", `{"taskId":"task-001","pushNotificationConfig":{"url":"https://attacker.example.com/hook"}}`},
+		{"tasks/pushNotificationConfig/get", `{"id":"task-001","pushNotificationConfigId":"cfg-001"}`},
+		{"tasks/pushNotificationConfig/list", `{"id":"task-001"}`},
+		{"tasks/pushNotificationConfig/delete", `{"id":"task-001","pushNotificationConfigId":"cfg-001"}`},
+	} {
+		for _, owner := range []string{"bob", ""} {
+			if _, rpcErr := callAs(owner, tt.method, tt.params); rpcErr == nil || rpcErr.Code != models.TaskNotFoundError {
+				t.Errorf("Expected %s as %q to report the task not found, got %+v", tt.method, owner, rpcErr)
+			}
+		}
+	}
+	if configs, _ := handler.notifier.Configs().List("task-001"); len(configs) != 1 || configs[0].ID != "cfg-001" {
+		t.Errorf("Expected only the owner's config, got %+v", configs)
+	}
+
+	if _, rpcErr = call("tasks/pushNotificationConfig/delete", `{"id":"task-001","pushNotificationConfigId":"cfg-001"}`); rpcErr != nil {
+		t.Errorf("Expected no error but got: %+v", rpcErr)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		params        string
+		expectedError int
+	}{
+		{"Get deleted config", "tasks/pushNotificationConfig/get", `{"id":"task-001","pushNotificationConfigId":"cfg-001"}`, models.InvalidParams},
+		{"Set on unknown task", "tasks/pushNotificationConfig/set", `{"taskId":"missing","pushNotificationConfig":{"url":"https://example.com/hook"}}`, models.TaskNotFoundError},
+		{"Set with invalid URL", "tasks/pushNotificationConfig/set", `{"taskId":"task-001","pushNotificationConfig":{"url":"ftp://example.com"}}`, models.InvalidParams},
+		{"Set with a loopback URL", "tasks/pushNotificationConfig/set", `{"taskId":"task-001","pushNotificationConfig":{"url":"http://127.0.0.1:8080/hook"}}`, models.InvalidParams},
+		{"Set with a metadata URL", "tasks/pushNotificationConfig/set", `{"taskId":"task-001","pushNotificationConfig":{"url":"http://169.254.169.254/latest/meta-data/"}}`, models.InvalidParams},
+		{"List on unknown task", "tasks/pushNotificationConfig/list", `{"id":"missing"}`, models.TaskNotFoundError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rpcErr := call(tt.method, tt.params)
+			if rpcErr == nil || rpcErr.Code != tt.expectedError {
+				t.Errorf("Expected error code %d, got %+v", tt.expectedError, rpcErr)
+			}
+		})
+	}
+}
+
+func TestA2AHandler_PushDeliveriesHandler(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	task := &models.TaskResult{
+		ID:     "task-001",
+		Status: models.Status{State: models.StateWorking},
+		Kind:   models.KindTask,
+		Owner:  "alice",
+	}
+	taskStore.Save(task)
+
+	if encoded, _ := json.Marshal(task); bytes.Contains(encoded, []byte("alice")) {
+		t.Errorf("Expected the owner to be left out of the task JSON, got %s", encoded)
+	}
+
+	tests := []struct {
+		name           string
+		owner          string
+		query          string
+		expectedStatus int
+	}{
+		{"Owner", "alice", "?taskId=task-001", http.StatusOK},
+		{"Another user", "bob", "?taskId=task-001", http.StatusNotFound},
+		{"No user", "", "?taskId=task-001", http.StatusNotFound},
+		{"Unknown task", "alice", "?taskId=missing", http.StatusNotFound},
+		{"Missing taskId", "alice", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/push/deliveries"+tt.query, nil)
+			req.Header.Set(OwnerHeader, tt.owner)
+			w := httptest.NewRecorder()
+			handler.PushDeliveriesHandler()(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && strings.TrimSpace(w.Body.String()) != "[]" {
+				t.Errorf("Expected no deliveries, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestA2AHandler_BuildTaskResult_KeepsContextID(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	conversations := store.NewMemoryConversationStore()
+	handler := NewA2AHandler(flashcardService, WithConversationStore(conversations))
+
+	flashcards := &models.FlashcardSet{
+		Title:      "Test Flashcards",
+		TotalCards: 1,
+		Flashcards: []models.Flashcard{{Question: "Q1", Answer: "A1", Topic: "Topic1"}},
+	}
+	userMsg := &models.Message{
+		Kind:      "message",
+		Role:      "user",
+		MessageID: "msg-001",
+		ContextID: "ctx-001",
+		Parts:     []models.MessagePart{{Kind: "text", Text: "Generate flashcards"}},
+	}
+
+	result := handler.buildTaskResult(flashcards, userMsg, nil)
+	if result.ContextID != "ctx-001" {
+		t.Errorf("Expected context ID ctx-001, got %s", result.ContextID)
+	}
+	if result.Status.Message.ContextID != "ctx-001" {
+		t.Errorf("Expected agent message context ID ctx-001, got %s", result.Status.Message.ContextID)
+	}
+
+	conversation, err := handler.loadConversation(context.Background(), "ctx-001")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	handler.updateConversation(conversation, result, flashcards)
+
+	conversation, err = conversations.Get("ctx-001")
+	if err != nil {
+		t.Fatalf("Expected conversation to be saved, got: %v", err)
+	}
+	if len(conversation.History) != 2 {
+		t.Errorf("Expected 2 messages in conversation, got %d", len(conversation.History))
+	}
+	if conversation.Deck != flashcards {
+		t.Error("Expected the generated deck to become the conversation deck")
+	}
+
+	// A follow-up task in the same context keeps the client's context ID
+	followUp, err := handler.newTask(&models.Message{MessageID: "msg-002", ContextID: "ctx-001"}, "")
+	if err != nil || followUp.ContextID != "ctx-001" {
+		t.Errorf("Expected follow-up context ID ctx-001, got %+v, %v", followUp, err)
+	}
+}
+
+func TestA2AHandler_NewTask_ExistingTaskID(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	taskStore := store.NewMemoryTaskStore()
+	handler := NewA2AHandler(flashcardService, WithTaskStore(taskStore))
+
+	earlier := models.Message{Kind: models.KindMessage, Role: models.RoleUser, MessageID: "msg-001"}
+	for _, task := range []*models.TaskResult{
+		{ID: "task-done", ContextID: "ctx-001", Status: models.Status{State: models.StateCompleted}, Owner: "alice"},
+		{ID: "task-running", ContextID: "ctx-001", Status: models.Status{State: models.StateWorking}, Owner: "alice"},
+		{ID: "task-waiting", ContextID: "ctx-001", Status: models.Status{State: models.StateSubmitted}, History: []models.Message{earlier}, Owner: "alice"},
+	} {
+		taskStore.Save(task)
+	}
+	handler.startTask(context.Background(), "task-running")
+
+	tests := []struct {
+		name          string
+		taskID        string
+		contextID     string
+		owner         string
+		expectedError int
+	}{
+		{"Unknown task", "task-new", "", "alice", 0},
+		{"Active task", "task-waiting", "", "alice", 0},
+		{"Another user's task", "task-waiting", "", "bob", models.TaskNotFoundError},
+		{"Finished task", "task-done", "", "alice", models.InvalidParams},
+		{"Running task", "task-running", "", "alice", models.InvalidParams},
+		{"Another context", "task-waiting", "ctx-002", "alice", models.InvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.Message{Kind: models.KindMessage, Role: models.RoleUser, MessageID: "msg-002", TaskID: tt.taskID, ContextID: tt.contextID}
+			task, err := handler.newTask(msg, tt.owner)
+
+			if tt.expectedError != 0 {
+				var appErr *apperrors.AppError
+				if !errors.As(err, &appErr) || appErr.Code != tt.expectedError {
+					t.Errorf("Expected error code %d, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if task.ID != tt.taskID || task.Owner != tt.owner || task.Status.State != models.StateSubmitted {
+				t.Errorf("Expected a submitted task %s for %s, got %+v", tt.taskID, tt.owner, task)
+			}
+			if last := task.History[len(task.History)-1]; last.MessageID != "msg-002" {
+				t.Errorf("Expected the new message last in the history, got %s", last.MessageID)
+			}
+		})
+	}
+
+	// A continued task keeps its context and earlier messages
+	task, _ := handler.newTask(&models.Message{MessageID: "msg-002", TaskID: "task-waiting"}, "alice")
+	if task.ContextID != "ctx-001" || len(task.History) != 2 || task.History[0].MessageID != "msg-001" {
+		t.Errorf("Expected the task's context and history to carry over, got %+v", task)
+	}
+
+	// Rejected messages leave the stored task alone
+	if stored, _ := taskStore.Get("task-done"); stored.Status.State != models.StateCompleted || stored.Owner != "alice" {
+		t.Errorf("Expected the finished task to be unchanged, got %+v", stored)
+	}
+}
+
+func TestA2AHandler_ProcessRequest_ConversationOwner(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	conversations := store.NewMemoryConversationStore()
+	handler := NewA2AHandler(flashcardService, WithConversationStore(conversations))
+
+	deck := &models.FlashcardSet{
+		Title:      "Biology",
+		TotalCards: 1,
+		Flashcards: []models.Flashcard{{Question: "Q1", Answer: "A1", Topic: "Topic1"}},
+	}
+	conversations.Save(&models.Conversation{ContextID: "ctx-001", Owner: "alice", Deck: deck})
+
+	if conversation, err := handler.loadConversation(withOwner(context.Background(), "alice"), "ctx-001"); err != nil || conversation.Deck == nil {
+		t.Errorf("Expected the owner to load the conversation, got %+v, %v", conversation, err)
+	}
+	if conversation, err := handler.loadConversation(withOwner(context.Background(), "bob"), "ctx-002"); err != nil || conversation.Owner != "bob" {
+		t.Errorf("Expected a new conversation owned by bob, got %+v, %v", conversation, err)
+	}
+
+	for _, owner := range []string{"bob", ""} {
+		t.Run("Owner "+owner, func(t *testing.T) {
+			msg := &models.Message{
+				Kind:      models.KindMessage,
+				Role:      models.RoleUser,
+				MessageID: "msg-002",
+				ContextID: "ctx-001",
+				Parts:     []models.MessagePart{{Kind: models.KindText, Text: "Make them harder"}},
+			}
+			_, err := handler.processRequest(withOwner(context.Background(), owner), "Make them harder", msg, nil, nil)
+
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) || appErr.Code != models.InvalidParams {
+				t.Errorf("Expected an InvalidParams error, got %v", err)
+			}
+		})
+	}
+
+	conversation, _ := conversations.Get("ctx-001")
+	if conversation.Owner != "alice" || conversation.Deck != deck || len(conversation.History) != 0 {
+		t.Errorf("Expected the conversation to be left alone, got %+v", conversation)
+	}
+}
+
+func TestNegotiateOutputModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		accepted []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "defaults", accepted: nil, want: []string{ModeMarkdown, ModeJSON}},
+		{name: "json only", accepted: []string{"application/json"}, want: []string{ModeJSON}},
+		{name: "text aliases", accepted: []string{"text", "text/plain", "text/markdown"}, want: []string{ModeMarkdown}},
+		{name: "skips unsupported", accepted: []string{"image/png", "text/csv"}, want: []string{ModeCSV}},
+		{name: "export formats", accepted: []string{"text/tab-separated-values", "text/x-quizlet", "application/x-ndjson", "application/jsonl"}, want: []string{ModeTSV, ModeQuizlet, ModeJSONL}},
+		{name: "none supported", accepted: []string{"image/png"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modes, err := negotiateOutputModes(&models.Configuration{AcceptedOutputModes: tt.accepted})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got modes %v", modes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(modes, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, modes)
+			}
+		})
+	}
+}
+
+func TestA2AHandler_BuildTaskResult_OutputModes(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	flashcards := &models.FlashcardSet{
+		Title:      "Test Flashcards",
+		Source:     "test",
+		TotalCards: 1,
+		Flashcards: []models.Flashcard{{Question: "Q1", Answer: "A1, with comma", Topic: "Topic1"}},
+	}
+	userMsg := &models.Message{Kind: "message", Role: "user", MessageID: "msg-001"}
+
+	result := handler.buildTaskResult(flashcards, userMsg, []string{ModeJSON, ModeCSV})
+	parts := result.Artifacts[0].Parts
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 artifact parts, got %d", len(parts))
+	}
+
+	if parts[0].Kind != models.KindData || parts[0].Data != flashcards {
+		t.Errorf("Expected data part carrying the flashcard set, got %+v", parts[0])
+	}
+	if metadata, _ := parts[0].Metadata.(map[string]interface{}); metadata["schema"] == nil {
+		t.Error("Expected data part to declare its schema")
+	}
+
+	if partMimeType(parts[1]) != ModeCSV {
+		t.Errorf("Expected CSV part, got metadata %v", parts[1].Metadata)
+	}
+	if !strings.Contains(parts[1].Text, `"A1, with comma"`) {
+		t.Errorf("Expected quoted CSV field, got %q", parts[1].Text)
+	}
+	if metadata, _ := parts[1].Metadata.(map[string]interface{}); metadata["filename"] != "test-flashcards.csv" {
+		t.Errorf("Expected the CSV filename, got metadata %v", parts[1].Metadata)
+	}
+
+	// Without a text mode the status message only summarizes the set
+	if strings.Contains(result.Status.Message.Parts[0].Text, "Q1") {
+		t.Errorf("Expected summary status message, got %q", result.Status.Message.Parts[0].Text)
+	}
+}
+
+func TestFlashcardSetSchema_CoversFields(t *testing.T) {
+	properties := models.FlashcardSetSchema["properties"].(map[string]interface{})
+	setType := reflect.TypeOf(models.FlashcardSet{})
+	for i := 0; i < setType.NumField(); i++ {
+		name, _, _ := strings.Cut(setType.Field(i).Tag.Get("json"), ",")
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Expected the FlashcardSet schema to declare %q", name)
+		}
+	}
+}
+
+func TestA2AHandler_BuildTaskResult_APKG(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	flashcards := &models.FlashcardSet{
+		Title:      "Test Flashcards",
+		Source:     "test",
+		TotalCards: 1,
+		Flashcards: []models.Flashcard{{Question: "Q1", Answer: "A1", Topic: "Topic1"}},
+	}
+	userMsg := &models.Message{Kind: "message", Role: "user", MessageID: "msg-001"}
+
+	result := handler.buildTaskResult(flashcards, userMsg, []string{ModeMarkdown, ModeAPKG})
+	parts := result.Artifacts[0].Parts
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 artifact parts, got %d", len(parts))
+	}
+
+	file := parts[1].File
+	if parts[1].Kind != models.KindFile || file == nil {
+		t.Fatalf("Expected a file part, got %+v", parts[1])
+	}
+	if file.Name != "test-flashcards.apkg" || file.MimeType != ModeAPKG {
+		t.Errorf("Expected test-flashcards.apkg as %s, got %s as %s", ModeAPKG, file.Name, file.MimeType)
+	}
+	data, err := base64.StdEncoding.DecodeString(file.Bytes)
+	if err != nil {
+		t.Fatalf("Expected base64 bytes, got: %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Errorf("Expected a zip package, got: %v", err)
+	}
+	if got := wholeSetParts(parts); len(got) != 1 || got[0].File != file {
+		t.Errorf("Expected the package to be sent with the whole set, got %+v", got)
+	}
+}
+
+func TestA2AHandler_MessageSend_UnsupportedOutputMode(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	body := `{"jsonrpc":"2.0","id":"req-001","method":"message/send","params":{` +
+		`"message":{"kind":"message","role":"user","messageId":"msg-001","parts":[{"kind":"text","text":"Photosynthesis"}]},` +
+		`"configuration":{"acceptedOutputModes":["image/png"]}}}`
+	req := httptest.NewRequest(http.MethodPost, "/a2a", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var resp models.JSONRPCResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != models.ContentTypeNotSupportedError {
+		t.Errorf("Expected error code %d, got %+v", models.ContentTypeNotSupportedError, resp.Error)
+	}
+}
+
+func TestExtractGenerationOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     models.Message
+		want    models.GenerationOptions
+		wantErr bool
+	}{
+		{name: "none", msg: models.Message{Parts: []models.MessagePart{{Kind: models.KindText, Text: "notes"}}}},
+		{
+			name: "metadata",
+			msg: models.Message{Metadata: map[string]interface{}{
+				"generationOptions": map[string]interface{}{"cardCount": float64(12), "language": "Hausa"},
+			}},
+			want: models.GenerationOptions{CardCount: 12, Language: "Hausa"},
+		},
+		{
+			name: "data part overrides metadata",
+			msg: models.Message{
+				Metadata: map[string]interface{}{
+					"generationOptions": map[string]interface{}{"cardCount": float64(12), "audience": "nurses"},
+				},
+				Parts: []models.MessagePart{{Kind: models.KindData, Data: map[string]interface{}{
+					"generationOptions": map[string]interface{}{"cardCount": float64(8), "difficulty": "Easy"},
+				}}},
+			},
+			want: models.GenerationOptions{CardCount: 8, Difficulty: models.DifficultyEasy, Audience: "nurses"},
+		},
+		{
+			name: "wrong type",
+			msg: models.Message{Metadata: map[string]interface{}{
+				"generationOptions": map[string]interface{}{"cardCount": "twelve"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "invalid value",
+			msg: models.Message{Metadata: map[string]interface{}{
+				"generationOptions": map[string]interface{}{"cardCount": float64(1000)},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := extractGenerationOptions(&tt.msg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got options %+v", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(opts, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// AgentInfo holds the deployment-specific fields of the agent card.
+type AgentInfo struct {
+	Name        string
+	Description string
+	URL         string
+	Version     string
+}
+
+var (
+	defaultInputModes  = []string{"text/plain", "application/pdf", "application/json"}
+	defaultOutputModes = []string{ModeMarkdown, ModeJSON, ModeCSV, ModeTSV, ModeQuizlet, ModeJSONL, ModeAPKG}
+)
+
+var agentSkills = []models.AgentSkill{
+	{
+		ID:          "generate-from-pdf-url",
+		Name:        "Generate flashcards from a PDF URL",
+		Description: "Downloads the PDF linked in the message and turns its content into question and answer flashcards.",
+		Tags:        []string{"flashcards", "pdf", "study"},
+		Examples:    []string{"Generate flashcards from https://example.com/biology-notes.pdf"},
+		InputModes:  []string{"text/plain"},
+	},
+	{
+		ID:          "generate-from-upload",
+		Name:        "Generate flashcards from an uploaded PDF",
+		Description: "Turns a PDF sent inline in the message into question and answer flashcards.",
+		Tags:        []string{"flashcards", "pdf", "upload"},
+		InputModes:  []string{"application/pdf"},
+	},
+	{
+		ID:          "generate-from-text",
+		Name:        "Generate flashcards from text",
+		Description: "Creates flashcards about a topic or from study notes given as plain text.",
+		Tags:        []string{"flashcards", "text", "study"},
+		Examples:    []string{"Create flashcards about the water cycle"},
+		InputModes:  []string{"text/plain"},
+	},
+	{
+		ID:          "mark-answer",
+		Name:        "Mark a quiz answer",
+		Description: "Marks the learner's own answer to a saved card against the card's answer, lists the key points missed and schedules the card's next review. Send the card ID in a data part as {\"quizAnswer\": {\"cardId\": \"...\"}} with the answer as text or in \"answer\".",
+		Tags:        []string{"quiz", "review", "study"},
+		InputModes:  []string{"application/json", "text/plain"},
+	},
+}
+
+// AgentCard builds the agent card from info and the methods this handler
+// registers, so advertised capabilities can't drift from what ServeHTTP does.
+func (h *A2AHandler) AgentCard(info AgentInfo) models.AgentCard {
+	return models.AgentCard{
+		Name:               info.Name,
+		Description:        info.Description,
+		URL:                info.URL,
+		Version:            info.Version,
+		ProtocolVersion:    "0.3.0",
+		PreferredTransport: "JSONRPC",
+		Capabilities: models.AgentCapabilities{
+			Streaming:              h.SupportsMethod("message/stream"),
+			PushNotifications:      h.SupportsMethod("tasks/pushNotificationConfig/set"),
+			StateTransitionHistory: false, // task history holds messages, not transitions
+		},
+		DefaultInputModes:  defaultInputModes,
+		DefaultOutputModes: defaultOutputModes,
+		Skills:             agentSkills,
+	}
+}
+
+// AgentCardHandler serves the agent card as JSON.
+func (h *A2AHandler) AgentCardHandler(info AgentInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(h.AgentCard(info)); err != nil {
+			log.Printf("Error encoding agent card: %v", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+)
+
+func TestA2AHandler_AgentCardHandler(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	info := AgentInfo{
+		Name:        "Test Agent",
+		Description: "Test description",
+		URL:         "http://localhost:8080/a2a",
+		Version:     "1.2.3",
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/agent.json", nil)
+	w := httptest.NewRecorder()
+	handler.AgentCardHandler(info)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var card models.AgentCard
+	if err := json.NewDecoder(w.Body).Decode(&card); err != nil {
+		t.Fatalf("Failed to decode agent card: %v", err)
+	}
+
+	if card.Name != info.Name || card.URL != info.URL || card.Version != info.Version {
+		t.Errorf("Expected card to use agent info, got %+v", card)
+	}
+
+	if card.Capabilities.Streaming != handler.SupportsMethod("message/stream") {
+		t.Error("Streaming capability out of sync with registered methods")
+	}
+	if card.Capabilities.PushNotifications != handler.SupportsMethod("tasks/pushNotificationConfig/set") {
+		t.Error("Push notification capability out of sync with registered methods")
+	}
+	if card.Capabilities.StateTransitionHistory {
+		t.Error("Expected no state transition history, tasks only record messages")
+	}
+
+	skills := make(map[string]bool)
+	for _, skill := range card.Skills {
+		skills[skill.ID] = true
+	}
+	for _, id := range []string{"generate-from-pdf-url", "generate-from-upload", "generate-from-text", "mark-answer"} {
+		if !skills[id] {
+			t.Errorf("Expected skill %s in agent card", id)
+		}
+	}
+}
+
+func TestA2AHandler_AgentCardHandler_MethodNotAllowed(t *testing.T) {
+	pdfService := service.NewPDFService()
+	flashcardService := newTestFlashcardService(t, pdfService)
+	handler := NewA2AHandler(flashcardService)
+
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/agent.json", nil)
+	w := httptest.NewRecorder()
+	handler.AgentCardHandler(AgentInfo{})(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+// OwnerHeader names the user a request acts for. Lagbaja doesn't
+// authenticate users itself; a gateway in front of it is expected to set
+// the header. Requests without it share an anonymous library.
+const OwnerHeader = "X-User-ID"
+
+// maxCardPatchSize bounds a PATCH body, which holds a single card.
+const maxCardPatchSize = 1 << 20
+
+// RequestOwner returns the user r acts for.
+func RequestOwner(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(OwnerHeader))
+}
+
+type ownerKey struct{}
+
+// withOwner records the request's owner on ctx for work that outlives
+// the request.
+func withOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+func ownerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// DeckHandler serves the /decks REST API over the deck library. Users
+// only see their own decks; other users' decks are reported as not found.
+type DeckHandler struct {
+	decks store.DeckStore
+}
+
+func NewDeckHandler(decks store.DeckStore) *DeckHandler {
+	return &DeckHandler{decks: decks}
+}
+
+// Register adds the /decks routes to mux.
+func (h *DeckHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /decks", h.handleList)
+	mux.HandleFunc("GET /decks/{id}", h.handleGet)
+	mux.HandleFunc("DELETE /decks/{id}", h.handleDelete)
+	mux.HandleFunc("POST /decks/{id}/duplicate", h.handleDuplicate)
+	mux.HandleFunc("PATCH /decks/{id}/cards/{cardId}", h.handlePatchCard)
+}
+
+func (h *DeckHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	summaries, err := h.decks.List(RequestOwner(r))
+	if err != nil {
+		log.Printf("Error listing decks: %v", err)
+		http.Error(w, "Failed to list decks", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (h *DeckHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	deck, ok := h.ownedDeck(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, deck)
+}
+
+func (h *DeckHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	deck, ok := h.ownedDeck(w, r)
+	if !ok {
+		return
+	}
+	if err := h.decks.Delete(deck.ID); err != nil && !errors.Is(err, store.ErrDeckNotFound) {
+		log.Printf("Error deleting deck %s: %v", deck.ID, err)
+		http.Error(w, "Failed to delete deck", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDuplicate copies a deck under a new ID. The copy's cards get new
+// IDs too, so they are studied separately from the original's.
+func (h *DeckHandler) handleDuplicate(w http.ResponseWriter, r *http.Request) {
+	deck, ok := h.ownedDeck(w, r)
+	if !ok {
+		return
+	}
+
+	set := deck.Set
+	set.Title += " (copy)"
+	set.Flashcards = append([]models.Flashcard(nil), deck.Set.Flashcards...)
+	for i := range set.Flashcards {
+		set.Flashcards[i].ID = ""
+	}
+	duplicate := store.NewDeck(deck.Owner, &set)
+	if err := h.decks.Create(duplicate); err != nil {
+		log.Printf("Error duplicating deck %s: %v", deck.ID, err)
+		http.Error(w, "Failed to duplicate deck", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", "/decks/"+duplicate.ID)
+	writeJSON(w, http.StatusCreated, duplicate)
+}
+
+// handlePatchCard updates the fields of one card present in the body,
+// leaving the others as they were, and returns the updated deck.
+func (h *DeckHandler) handlePatchCard(w http.ResponseWriter, r *http.Request) {
+	deck, ok := h.ownedDeck(w, r)
+	if !ok {
+		return
+	}
+	index := deck.Card(r.PathValue("cardId"))
+	if index < 0 {
+		http.Error(w, "Card not found", http.StatusNotFound)
+		return
+	}
+
+	// Decoding writes through the card's pointers, so keep what's needed
+	// of the original by value
+	card := deck.Set.Flashcards[index]
+	id, evidence, grounded := card.ID, card.Evidence, card.Grounded != nil && *card.Grounded
+	checked := card.Grounded != nil
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCardPatchSize)).Decode(&card); err != nil {
+		http.Error(w, "Invalid flashcard JSON", http.StatusBadRequest)
+		return
+	}
+	// The ID names the card being patched, so it can't be changed
+	card.ID = id
+	if err := service.ValidateCard(&card); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if checked && card.Evidence == evidence {
+		card.Grounded = &grounded
+	}
+
+	deck.Set.Flashcards[index] = card
+	if err := h.decks.Update(deck); err != nil {
+		if errors.Is(err, store.ErrDeckNotFound) {
+			http.Error(w, "Deck not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error updating deck %s: %v", deck.ID, err)
+		http.Error(w, "Failed to update deck", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, deck)
+}
+
+// ownedDeck loads the deck named in the path, writing a 404 if it doesn't
+// exist or belongs to another user.
+func (h *DeckHandler) ownedDeck(w http.ResponseWriter, r *http.Request) (*models.Deck, bool) {
+	deck, err := h.decks.Get(r.PathValue("id"))
+	if err != nil && !errors.Is(err, store.ErrDeckNotFound) {
+		log.Printf("Error loading deck %s: %v", r.PathValue("id"), err)
+		http.Error(w, "Failed to load deck", http.StatusInternalServerError)
+		return nil, false
+	}
+	if err != nil || deck.Owner != RequestOwner(r) {
+		http.Error(w, "Deck not found", http.StatusNotFound)
+		return nil, false
+	}
+	return deck, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
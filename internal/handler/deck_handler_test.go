@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+// newDeckServer serves a DeckHandler over a store holding one deck owned
+// by alice.
+func newDeckServer(t *testing.T) (*http.ServeMux, store.DeckStore, *models.Deck) {
+	t.Helper()
+
+	grounded := true
+	decks := store.NewMemoryDeckStore()
+	deck := store.NewDeck("alice", &models.FlashcardSet{
+		Title: "Biology",
+		Flashcards: []models.Flashcard{
+			{Question: "Q1", Answer: "A1", Topic: "Topic1", Evidence: "Quote one", Grounded: &grounded},
+			{Question: "Q2", Answer: "A2", Topic: "Topic2"},
+		},
+	})
+	if err := decks.Create(deck); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	NewDeckHandler(decks).Register(mux)
+	return mux, decks, deck
+}
+
+func serveDeckRequest(mux *http.ServeMux, method, path, owner, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if owner != "" {
+		req.Header.Set(OwnerHeader, owner)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeckHandler_ListAndGet(t *testing.T) {
+	mux, _, deck := newDeckServer(t)
+
+	w := serveDeckRequest(mux, http.MethodGet, "/decks", "alice", "")
+	var summaries []models.DeckSummary
+	if err := json.NewDecoder(w.Body).Decode(&summaries); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].ID != deck.ID || summaries[0].TotalCards != 2 {
+		t.Errorf("Expected alice's deck, got %+v", summaries)
+	}
+
+	w = serveDeckRequest(mux, http.MethodGet, "/decks", "bob", "")
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("Expected an empty list for bob, got %s", w.Body.String())
+	}
+
+	w = serveDeckRequest(mux, http.MethodGet, "/decks/"+deck.ID, "alice", "")
+	var got models.Deck
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Set.Title != "Biology" || len(got.Set.Flashcards) != 2 {
+		t.Errorf("Expected the deck, got %d %+v", w.Code, got)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		owner string
+	}{
+		{"Missing deck", "/decks/missing", "alice"},
+		{"Another user's deck", "/decks/" + deck.ID, "bob"},
+		{"Anonymous user", "/decks/" + deck.ID, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveDeckRequest(mux, http.MethodGet, tt.path, tt.owner, ""); w.Code != http.StatusNotFound {
+				t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
+
+func TestDeckHandler_PatchCard(t *testing.T) {
+	tests := []struct {
+		name         string
+		card         string
+		body         string
+		wantStatus   int
+		wantAnswer   string
+		wantGrounded bool
+	}{
+		{"Answer only", "0", `{"answer": "Edited", "id": "ignored"}`, http.StatusOK, "Edited", true},
+		{"New evidence", "0", `{"evidence": "Another quote"}`, http.StatusOK, "A1", false},
+		{"Empty question", "0", `{"question": " "}`, http.StatusBadRequest, "", false},
+		{"Invalid JSON", "0", `{"answer":`, http.StatusBadRequest, "", false},
+		{"Missing card", "missing", `{"answer": "Edited"}`, http.StatusNotFound, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux, decks, deck := newDeckServer(t)
+			cardID := tt.card
+			if cardID == "0" {
+				cardID = deck.Set.Flashcards[0].ID
+			}
+
+			w := serveDeckRequest(mux, http.MethodPatch, "/decks/"+deck.ID+"/cards/"+cardID, "alice", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			stored, _ := decks.Get(deck.ID)
+			card := stored.Set.Flashcards[0]
+			if card.ID != cardID || card.Answer != tt.wantAnswer || card.Question != "Q1" {
+				t.Errorf("Expected only the patched fields to change, got %+v", card)
+			}
+			if (card.Grounded != nil) != tt.wantGrounded {
+				t.Errorf("Expected grounded kept: %v, got %v", tt.wantGrounded, card.Grounded)
+			}
+			if stored.Set.Flashcards[1].Answer != "A2" {
+				t.Errorf("Expected the other card unchanged, got %+v", stored.Set.Flashcards[1])
+			}
+		})
+	}
+
+	mux, _, deck := newDeckServer(t)
+	path := "/decks/" + deck.ID + "/cards/" + deck.Set.Flashcards[0].ID
+	if w := serveDeckRequest(mux, http.MethodPatch, path, "bob", `{"answer": "Mine"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's patch to be rejected with %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeckHandler_Duplicate(t *testing.T) {
+	mux, decks, deck := newDeckServer(t)
+
+	w := serveDeckRequest(mux, http.MethodPost, "/decks/"+deck.ID+"/duplicate", "alice", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var duplicate models.Deck
+	json.NewDecoder(w.Body).Decode(&duplicate)
+
+	if duplicate.ID == deck.ID || duplicate.Set.DeckID != duplicate.ID || duplicate.Owner != "alice" {
+		t.Errorf("Expected a new deck for alice, got %+v", duplicate)
+	}
+	if w.Header().Get("Location") != "/decks/"+duplicate.ID {
+		t.Errorf("Expected the copy's location, got %q", w.Header().Get("Location"))
+	}
+	if duplicate.Set.Title != "Biology (copy)" {
+		t.Errorf("Expected %q, got %q", "Biology (copy)", duplicate.Set.Title)
+	}
+	for i, card := range duplicate.Set.Flashcards {
+		if card.ID == "" || card.ID == deck.Set.Flashcards[i].ID {
+			t.Errorf("Expected card %d to get a new ID, got %q", i, card.ID)
+		}
+	}
+
+	original, _ := decks.Get(deck.ID)
+	if original.Set.Flashcards[0].ID != deck.Set.Flashcards[0].ID || original.Set.Title != "Biology" {
+		t.Errorf("Expected the original unchanged, got %+v", original.Set)
+	}
+	if summaries, _ := decks.List("alice"); len(summaries) != 2 {
+		t.Errorf("Expected 2 decks, got %d", len(summaries))
+	}
+}
+
+func TestDeckHandler_Delete(t *testing.T) {
+	mux, decks, deck := newDeckServer(t)
+
+	if w := serveDeckRequest(mux, http.MethodDelete, "/decks/"+deck.ID, "bob", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's delete to be rejected with %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := serveDeckRequest(mux, http.MethodDelete, "/decks/"+deck.ID, "alice", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if _, err := decks.Get(deck.ID); err != store.ErrDeckNotFound {
+		t.Errorf("Expected the deck to be deleted, got %v", err)
+	}
+	if w := serveDeckRequest(mux, http.MethodDelete, "/decks/"+deck.ID, "alice", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d deleting twice, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestA2AHandler_MessageSend_SavesDeck(t *testing.T) {
+	decks := store.NewMemoryDeckStore()
+	handler := NewA2AHandler(newTestFlashcardService(t, service.NewPDFService()), WithDeckStore(decks))
+
+	send := func(owner, text string) *models.TaskResult {
+		body, _ := json.Marshal(models.JSONRPCRequest{
+			JSONRPC: "2.0",
+			ID:      "req-001",
+			Method:  "message/send",
+			Params: map[string]interface{}{
+				"message": map[string]interface{}{
+					"kind": "message", "role": "user", "messageId": "msg-" + text, "contextId": "ctx-001",
+					"parts": []map[string]interface{}{{"kind": "text", "text": text}},
+				},
+				"configuration": map[string]interface{}{"acceptedOutputModes": []string{ModeJSON}},
+			},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewReader(body))
+		req.Header.Set(OwnerHeader, owner)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var response struct {
+			Result *models.TaskResult `json:"result"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Result == nil {
+			t.Fatalf("Expected a task, got %s", w.Body.String())
+		}
+		return response.Result
+	}
+	deckID := func(task *models.TaskResult) string {
+		data, _ := json.Marshal(task.Artifacts[0].Parts[0].Data)
+		var set models.FlashcardSet
+		json.Unmarshal(data, &set)
+		return set.DeckID
+	}
+
+	first := send("alice", "Basic arithmetic")
+	id := deckID(first)
+	saved, err := decks.Get(id)
+	if err != nil {
+		t.Fatalf("Expected the set to be saved as deck %q, got: %v", id, err)
+	}
+	if saved.Owner != "alice" || saved.Set.TotalCards != 2 || saved.Set.Flashcards[0].ID == "" {
+		t.Errorf("Expected alice's deck with card IDs, got %+v", saved)
+	}
+	if !strings.Contains(first.Status.Message.Parts[0].Text, "saved as deck "+id) {
+		t.Errorf("Expected the summary to name the deck, got %q", first.Status.Message.Parts[0].Text)
+	}
+
+	// A follow-up in the same conversation updates the deck it refines
+	if got := deckID(send("alice", "Make them harder")); got != id {
+		t.Errorf("Expected the refined set to stay deck %q, got %q", id, got)
+	}
+	refined, _ := decks.Get(id)
+	if refined.Set.Flashcards[0].ID != saved.Set.Flashcards[0].ID {
+		t.Errorf("Expected the kept card to keep its ID, got %+v", refined.Set.Flashcards)
+	}
+	if summaries, _ := decks.List("alice"); len(summaries) != 1 {
+		t.Errorf("Expected 1 deck for alice, got %d", len(summaries))
+	}
+}
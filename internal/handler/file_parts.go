@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+const pdfMimeType = "application/pdf"
+
+// maxInlinePDFs is how many PDFs at the size limit one message can carry
+// inline. Their combined size is limited to that many times MaxPDFSize.
+const maxInlinePDFs = 4
+
+// maxInlinePDFBytes is the combined size limit of the PDFs sent inline in
+// one message, or zero for no limit.
+func (h *A2AHandler) maxInlinePDFBytes() int64 {
+	return h.flashcardService.MaxPDFSize() * maxInlinePDFs
+}
+
+// maxBodySize is the largest request body read: the inline PDFs base64
+// encoded, plus room for the rest of the message. Zero means no limit.
+func (h *A2AHandler) maxBodySize() int64 {
+	limit := h.maxInlinePDFBytes()
+	if limit <= 0 {
+		return 0
+	}
+	return (limit+2)/3*4 + 1<<20
+}
+
+// extractFiles returns the PDFs attached to msg, from A2A file parts and
+// from legacy data parts of the form {"contentType": "application/pdf",
+// "data": "<base64>"}.
+func (h *A2AHandler) extractFiles(msg *models.Message) ([]service.PDFFile, error) {
+	var files []service.PDFFile
+	var total int64
+	for _, part := range msg.Parts {
+		switch part.Kind {
+		case models.KindFile:
+			file, err := h.decodeFilePart(part.File)
+			if err != nil {
+				return nil, err
+			}
+			if total, err = h.addInlineSize(total, file.Data); err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+
+		case models.KindData:
+			dataMap, ok := part.Data.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if contentType, _ := dataMap["contentType"].(string); contentType != pdfMimeType {
+				continue
+			}
+			encoded, ok := dataMap["data"].(string)
+			if !ok {
+				return nil, apperrors.NewAppError(
+					models.InvalidParams,
+					"PDF data part is missing base64 'data'",
+					nil,
+				)
+			}
+			data, err := h.decodePDFBytes(encoded)
+			if err != nil {
+				return nil, err
+			}
+			if total, err = h.addInlineSize(total, data); err != nil {
+				return nil, err
+			}
+			files = append(files, service.PDFFile{Data: data})
+		}
+	}
+	return files, nil
+}
+
+// addInlineSize adds the size of an inline PDF to total, returning an
+// error once the PDFs in a message are too large together.
+func (h *A2AHandler) addInlineSize(total int64, data []byte) (int64, error) {
+	total += int64(len(data))
+	if limit := h.maxInlinePDFBytes(); limit > 0 && total > limit {
+		return total, apperrors.NewAppError(
+			models.InvalidParams,
+			fmt.Sprintf("PDF files exceed the combined maximum size of %d bytes", limit),
+			nil,
+		)
+	}
+	return total, nil
+}
+
+// hasFileParts reports whether msg carries any file, so a message without
+// text can still be processed.
+func hasFileParts(msg *models.Message) bool {
+	for _, part := range msg.Parts {
+		if part.Kind == models.KindFile {
+			return true
+		}
+		if dataMap, ok := part.Data.(map[string]interface{}); ok && part.Kind == models.KindData {
+			if contentType, _ := dataMap["contentType"].(string); contentType == pdfMimeType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeFilePart validates a file part and decodes its inline bytes.
+func (h *A2AHandler) decodeFilePart(file *models.FileContent) (service.PDFFile, error) {
+	if file == nil {
+		return service.PDFFile{}, apperrors.NewAppError(
+			models.InvalidParams,
+			"File part is missing 'file'",
+			nil,
+		)
+	}
+
+	if file.MimeType != "" && !strings.EqualFold(file.MimeType, pdfMimeType) {
+		return service.PDFFile{}, apperrors.NewAppError(
+			models.ContentTypeNotSupportedError,
+			fmt.Sprintf("Unsupported file type %q, only %s is supported", file.MimeType, pdfMimeType),
+			nil,
+		)
+	}
+
+	switch {
+	case file.Bytes != "" && file.URI != "":
+		return service.PDFFile{}, apperrors.NewAppError(
+			models.InvalidParams,
+			"File part must set either 'bytes' or 'uri', not both",
+			nil,
+		)
+
+	case file.URI != "":
+		parsed, err := url.Parse(file.URI)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return service.PDFFile{}, apperrors.NewAppError(
+				models.InvalidParams,
+				"File 'uri' must be an http or https URL",
+				err,
+			)
+		}
+		return service.PDFFile{Name: file.Name, URI: file.URI}, nil
+
+	case file.Bytes != "":
+		data, err := h.decodePDFBytes(file.Bytes)
+		if err != nil {
+			return service.PDFFile{}, err
+		}
+		return service.PDFFile{Name: file.Name, Data: data}, nil
+
+	default:
+		return service.PDFFile{}, apperrors.NewAppError(
+			models.InvalidParams,
+			"File part must set 'bytes' or 'uri'",
+			nil,
+		)
+	}
+}
+
+// decodePDFBytes decodes a base64 PDF, rejecting it before decoding if it
+// can't fit within the size limit.
+func (h *A2AHandler) decodePDFBytes(encoded string) ([]byte, error) {
+	// Padding makes DecodedLen overestimate by up to two bytes
+	if err := h.flashcardService.CheckPDFSize(int64(base64.StdEncoding.DecodedLen(len(encoded)) - 2)); err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			"File bytes are not valid base64",
+			err,
+		)
+	}
+
+	if err := h.flashcardService.CheckPDFSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
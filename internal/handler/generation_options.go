@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// generationOptionsKey holds GenerationOptions in message metadata or in a
+// data part.
+const generationOptionsKey = "generationOptions"
+
+// extractGenerationOptions reads the options sent with msg. They can be
+// given in the message metadata or in a data part, both under
+// "generationOptions"; fields in a data part override the metadata.
+func extractGenerationOptions(msg *models.Message) (models.GenerationOptions, error) {
+	var opts models.GenerationOptions
+
+	sources := []interface{}{msg.Metadata}
+	for _, part := range msg.Parts {
+		if part.Kind == models.KindData {
+			sources = append(sources, part.Data)
+		}
+	}
+
+	for _, source := range sources {
+		container, ok := source.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, ok := container[generationOptionsKey]
+		if !ok {
+			continue
+		}
+		if err := decodeGenerationOptions(value, &opts); err != nil {
+			return models.GenerationOptions{}, err
+		}
+	}
+
+	if err := service.ValidateOptions(&opts); err != nil {
+		return models.GenerationOptions{}, err
+	}
+	return opts, nil
+}
+
+// decodeGenerationOptions decodes a JSON value onto opts, leaving fields it
+// doesn't set unchanged.
+func decodeGenerationOptions(value interface{}, opts *models.GenerationOptions) error {
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, opts)
+	}
+	if err != nil {
+		return apperrors.NewAppError(
+			models.InvalidParams,
+			"invalid generation options",
+			err,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/export"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// Output modes a client can list in configuration.acceptedOutputModes
+const (
+	ModeMarkdown = "text/markdown"
+	ModeText     = "text/plain"
+	ModeJSON     = "application/json"
+	ModeCSV      = export.CSVMimeType
+	ModeTSV      = export.TSVMimeType
+	ModeQuizlet  = export.QuizletMimeType
+	ModeJSONL    = export.JSONLMimeType
+	ModeAPKG     = export.APKGMimeType
+)
+
+// supportedOutputModes maps every accepted spelling to the canonical mode.
+// Export formats are looked up in the export package.
+var supportedOutputModes = map[string]string{
+	"text":       ModeMarkdown,
+	ModeText:     ModeMarkdown,
+	ModeMarkdown: ModeMarkdown,
+	ModeJSON:     ModeJSON,
+}
+
+// defaultOutputModeSet is used when the client doesn't list any modes.
+var defaultOutputModeSet = []string{ModeMarkdown, ModeJSON}
+
+// negotiateOutputModes returns the canonical modes the client accepts that
+// this agent can produce. It fails if the client listed modes but none of
+// them are supported.
+func negotiateOutputModes(config *models.Configuration) ([]string, error) {
+	if config == nil || len(config.AcceptedOutputModes) == 0 {
+		return defaultOutputModeSet, nil
+	}
+
+	var modes []string
+	seen := make(map[string]bool)
+	for _, accepted := range config.AcceptedOutputModes {
+		mode, ok := supportedOutputModes[strings.ToLower(strings.TrimSpace(accepted))]
+		if format, isExport := export.ByMimeType(accepted); !ok && isExport {
+			mode, ok = format.MimeType, true
+		}
+		if !ok || seen[mode] {
+			continue
+		}
+		seen[mode] = true
+		modes = append(modes, mode)
+	}
+
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("none of the accepted output modes %v are supported", config.AcceptedOutputModes)
+	}
+	return modes, nil
+}
+
+func hasMode(modes []string, mode string) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// flashcardParts renders the set in each requested output mode.
+func (h *A2AHandler) flashcardParts(flashcards *models.FlashcardSet, modes []string) []models.MessagePart {
+	var parts []models.MessagePart
+	for _, mode := range modes {
+		switch mode {
+		case ModeMarkdown:
+			parts = append(parts, models.MessagePart{
+				Kind: models.KindText,
+				Text: h.flashcardService.FormatAsText(flashcards),
+			})
+		case ModeJSON:
+			parts = append(parts, models.MessagePart{
+				Kind: models.KindData,
+				Data: flashcards,
+				Metadata: map[string]interface{}{
+					"mimeType": ModeJSON,
+					"schema":   models.FlashcardSetSchema,
+				},
+			})
+		default:
+			if part, ok := exportPart(flashcards, mode); ok {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
+
+// cardParts renders a single streamed card. Export formats are only
+// produced for the whole set.
+func (h *A2AHandler) cardParts(number int, card models.Flashcard, modes []string) []models.MessagePart {
+	var parts []models.MessagePart
+	if hasMode(modes, ModeMarkdown) {
+		parts = append(parts, models.MessagePart{
+			Kind: models.KindText,
+			Text: h.flashcardService.FormatCard(number, card),
+		})
+	}
+	if hasMode(modes, ModeJSON) {
+		parts = append(parts, models.MessagePart{
+			Kind: models.KindData,
+			Data: card,
+			Metadata: map[string]interface{}{
+				"mimeType": ModeJSON,
+				"schema":   models.FlashcardSchema,
+			},
+		})
+	}
+	return parts
+}
+
+// exportPart renders the set in the export format with mimeType: a text
+// part for text formats and a file part for binary ones, both named with a
+// download filename. It reports false if the set can't be encoded, leaving
+// the other modes to carry the result.
+func exportPart(flashcards *models.FlashcardSet, mimeType string) (models.MessagePart, bool) {
+	format, ok := export.ByMimeType(mimeType)
+	if !ok {
+		return models.MessagePart{}, false
+	}
+
+	var buf bytes.Buffer
+	if err := format.Encode(&buf, flashcards); err != nil {
+		log.Printf("Error exporting flashcards as %s: %v", format.Name, err)
+		return models.MessagePart{}, false
+	}
+
+	filename := export.Filename(flashcards, format.Extension)
+	if format.Binary {
+		return models.MessagePart{
+			Kind: models.KindFile,
+			File: &models.FileContent{
+				Name:     filename,
+				MimeType: format.MimeType,
+				Bytes:    base64.StdEncoding.EncodeToString(buf.Bytes()),
+			},
+		}, true
+	}
+	return models.MessagePart{
+		Kind: models.KindText,
+		Text: buf.String(),
+		Metadata: map[string]interface{}{
+			"mimeType": format.MimeType,
+			"filename": filename,
+		},
+	}, true
+}
+
+// wholeSetParts returns the export format parts, which are only produced
+// for the whole set and which streaming sends with the last card.
+func wholeSetParts(parts []models.MessagePart) []models.MessagePart {
+	var whole []models.MessagePart
+	for _, part := range parts {
+		if _, ok := export.ByMimeType(partMimeType(part)); ok {
+			whole = append(whole, part)
+		}
+	}
+	return whole
+}
+
+// partMimeType returns the mime type of a file part, or the mimeType
+// recorded in another part's metadata.
+func partMimeType(part models.MessagePart) string {
+	if part.File != nil {
+		return part.File.MimeType
+	}
+	metadata, ok := part.Metadata.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	mimeType, _ := metadata["mimeType"].(string)
+	return mimeType
+}
+
+// summaryText is the status message shown when the client accepts no text
+// mode, so the message still says what happened.
+func summaryText(flashcards *models.FlashcardSet) string {
+	summary := fmt.Sprintf("Generated %d flashcards from: %s", flashcards.TotalCards, flashcards.Source)
+	if flashcards.DeckID != "" {
+		summary += fmt.Sprintf(" (saved as deck %s)", flashcards.DeckID)
+	}
+	if flashcards.Cached {
+		summary += " (from cache)"
+	}
+	return summary
+}
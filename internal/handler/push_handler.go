@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
+	"github.com/tobey0x/lagbaja/internal/push"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+// registerPushConfig stores a webhook sent inline with message/send.
+func (h *A2AHandler) registerPushConfig(taskID string, config *models.Configuration) {
+	if config == nil || config.PushNotificationConfig == nil {
+		return
+	}
+	if err := h.validatePushConfig(config.PushNotificationConfig); err != nil {
+		log.Printf("Ignoring push config for task %s: %v", taskID, err)
+		return
+	}
+	if _, err := h.notifier.Configs().Set(taskID, *config.PushNotificationConfig); err != nil {
+		log.Printf("Error saving push config for task %s: %v", taskID, err)
+	}
+}
+
+// validatePushConfig checks that a webhook URL is an absolute http or
+// https URL. A URL naming a private address outright is refused here;
+// hostnames are checked by the notifier when it connects.
+func (h *A2AHandler) validatePushConfig(config *models.PushNotificationConfig) error {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || netguard.CheckURL(parsed) != nil {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil {
+		if err := netguard.CheckAddress(netip.AddrPortFrom(addr, 0).String(), h.notifier.AllowedNetworks); err != nil {
+			return fmt.Errorf("url must not point to a private network address")
+		}
+	}
+	return nil
+}
+
+func (h *A2AHandler) handlePushConfigSet(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.TaskPushNotificationConfig
+	if err := decodeParams(req.Params, &params); err != nil || params.TaskID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing or invalid 'taskId' parameter")
+		return
+	}
+	if err := h.validatePushConfig(&params.PushNotificationConfig); err != nil {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", err.Error())
+		return
+	}
+	if _, ok := h.ownedTask(w, r, req, params.TaskID); !ok {
+		return
+	}
+
+	config, err := h.notifier.Configs().Set(params.TaskID, params.PushNotificationConfig)
+	if err != nil {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return
+	}
+
+	h.sendSuccess(w, req.ID, models.TaskPushNotificationConfig{
+		TaskID:                 params.TaskID,
+		PushNotificationConfig: config,
+	})
+}
+
+func (h *A2AHandler) handlePushConfigGet(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.PushNotificationConfigParams
+	if err := decodeParams(req.Params, &params); err != nil || params.ID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing or invalid 'id' parameter")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, req, params.ID); !ok {
+		return
+	}
+
+	config, err := h.notifier.Configs().Get(params.ID, params.PushNotificationConfigID)
+	if errors.Is(err, push.ErrConfigNotFound) {
+		h.sendError(w, req.ID, models.InvalidParams, "Push notification config not found", fmt.Sprintf("No push notification config for task %s", params.ID))
+		return
+	}
+	if err != nil {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return
+	}
+
+	h.sendSuccess(w, req.ID, models.TaskPushNotificationConfig{
+		TaskID:                 params.ID,
+		PushNotificationConfig: config,
+	})
+}
+
+func (h *A2AHandler) handlePushConfigList(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.PushNotificationConfigParams
+	if err := decodeParams(req.Params, &params); err != nil || params.ID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing or invalid 'id' parameter")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, req, params.ID); !ok {
+		return
+	}
+
+	configs, err := h.notifier.Configs().List(params.ID)
+	if err != nil {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return
+	}
+
+	result := make([]models.TaskPushNotificationConfig, 0, len(configs))
+	for _, config := range configs {
+		result = append(result, models.TaskPushNotificationConfig{
+			TaskID:                 params.ID,
+			PushNotificationConfig: config,
+		})
+	}
+	h.sendSuccess(w, req.ID, result)
+}
+
+func (h *A2AHandler) handlePushConfigDelete(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.PushNotificationConfigParams
+	if err := decodeParams(req.Params, &params); err != nil || params.ID == "" || params.PushNotificationConfigID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing 'id' or 'pushNotificationConfigId' parameter")
+		return
+	}
+	if _, ok := h.ownedTask(w, r, req, params.ID); !ok {
+		return
+	}
+
+	err := h.notifier.Configs().Delete(params.ID, params.PushNotificationConfigID)
+	if errors.Is(err, push.ErrConfigNotFound) {
+		h.sendError(w, req.ID, models.InvalidParams, "Push notification config not found", fmt.Sprintf("No push notification config %s for task %s", params.PushNotificationConfigID, params.ID))
+		return
+	}
+	if err != nil {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return
+	}
+
+	h.sendSuccess(w, req.ID, nil)
+}
+
+// PushDeliveriesHandler lists recorded webhook delivery attempts for the
+// task given by the taskId query parameter. Tasks sent by another user are
+// reported as not found.
+func (h *A2AHandler) PushDeliveriesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		taskID := r.URL.Query().Get("taskId")
+		if taskID == "" {
+			http.Error(w, "Missing taskId query parameter", http.StatusBadRequest)
+			return
+		}
+
+		task, err := h.taskStore.Get(taskID)
+		if err != nil && !errors.Is(err, store.ErrTaskNotFound) {
+			log.Printf("Error loading task %s: %v", taskID, err)
+			http.Error(w, "Failed to load task", http.StatusInternalServerError)
+			return
+		}
+		if err != nil || task.Owner != RequestOwner(r) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+
+		attempts := h.notifier.Attempts(taskID)
+		if attempts == nil {
+			attempts = []push.DeliveryAttempt{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(attempts); err != nil {
+			log.Printf("Error encoding delivery attempts: %v", err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/review"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// quizAnswerKey holds a quizAnswer in message metadata or in a data part.
+const quizAnswerKey = "quizAnswer"
+
+// answerArtifactName names the artifact of a marked answer.
+const answerArtifactName = "answer-mark"
+
+// quizAnswer is a learner's answer to a saved card. When Answer is empty
+// the message's text is the answer.
+type quizAnswer struct {
+	CardID string `json:"cardId"`
+	Answer string `json:"answer"`
+}
+
+// extractQuizAnswer reads the quiz answer sent with msg, if any. It can be
+// given in the message metadata or in a data part, under "quizAnswer".
+func extractQuizAnswer(msg *models.Message) (*quizAnswer, error) {
+	sources := []interface{}{msg.Metadata}
+	for _, part := range msg.Parts {
+		if part.Kind == models.KindData {
+			sources = append(sources, part.Data)
+		}
+	}
+
+	var quiz *quizAnswer
+	for _, source := range sources {
+		container, ok := source.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, ok := container[quizAnswerKey]
+		if !ok {
+			continue
+		}
+		if quiz == nil {
+			quiz = &quizAnswer{}
+		}
+		data, err := json.Marshal(value)
+		if err == nil {
+			err = json.Unmarshal(data, quiz)
+		}
+		if err != nil {
+			return nil, apperrors.NewAppError(models.InvalidParams, "invalid quiz answer", err)
+		}
+	}
+	if quiz == nil {
+		return nil, nil
+	}
+
+	if strings.TrimSpace(quiz.CardID) == "" {
+		return nil, apperrors.NewAppError(models.InvalidParams, "quiz answer needs a cardId", nil)
+	}
+	if strings.TrimSpace(quiz.Answer) == "" {
+		for _, part := range msg.Parts {
+			if part.Kind == models.KindText {
+				quiz.Answer = part.Text
+				break
+			}
+		}
+	}
+	if strings.TrimSpace(quiz.Answer) == "" {
+		return nil, apperrors.NewAppError(models.InvalidParams, "quiz answer needs an answer", nil)
+	}
+	return quiz, nil
+}
+
+// hasQuizAnswer reports whether msg carries a quiz answer, so a message
+// without text can still be processed.
+func hasQuizAnswer(msg *models.Message) bool {
+	quiz, err := extractQuizAnswer(msg)
+	return quiz != nil || err != nil
+}
+
+// markAnswer marks a quiz answer against the request owner's card and
+// records the review.
+func (h *A2AHandler) markAnswer(ctx context.Context, quiz *quizAnswer, userMsg *models.Message, modes []string) (*models.TaskResult, error) {
+	log.Printf("Marking answer to card %s", quiz.CardID)
+
+	result, err := h.reviews.Answer(ctx, ownerFrom(ctx), quiz.CardID, quiz.Answer)
+	if errors.Is(err, store.ErrCardNotFound) {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			fmt.Sprintf("card %s is not in your deck library", quiz.CardID),
+			err,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.buildAnswerResult(result, userMsg, modes), nil
+}
+
+// buildAnswerResult builds the completed task for a marked answer: the
+// feedback as the status message and an artifact with the marking as text
+// and JSON, as the client accepts.
+func (h *A2AHandler) buildAnswerResult(result *models.AnswerResult, userMsg *models.Message, modes []string) *models.TaskResult {
+	if modes == nil {
+		modes = defaultOutputModeSet
+	}
+
+	if userMsg.TaskID == "" {
+		userMsg.TaskID = uuid.New().String()
+	}
+	if userMsg.ContextID == "" {
+		userMsg.ContextID = uuid.New().String()
+	}
+
+	text := formatAnswerResult(result)
+	responseMsg := h.agentMessage(userMsg.TaskID, text)
+	responseMsg.ContextID = userMsg.ContextID
+
+	var parts []models.MessagePart
+	if hasMode(modes, ModeMarkdown) {
+		parts = append(parts, models.MessagePart{Kind: models.KindText, Text: text})
+	}
+	if hasMode(modes, ModeJSON) {
+		parts = append(parts, models.MessagePart{
+			Kind:     models.KindData,
+			Data:     result,
+			Metadata: map[string]interface{}{"mimeType": ModeJSON},
+		})
+	}
+	if len(parts) == 0 {
+		parts = append(parts, models.MessagePart{Kind: models.KindText, Text: text})
+	}
+
+	return &models.TaskResult{
+		ID:        userMsg.TaskID,
+		ContextID: userMsg.ContextID,
+		Status: models.Status{
+			State:     models.StateCompleted,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Message:   &responseMsg,
+		},
+		Artifacts: []models.Artifact{
+			{
+				ArtifactID:  uuid.New().String(),
+				Name:        answerArtifactName,
+				Description: "Marked answer to card " + result.CardID,
+				Parts:       parts,
+			},
+		},
+		History: []models.Message{*userMsg, responseMsg},
+		Kind:    models.KindTask,
+	}
+}
+
+// gradeNames name the review grades in feedback.
+var gradeNames = map[review.Grade]string{
+	review.Again: "Again",
+	review.Hard:  "Hard",
+	review.Good:  "Good",
+	review.Easy:  "Easy",
+}
+
+// formatAnswerResult renders a marked answer as Markdown.
+func formatAnswerResult(result *models.AnswerResult) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "**Score: %.0f%%** (graded %s)\n\n", result.Score*100, gradeNames[review.Grade(result.Grade)])
+	if result.Feedback != "" {
+		text.WriteString(result.Feedback + "\n\n")
+	}
+	if len(result.MissingPoints) > 0 {
+		text.WriteString("**Missed:**\n")
+		for _, point := range result.MissingPoints {
+			text.WriteString("- " + point + "\n")
+		}
+		text.WriteString("\n")
+	}
+	fmt.Fprintf(&text, "**Expected answer:** %s\n\n", result.Expected)
+	if result.State != nil {
+		fmt.Fprintf(&text, "Next review: %s", result.State.Due.Format("2006-01-02"))
+	}
+	return text.String()
+}
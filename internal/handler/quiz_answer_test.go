@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/review"
+	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+func TestExtractQuizAnswer(t *testing.T) {
+	dataPart := func(data map[string]interface{}) models.MessagePart {
+		return models.MessagePart{Kind: models.KindData, Data: data}
+	}
+	textPart := models.MessagePart{Kind: models.KindText, Text: "In the chloroplasts"}
+
+	tests := []struct {
+		name       string
+		msg        models.Message
+		wantCardID string
+		wantAnswer string
+		wantErr    string
+	}{
+		{
+			name: "No quiz answer",
+			msg:  models.Message{Parts: []models.MessagePart{textPart}},
+		},
+		{
+			name:       "Answer in the data part",
+			msg:        models.Message{Parts: []models.MessagePart{dataPart(map[string]interface{}{quizAnswerKey: map[string]interface{}{"cardId": "card-1", "answer": "Chloroplasts"}})}},
+			wantCardID: "card-1",
+			wantAnswer: "Chloroplasts",
+		},
+		{
+			name:       "Answer as text",
+			msg:        models.Message{Parts: []models.MessagePart{textPart, dataPart(map[string]interface{}{quizAnswerKey: map[string]interface{}{"cardId": "card-1"}})}},
+			wantCardID: "card-1",
+			wantAnswer: "In the chloroplasts",
+		},
+		{
+			name:       "Card in metadata",
+			msg:        models.Message{Metadata: map[string]interface{}{quizAnswerKey: map[string]interface{}{"cardId": "card-2"}}, Parts: []models.MessagePart{textPart}},
+			wantCardID: "card-2",
+			wantAnswer: "In the chloroplasts",
+		},
+		{
+			name:    "Missing card ID",
+			msg:     models.Message{Parts: []models.MessagePart{textPart, dataPart(map[string]interface{}{quizAnswerKey: map[string]interface{}{"answer": "x"}})}},
+			wantErr: "quiz answer needs a cardId",
+		},
+		{
+			name:    "Missing answer",
+			msg:     models.Message{Parts: []models.MessagePart{dataPart(map[string]interface{}{quizAnswerKey: map[string]interface{}{"cardId": "card-1"}})}},
+			wantErr: "quiz answer needs an answer",
+		},
+		{
+			name:    "Invalid quiz answer",
+			msg:     models.Message{Parts: []models.MessagePart{dataPart(map[string]interface{}{quizAnswerKey: "card-1"})}},
+			wantErr: "invalid quiz answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiz, err := extractQuizAnswer(&tt.msg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				if !hasQuizAnswer(&tt.msg) {
+					t.Error("Expected an invalid quiz answer to count as content, so its error is reported")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if tt.wantCardID == "" {
+				if quiz != nil {
+					t.Errorf("Expected no quiz answer, got %+v", quiz)
+				}
+				return
+			}
+			if quiz == nil || quiz.CardID != tt.wantCardID || quiz.Answer != tt.wantAnswer {
+				t.Errorf("Expected card %q answered %q, got %+v", tt.wantCardID, tt.wantAnswer, quiz)
+			}
+		})
+	}
+}
+
+func TestA2AHandler_MessageSend_QuizAnswer(t *testing.T) {
+	decks := store.NewMemoryDeckStore()
+	deck := store.NewDeck("alice", &models.FlashcardSet{
+		Title:      "Biology",
+		Flashcards: []models.Flashcard{{Question: "Where does photosynthesis take place?", Answer: "In the chloroplasts"}},
+	})
+	decks.Create(deck)
+	cardID := deck.Set.Flashcards[0].ID
+
+	provider := service.NewFakeProvider(service.FakeResponse{Text: `{"score": 0.5, "missingPoints": ["chloroplasts"], "feedback": "Half right."}`})
+	flashcardService := service.NewFlashcardService(service.NewPDFService(), provider)
+	reviews := review.NewService(decks, store.NewMemoryReviewStore(), review.NewFSRS())
+	reviews.Marker = flashcardService
+	handler := NewA2AHandler(flashcardService, WithDeckStore(decks), WithReviewService(reviews))
+
+	send := func(owner string, parts ...map[string]interface{}) map[string]json.RawMessage {
+		body, _ := json.Marshal(models.JSONRPCRequest{
+			JSONRPC: "2.0",
+			ID:      "req-001",
+			Method:  "message/send",
+			Params: map[string]interface{}{
+				"message": map[string]interface{}{"kind": "message", "role": "user", "messageId": "msg-001", "parts": parts},
+			},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/a2a", bytes.NewReader(body))
+		req.Header.Set(OwnerHeader, owner)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var response map[string]json.RawMessage
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+	quiz := func(fields map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"kind": "data", "data": map[string]interface{}{quizAnswerKey: fields}}
+	}
+
+	response := send("alice", map[string]interface{}{"kind": "text", "text": "Leaves"}, quiz(map[string]interface{}{"cardId": cardID}))
+	var task models.TaskResult
+	if err := json.Unmarshal(response["result"], &task); err != nil || task.Status.State != models.StateCompleted {
+		t.Fatalf("Expected a completed task, got %s", response["error"])
+	}
+	text := task.Status.Message.Parts[0].Text
+	for _, want := range []string{"**Score: 50%** (graded Hard)", "Half right.", "- chloroplasts", "**Expected answer:** In the chloroplasts", "Next review: "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the feedback to contain %q, got %q", want, text)
+		}
+	}
+	if len(task.Artifacts) != 1 || task.Artifacts[0].Name != answerArtifactName || len(task.Artifacts[0].Parts) != 2 {
+		t.Fatalf("Expected an answer artifact in Markdown and JSON, got %+v", task.Artifacts)
+	}
+	data, _ := json.Marshal(task.Artifacts[0].Parts[1].Data)
+	var result models.AnswerResult
+	json.Unmarshal(data, &result)
+	if result.CardID != cardID || result.Answer != "Leaves" || result.Grade != int(review.Hard) || result.State == nil {
+		t.Errorf("Expected the marked answer, got %+v", result)
+	}
+	if state, err := reviews.Next("alice", "", 0); err != nil || state.New != 0 {
+		t.Errorf("Expected the answer to schedule the card, got %+v, %v", state, err)
+	}
+
+	// An exact answer in the data part skips the model
+	response = send("alice", quiz(map[string]interface{}{"cardId": cardID, "answer": "in the chloroplasts."}))
+	json.Unmarshal(response["result"], &task)
+	if !strings.Contains(task.Status.Message.Parts[0].Text, "**Score: 100%** (graded Good)") {
+		t.Errorf("Expected a full score, got %q", task.Status.Message.Parts[0].Text)
+	}
+	if n := len(provider.Requests()); n != 1 {
+		t.Errorf("Expected one model call in all, got %d", n)
+	}
+
+	tests := []struct {
+		name  string
+		owner string
+		parts []map[string]interface{}
+		want  string
+	}{
+		{"Another user's card", "bob", []map[string]interface{}{quiz(map[string]interface{}{"cardId": cardID, "answer": "x"})}, "is not in your deck library"},
+		{"Missing answer", "alice", []map[string]interface{}{quiz(map[string]interface{}{"cardId": cardID})}, "quiz answer needs an answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := send(tt.owner, tt.parts...)
+			var rpcErr models.RPCError
+			json.Unmarshal(response["error"], &rpcErr)
+			if rpcErr.Code != models.InvalidParams || !strings.Contains(string(response["error"]), tt.want) {
+				t.Errorf("Expected an invalid params error containing %q, got %s", tt.want, response["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/review"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// ReviewHandler serves the spaced repetition endpoints over the request
+// owner's deck library.
+type ReviewHandler struct {
+	reviews *review.Service
+}
+
+func NewReviewHandler(reviews *review.Service) *ReviewHandler {
+	return &ReviewHandler{reviews: reviews}
+}
+
+// Register adds the /review routes to mux.
+func (h *ReviewHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /review/next", h.handleNext)
+	mux.HandleFunc("POST /review/{cardId}", h.handleReview)
+	mux.HandleFunc("POST /review/{cardId}/answer", h.handleAnswer)
+}
+
+// reviewRequest is the body of POST /review/{cardId}.
+type reviewRequest struct {
+	Grade review.Grade `json:"grade"`
+}
+
+// answerRequest is the body of POST /review/{cardId}/answer.
+type answerRequest struct {
+	Answer string `json:"answer"`
+}
+
+func (h *ReviewHandler) handleNext(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	queue, err := h.reviews.Next(RequestOwner(r), r.URL.Query().Get("deckId"), limit)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, queue)
+}
+
+func (h *ReviewHandler) handleReview(w http.ResponseWriter, r *http.Request) {
+	var req reviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCardPatchSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid review JSON", http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.reviews.Review(RequestOwner(r), r.PathValue("cardId"), req.Grade)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (h *ReviewHandler) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	var req answerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCardPatchSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid answer JSON", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.reviews.Answer(r.Context(), RequestOwner(r), r.PathValue("cardId"), req.Answer)
+	if err != nil {
+		writeReviewError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeReviewError reports missing decks and cards as 404, invalid grades
+// and answers as 400, and answers the model couldn't mark as 502.
+func writeReviewError(w http.ResponseWriter, err error) {
+	var appErr *apperrors.AppError
+	switch {
+	case errors.Is(err, store.ErrDeckNotFound):
+		http.Error(w, "Deck not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrCardNotFound):
+		http.Error(w, "Card not found", http.StatusNotFound)
+	case errors.As(err, &appErr) && appErr.Code == models.InvalidParams:
+		http.Error(w, appErr.Message, http.StatusBadRequest)
+	case errors.As(err, &appErr):
+		log.Printf("Error marking answer: %v", err)
+		http.Error(w, appErr.Message, http.StatusBadGateway)
+	default:
+		log.Printf("Error reviewing cards: %v", err)
+		http.Error(w, "Failed to review cards", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/review"
+	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+)
+
+// newReviewServer serves a ReviewHandler over alice's deck from
+// newDeckServer, with the clock stopped. Answers the model marks score 0.4.
+func newReviewServer(t *testing.T) (*http.ServeMux, *models.Deck) {
+	t.Helper()
+
+	_, decks, deck := newDeckServer(t)
+	reviews := review.NewService(decks, store.NewMemoryReviewStore(), review.SM2{})
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	reviews.Now = func() time.Time { return now }
+	reviews.Marker = service.NewFlashcardService(service.NewPDFService(), service.NewFakeProvider(service.FakeResponse{
+		Text: `{"score": 0.4, "missingPoints": ["A1"], "feedback": "Not quite."}`,
+	}))
+
+	mux := http.NewServeMux()
+	NewReviewHandler(reviews).Register(mux)
+	return mux, deck
+}
+
+func TestReviewHandler_NextAndReview(t *testing.T) {
+	mux, deck := newReviewServer(t)
+	cardID := deck.Set.Flashcards[0].ID
+
+	w := serveDeckRequest(mux, http.MethodGet, "/review/next", "alice", "")
+	var queue models.ReviewQueue
+	json.NewDecoder(w.Body).Decode(&queue)
+	if w.Code != http.StatusOK || queue.New != 2 || len(queue.Cards) != 2 || queue.Cards[0].Card.ID != cardID {
+		t.Errorf("Expected alice's two new cards, got %d %+v", w.Code, queue)
+	}
+
+	w = serveDeckRequest(mux, http.MethodPost, "/review/"+cardID, "alice", `{"grade":3}`)
+	var state models.ReviewState
+	json.NewDecoder(w.Body).Decode(&state)
+	if w.Code != http.StatusOK || state.CardID != cardID || state.Interval != 1 || state.Scheduler != review.SchedulerSM2 {
+		t.Errorf("Expected the card scheduled for tomorrow, got %d %+v", w.Code, state)
+	}
+
+	w = serveDeckRequest(mux, http.MethodGet, "/review/next?limit=5&deckId="+deck.ID, "alice", "")
+	queue = models.ReviewQueue{}
+	json.NewDecoder(w.Body).Decode(&queue)
+	if len(queue.Cards) != 1 || queue.Cards[0].Card.ID == cardID {
+		t.Errorf("Expected only the unreviewed card, got %+v", queue.Cards)
+	}
+}
+
+func TestReviewHandler_Errors(t *testing.T) {
+	mux, deck := newReviewServer(t)
+	cardID := deck.Set.Flashcards[0].ID
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		owner      string
+		body       string
+		wantStatus int
+	}{
+		{"Bad limit", http.MethodGet, "/review/next?limit=zero", "alice", "", http.StatusBadRequest},
+		{"Negative limit", http.MethodGet, "/review/next?limit=-1", "alice", "", http.StatusBadRequest},
+		{"Another user's deck", http.MethodGet, "/review/next?deckId=" + deck.ID, "bob", "", http.StatusNotFound},
+		{"Missing deck", http.MethodGet, "/review/next?deckId=missing", "alice", "", http.StatusNotFound},
+		{"Invalid JSON", http.MethodPost, "/review/" + cardID, "alice", `{"grade":`, http.StatusBadRequest},
+		{"Missing grade", http.MethodPost, "/review/" + cardID, "alice", `{}`, http.StatusBadRequest},
+		{"Grade out of range", http.MethodPost, "/review/" + cardID, "alice", `{"grade":5}`, http.StatusBadRequest},
+		{"Another user's card", http.MethodPost, "/review/" + cardID, "bob", `{"grade":3}`, http.StatusNotFound},
+		{"Missing card", http.MethodPost, "/review/missing", "alice", `{"grade":3}`, http.StatusNotFound},
+		{"Wrong method", http.MethodGet, "/review/" + cardID, "alice", "", http.StatusMethodNotAllowed},
+		{"Invalid answer JSON", http.MethodPost, "/review/" + cardID + "/answer", "alice", `answer`, http.StatusBadRequest},
+		{"Blank answer", http.MethodPost, "/review/" + cardID + "/answer", "alice", `{"answer": " "}`, http.StatusBadRequest},
+		{"Answer to another user's card", http.MethodPost, "/review/" + cardID + "/answer", "bob", `{"answer": "A1"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveDeckRequest(mux, tt.method, tt.path, tt.owner, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReviewHandler_Answer(t *testing.T) {
+	mux, deck := newReviewServer(t)
+
+	tests := []struct {
+		name        string
+		cardID      string
+		answer      string
+		wantScore   float64
+		wantGrade   review.Grade
+		wantMethod  string
+		wantMissing int
+	}{
+		{"Exact answer", deck.Set.Flashcards[0].ID, "a1", 1, review.Good, models.MarkedExact, 0},
+		{"Answer marked by the model", deck.Set.Flashcards[1].ID, "Something else", 0.4, review.Again, models.MarkedModel, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveDeckRequest(mux, http.MethodPost, "/review/"+tt.cardID+"/answer", "alice", `{"answer": "`+tt.answer+`"}`)
+			var result models.AnswerResult
+			json.NewDecoder(w.Body).Decode(&result)
+			if w.Code != http.StatusOK || result.Score != tt.wantScore || result.Method != tt.wantMethod {
+				t.Fatalf("Expected score %v by %q, got %d %+v", tt.wantScore, tt.wantMethod, w.Code, result)
+			}
+			if result.Grade != int(tt.wantGrade) || result.State == nil || result.State.LastGrade != int(tt.wantGrade) {
+				t.Errorf("Expected the review graded %d, got %d and %+v", tt.wantGrade, result.Grade, result.State)
+			}
+			if len(result.MissingPoints) != tt.wantMissing {
+				t.Errorf("Expected %d missing points, got %v", tt.wantMissing, result.MissingPoints)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/service"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// sseWriter writes JSON-RPC responses as Server-Sent Events.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	id      string
+}
+
+func (s *sseWriter) send(result interface{}) {
+	data, err := json.Marshal(models.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      s.id,
+		Result:  result,
+	})
+	if err != nil {
+		log.Printf("Error encoding stream event: %v", err)
+		return
+	}
+
+	fmt.Fprintf(s.w, "data: %s\n\n", data)
+	s.flusher.Flush()
+}
+
+// handleMessageStream runs the same generation as message/send but streams
+// the task, status updates and one artifact update per flashcard as SSE.
+func (h *A2AHandler) handleMessageStream(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	msg, err := h.extractMessage(req.Params)
+	if err != nil {
+		appErr := err.(*apperrors.AppError)
+		h.sendError(w, req.ID, appErr.Code, appErr.Message, appErr.Error())
+		return
+	}
+
+	userInput := h.extractUserInput(msg)
+	if userInput == "" && !hasFileParts(msg) && !hasQuizAnswer(msg) {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "No text or file content found in message")
+		return
+	}
+	if _, err := extractGenerationOptions(msg); err != nil {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+	if _, err := extractQuizAnswer(msg); err != nil {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+
+	config := h.extractConfiguration(req.Params)
+	modes, err := negotiateOutputModes(config)
+	if err != nil {
+		h.sendError(w, req.ID, models.ContentTypeNotSupportedError, "Incompatible content types", err.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.sendError(w, req.ID, models.UnsupportedOperationError, "Streaming not supported", "Response writer cannot flush")
+		return
+	}
+
+	task, err := h.newTask(msg, RequestOwner(r))
+	if err != nil {
+		h.sendAppError(w, req.ID, err)
+		return
+	}
+
+	// Generation outlives the server's WriteTimeout, so lift the deadline
+	// for this response. Writers that don't support it are left alone.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &sseWriter{w: w, flusher: flusher, id: req.ID}
+
+	h.registerPushConfig(task.ID, config)
+	h.saveTask(task)
+	stream.send(task)
+
+	// Closing the stream or calling tasks/cancel stops the generation
+	ctx := h.startTask(withOwner(r.Context(), RequestOwner(r)), task.ID)
+
+	artifactID := uuid.New().String()
+	var pending *models.Flashcard
+	cardCount := 0
+
+	// Cards are sent one behind so the last one can carry lastChunk and,
+	// when requested, the CSV rendering of the whole set
+	sendCard := func(card models.Flashcard, last bool, setParts []models.MessagePart) {
+		cardCount++
+		parts := append(h.cardParts(cardCount, card, modes), setParts...)
+
+		stream.send(models.TaskArtifactUpdateEvent{
+			TaskID:    task.ID,
+			ContextID: task.ContextID,
+			Kind:      models.KindArtifactUpdate,
+			Artifact: models.Artifact{
+				ArtifactID: artifactID,
+				Name:       flashcardArtifactName,
+				Parts:      parts,
+			},
+			Append:    cardCount > 1,
+			LastChunk: last,
+		})
+	}
+
+	progress := func(p service.Progress) {
+		if p.Stage == service.StageCard {
+			if pending != nil {
+				sendCard(*pending, false, nil)
+			}
+			pending = p.Card
+			return
+		}
+
+		statusMsg := h.agentMessage(task.ID, p.Message)
+		task.Status = models.Status{
+			State:     models.StateWorking,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Message:   &statusMsg,
+		}
+		h.saveActiveTask(task)
+		stream.send(models.TaskStatusUpdateEvent{
+			TaskID:    task.ID,
+			ContextID: task.ContextID,
+			Kind:      models.KindStatusUpdate,
+			Status:    task.Status,
+		})
+	}
+
+	result, err := h.processRequest(ctx, userInput, msg, modes, progress)
+	var wholeSet []models.MessagePart
+	if err != nil {
+		log.Printf("Task %s failed: %v", task.ID, err)
+	} else if len(result.Artifacts) > 0 {
+		result.Artifacts[0].ArtifactID = artifactID
+		wholeSet = wholeSetParts(result.Artifacts[0].Parts)
+	}
+
+	final := h.finishTask(ctx, task, result, err)
+	if final.Status.State == models.StateCompleted && pending != nil {
+		sendCard(*pending, true, wholeSet)
+	}
+
+	stream.send(models.TaskStatusUpdateEvent{
+		TaskID:    final.ID,
+		ContextID: final.ContextID,
+		Kind:      models.KindStatusUpdate,
+		Status:    final.Status,
+		Final:     true,
+	})
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// runningTask tracks an in-flight task so tasks/cancel can stop it.
+type runningTask struct {
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// newTask creates a submitted task for msg on behalf of owner, assigning
+// task and context IDs if the client didn't send them. A taskId that is
+// already stored continues that task, keeping its history, but only for
+// its owner and only while it hasn't finished and isn't running.
+func (h *A2AHandler) newTask(msg *models.Message, owner string) (*models.TaskResult, error) {
+	var history []models.Message
+	if msg.TaskID != "" {
+		existing, err := h.continuedTask(msg, owner)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			msg.ContextID = existing.ContextID
+			history = existing.History
+		}
+	}
+	if msg.TaskID == "" {
+		msg.TaskID = uuid.New().String()
+	}
+	if msg.ContextID == "" {
+		msg.ContextID = uuid.New().String()
+	}
+
+	return &models.TaskResult{
+		ID:        msg.TaskID,
+		ContextID: msg.ContextID,
+		Status: models.Status{
+			State:     models.StateSubmitted,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		},
+		History: append(history, *msg),
+		Kind:    models.KindTask,
+		Owner:   owner,
+	}, nil
+}
+
+// continuedTask returns the stored task msg names, or nil if there is
+// none. It returns an error if owner can't send another message to it.
+func (h *A2AHandler) continuedTask(msg *models.Message, owner string) (*models.TaskResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	task, err := h.taskStore.Get(msg.TaskID)
+	if errors.Is(err, store.ErrTaskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.NewAppError(models.InternalError, "failed to load task", err)
+	}
+
+	switch {
+	case task.Owner != owner:
+		return nil, apperrors.NewAppError(models.TaskNotFoundError, fmt.Sprintf("task %s not found", task.ID), nil)
+	case models.IsTerminalState(task.Status.State):
+		return nil, apperrors.NewAppError(models.InvalidParams, fmt.Sprintf("task %s is already %s; send the message without a taskId to start a new task", task.ID, task.Status.State), nil)
+	case h.running[task.ID] != nil:
+		return nil, apperrors.NewAppError(models.InvalidParams, fmt.Sprintf("task %s is still running", task.ID), nil)
+	case msg.ContextID != "" && msg.ContextID != task.ContextID:
+		return nil, apperrors.NewAppError(models.InvalidParams, fmt.Sprintf("task %s belongs to context %s", task.ID, task.ContextID), nil)
+	}
+	return task, nil
+}
+
+// startTask registers taskID as running and returns the context its work
+// must use. Canceling the task through tasks/cancel cancels that context.
+func (h *A2AHandler) startTask(parent context.Context, taskID string) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running[taskID] = &runningTask{cancel: cancel}
+	return ctx
+}
+
+// runTask executes a queued task and records its final state.
+func (h *A2AHandler) runTask(ctx context.Context, task *models.TaskResult, userInput string, msg *models.Message, modes []string) {
+	if ctx.Err() != nil {
+		h.finishTask(ctx, task, nil, ctx.Err())
+		return
+	}
+
+	task.Status = models.Status{
+		State:     models.StateWorking,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	h.saveActiveTask(task)
+
+	result, err := h.processRequest(ctx, userInput, msg, modes, nil)
+	if err != nil {
+		log.Printf("Task %s failed: %v", task.ID, err)
+	}
+	h.finishTask(ctx, task, result, err)
+}
+
+// finishTask records the outcome of a run and returns the task as stored.
+// A task canceled while running keeps its canceled state even if the work
+// managed to finish.
+func (h *A2AHandler) finishTask(ctx context.Context, task, result *models.TaskResult, err error) *models.TaskResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	running := h.running[task.ID]
+	delete(h.running, task.ID)
+	if running != nil {
+		defer running.cancel()
+		if running.canceled {
+			if stored, getErr := h.taskStore.Get(task.ID); getErr == nil {
+				return stored
+			}
+			h.cancelTask(task)
+			return task
+		}
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			h.cancelTask(task)
+		} else {
+			h.failTask(task, err.Error())
+		}
+		return task
+	}
+
+	// The result only holds this run's messages, so keep the earlier
+	// history of a continued task
+	earlier := task.History[:max(len(task.History)-1, 0)]
+	result.History = append(append([]models.Message(nil), earlier...), result.History...)
+	result.ContextID = task.ContextID
+	result.Owner = task.Owner
+	h.saveTask(result)
+	return result
+}
+
+// saveActiveTask saves an intermediate state unless the task was canceled
+// in the meantime.
+func (h *A2AHandler) saveActiveTask(task *models.TaskResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if running, ok := h.running[task.ID]; ok && running.canceled {
+		return
+	}
+	h.saveTask(task)
+}
+
+// failTask moves task to the failed state with an agent message explaining why.
+func (h *A2AHandler) failTask(task *models.TaskResult, reason string) {
+	h.setFinalStatus(task, models.StateFailed, reason)
+}
+
+// cancelTask moves task to the canceled state.
+func (h *A2AHandler) cancelTask(task *models.TaskResult) {
+	h.setFinalStatus(task, models.StateCanceled, "Task was canceled")
+}
+
+func (h *A2AHandler) setFinalStatus(task *models.TaskResult, state, reason string) {
+	statusMsg := h.agentMessage(task.ID, reason)
+	task.Status = models.Status{
+		State:     state,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Message:   &statusMsg,
+	}
+	task.History = append(task.History, statusMsg)
+	h.saveTask(task)
+}
+
+// saveTask persists task and notifies its webhooks if the state changed.
+func (h *A2AHandler) saveTask(task *models.TaskResult) {
+	if err := h.taskStore.Save(task); err != nil {
+		log.Printf("Error saving task %s: %v", task.ID, err)
+	}
+	h.notifier.Notify(task)
+}
+
+func (h *A2AHandler) agentMessage(taskID, text string) models.Message {
+	return models.Message{
+		Kind:      models.KindMessage,
+		Role:      models.RoleAgent,
+		MessageID: uuid.New().String(),
+		TaskID:    taskID,
+		Parts: []models.MessagePart{
+			{
+				Kind: models.KindText,
+				Text: text,
+			},
+		},
+	}
+}
+
+// Shutdown stops accepting background tasks and waits for running ones
+// and their push notifications to finish.
+func (h *A2AHandler) Shutdown(ctx context.Context) error {
+	if err := h.workers.Shutdown(ctx); err != nil {
+		return err
+	}
+	return h.notifier.Shutdown(ctx)
+}
+
+// ownedTask loads taskID, sending TaskNotFoundError and returning false if
+// it doesn't exist or was sent by a user other than the request owner.
+func (h *A2AHandler) ownedTask(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest, taskID string) (*models.TaskResult, bool) {
+	task, err := h.taskStore.Get(taskID)
+	if err != nil && !errors.Is(err, store.ErrTaskNotFound) {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return nil, false
+	}
+	if err != nil || task.Owner != RequestOwner(r) {
+		h.sendError(w, req.ID, models.TaskNotFoundError, "Task not found", fmt.Sprintf("Task %s not found", taskID))
+		return nil, false
+	}
+	return task, true
+}
+
+func (h *A2AHandler) handleTasksGet(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.TaskQueryParams
+	if err := decodeParams(req.Params, &params); err != nil || params.ID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing or invalid 'id' parameter")
+		return
+	}
+
+	task, ok := h.ownedTask(w, r, req, params.ID)
+	if !ok {
+		return
+	}
+
+	if params.HistoryLength != nil {
+		task.History = trimHistory(task.History, *params.HistoryLength)
+	}
+
+	h.sendSuccess(w, req.ID, task)
+}
+
+// handleTasksCancel stops a running task, aborting any download, extraction
+// or model call it has in flight, and moves it to the canceled state.
+func (h *A2AHandler) handleTasksCancel(w http.ResponseWriter, r *http.Request, req models.JSONRPCRequest) {
+	var params models.TaskIDParams
+	if err := decodeParams(req.Params, &params); err != nil || params.ID == "" {
+		h.sendError(w, req.ID, models.InvalidParams, "Invalid params", "Missing or invalid 'id' parameter")
+		return
+	}
+
+	h.mu.Lock()
+	task, err := h.taskStore.Get(params.ID)
+	if err == nil && task.Owner != RequestOwner(r) {
+		// Other users' tasks are reported as not found
+		err = store.ErrTaskNotFound
+	}
+	canceled := false
+	if err == nil && !models.IsTerminalState(task.Status.State) {
+		if running, ok := h.running[task.ID]; ok {
+			running.canceled = true
+			running.cancel()
+		}
+		h.cancelTask(task)
+		canceled = true
+	}
+	h.mu.Unlock()
+
+	if errors.Is(err, store.ErrTaskNotFound) {
+		h.sendError(w, req.ID, models.TaskNotFoundError, "Task not found", fmt.Sprintf("Task %s not found", params.ID))
+		return
+	}
+	if err != nil {
+		h.sendError(w, req.ID, models.InternalError, "Internal error", err.Error())
+		return
+	}
+	if !canceled {
+		h.sendError(w, req.ID, models.TaskNotCancelableError, "Task cannot be canceled", fmt.Sprintf("Task %s is already %s", params.ID, task.Status.State))
+		return
+	}
+
+	h.sendSuccess(w, req.ID, task)
+}
+
+// decodeParams converts the loosely typed JSON-RPC params into a typed struct.
+func decodeParams(params map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// trimHistory keeps only the most recent n messages.
+func trimHistory(history []models.Message, n int) []models.Message {
+	if n < 0 || n >= len(history) {
+		return history
+	}
+	return history[len(history)-n:]
+}
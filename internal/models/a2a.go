@@ -1,19 +1,30 @@
 package models
 
-
 type Message struct {
 	Kind      string        `json:"kind"`
 	Role      string        `json:"role"`
 	Parts     []MessagePart `json:"parts"`
 	MessageID string        `json:"messageId"`
 	TaskID    string        `json:"taskId,omitempty"`
+	ContextID string        `json:"contextId,omitempty"`
 	Metadata  interface{}   `json:"metadata,omitempty"`
 }
 
 type MessagePart struct {
-	Kind string      `json:"kind"`
-	Text string      `json:"text,omitempty"`
-	Data interface{} `json:"data,omitempty"`
+	Kind     string       `json:"kind"`
+	Text     string       `json:"text,omitempty"`
+	Data     interface{}  `json:"data,omitempty"`
+	File     *FileContent `json:"file,omitempty"`
+	Metadata interface{}  `json:"metadata,omitempty"`
+}
+
+// FileContent is the file of a "file" part. Exactly one of Bytes (base64)
+// or URI is set.
+type FileContent struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Bytes    string `json:"bytes,omitempty"`
+	URI      string `json:"uri,omitempty"`
 }
 
 type TaskResult struct {
@@ -23,31 +34,119 @@ type TaskResult struct {
 	Artifacts []Artifact `json:"artifacts"`
 	History   []Message  `json:"history"`
 	Kind      string     `json:"kind"`
+
+	// Owner is the user who sent the task. It isn't part of the A2A task
+	// object, so it is never sent to clients or webhooks.
+	Owner string `json:"-"`
 }
 
 type Status struct {
-	State     string  `json:"state"`
-	Timestamp string  `json:"timestamp"`
-	Message   Message `json:"message"`
+	State     string   `json:"state"`
+	Timestamp string   `json:"timestamp"`
+	Message   *Message `json:"message,omitempty"`
 }
 
 type Artifact struct {
-	ArtifactID string        `json:"artifactId"`
-	Name       string        `json:"name"`
-	Parts      []MessagePart `json:"parts"`
+	ArtifactID  string        `json:"artifactId"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Parts       []MessagePart `json:"parts"`
+}
+
+// TaskStatusUpdateEvent is streamed by message/stream when a task's status
+// changes. Final marks the last event of the stream.
+type TaskStatusUpdateEvent struct {
+	TaskID    string `json:"taskId"`
+	ContextID string `json:"contextId"`
+	Kind      string `json:"kind"`
+	Status    Status `json:"status"`
+	Final     bool   `json:"final"`
+}
+
+// TaskArtifactUpdateEvent is streamed by message/stream as artifact content
+// becomes available. Append adds the parts to a previously sent artifact
+// with the same ID.
+type TaskArtifactUpdateEvent struct {
+	TaskID    string   `json:"taskId"`
+	ContextID string   `json:"contextId"`
+	Kind      string   `json:"kind"`
+	Artifact  Artifact `json:"artifact"`
+	Append    bool     `json:"append,omitempty"`
+	LastChunk bool     `json:"lastChunk,omitempty"`
 }
 
 type Configuration struct {
-	Blocking bool `json:"blocking"`
+	AcceptedOutputModes    []string                `json:"acceptedOutputModes,omitempty"`
+	Blocking               *bool                   `json:"blocking,omitempty"`
+	PushNotificationConfig *PushNotificationConfig `json:"pushNotificationConfig,omitempty"`
+}
+
+// IsBlocking reports whether the client wants to wait for the final result.
+// Requests without a configuration keep the original blocking behavior.
+func (c *Configuration) IsBlocking() bool {
+	return c == nil || c.Blocking == nil || *c.Blocking
+}
+
+type MessageSendParams struct {
+	Message       Message        `json:"message"`
+	Configuration *Configuration `json:"configuration,omitempty"`
+	Metadata      interface{}    `json:"metadata,omitempty"`
+}
+
+// PushNotificationConfig registers a webhook that receives the task each
+// time its state changes.
+type PushNotificationConfig struct {
+	ID             string                              `json:"id,omitempty"`
+	URL            string                              `json:"url"`
+	Token          string                              `json:"token,omitempty"`
+	Authentication *PushNotificationAuthenticationInfo `json:"authentication,omitempty"`
+}
+
+type PushNotificationAuthenticationInfo struct {
+	Schemes     []string `json:"schemes"`
+	Credentials string   `json:"credentials,omitempty"`
+}
+
+type TaskPushNotificationConfig struct {
+	TaskID                 string                 `json:"taskId"`
+	PushNotificationConfig PushNotificationConfig `json:"pushNotificationConfig"`
+}
+
+type PushNotificationConfigParams struct {
+	ID                       string `json:"id"`
+	PushNotificationConfigID string `json:"pushNotificationConfigId,omitempty"`
+}
+
+type TaskIDParams struct {
+	ID       string      `json:"id"`
+	Metadata interface{} `json:"metadata,omitempty"`
+}
+
+type TaskQueryParams struct {
+	ID            string      `json:"id"`
+	HistoryLength *int        `json:"historyLength,omitempty"`
+	Metadata      interface{} `json:"metadata,omitempty"`
 }
 
 // Task states
 const (
+	StateSubmitted = "submitted"
+	StateWorking   = "working"
 	StateCompleted = "completed"
 	StateRunning   = "running"
 	StateFailed    = "failed"
+	StateCanceled  = "canceled"
 )
 
+// IsTerminalState reports whether a task in state can no longer change.
+func IsTerminalState(state string) bool {
+	switch state {
+	case StateCompleted, StateFailed, StateCanceled:
+		return true
+	}
+	return false
+}
+
 // Message roles
 const (
 	RoleUser  = "user"
@@ -58,5 +157,13 @@ const (
 const (
 	KindText    = "text"
 	KindData    = "data"
+	KindFile    = "file"
 	KindMessage = "message"
-)
\ No newline at end of file
+)
+
+// Result kinds
+const (
+	KindTask           = "task"
+	KindStatusUpdate   = "status-update"
+	KindArtifactUpdate = "artifact-update"
+)
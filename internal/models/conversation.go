@@ -0,0 +1,13 @@
+package models
+
+// Conversation is the state shared by every task with the same contextId:
+// the messages exchanged so far and the most recent flashcard deck, which
+// follow-up requests refine. Only its owner, the user who started it, can
+// continue it.
+type Conversation struct {
+	ContextID string        `json:"contextId"`
+	Owner     string        `json:"owner,omitempty"`
+	History   []Message     `json:"history"`
+	Deck      *FlashcardSet `json:"deck,omitempty"`
+	UpdatedAt string        `json:"updatedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// Deck is a flashcard set saved in the library.
+type Deck struct {
+	ID        string       `json:"id"`
+	Owner     string       `json:"owner,omitempty"`
+	CreatedAt string       `json:"createdAt"`
+	UpdatedAt string       `json:"updatedAt"`
+	Set       FlashcardSet `json:"set"`
+}
+
+// DeckSummary describes a deck without its cards, for listings.
+type DeckSummary struct {
+	ID         string `json:"id"`
+	Owner      string `json:"owner,omitempty"`
+	Title      string `json:"title"`
+	TotalCards int    `json:"totalCards"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+// Summary describes the deck without its cards.
+func (d *Deck) Summary() DeckSummary {
+	return DeckSummary{
+		ID:         d.ID,
+		Owner:      d.Owner,
+		Title:      d.Set.Title,
+		TotalCards: d.Set.TotalCards,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+	}
+}
+
+// Card returns the index of the card with the given ID, or -1.
+func (d *Deck) Card(id string) int {
+	for i, card := range d.Set.Flashcards {
+		if card.ID == id {
+			return i
+		}
+	}
+	return -1
+}
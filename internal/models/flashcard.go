@@ -1,21 +1,180 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+)
+
+// Card types. Every card has a Question and Answer so it can be shown as
+// plain question and answer; the other types add their own fields.
+const (
+	// CardTypeBasic is a plain question and answer.
+	CardTypeBasic = "basic"
+	// CardTypeReverse is a term (Question) and definition (Answer) studied
+	// in both directions.
+	CardTypeReverse = "reverse"
+	// CardTypeCloze hides the {{c1::...}} deletions in Cloze.
+	CardTypeCloze = "cloze"
+	// CardTypeMCQ offers Options, of which CorrectIndex is right.
+	CardTypeMCQ = "mcq"
+	// CardTypeTrueFalse asks whether the Question statement IsTrue.
+	CardTypeTrueFalse = "true_false"
+)
+
+// CardTypes lists every card type.
+var CardTypes = []string{CardTypeBasic, CardTypeReverse, CardTypeCloze, CardTypeMCQ, CardTypeTrueFalse}
+
 type Flashcard struct {
+	// ID identifies the card once its set is saved as a deck.
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty" enum:"basic,reverse,cloze,mcq,true_false"`
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
 	Topic    string `json:"topic,omitempty"`
+	Source   string `json:"source,omitempty"`
+
+	// Cloze is the text of a cloze card with its deletions marked as
+	// {{c1::answer}} or {{c1::answer::hint}}.
+	Cloze string `json:"cloze,omitempty"`
+
+	// Options, CorrectIndex and Rationales belong to multiple choice
+	// cards. Rationales, when given, explain each option in order.
+	Options      []string `json:"options,omitempty"`
+	CorrectIndex *int     `json:"correctIndex,omitempty"`
+	Rationales   []string `json:"rationales,omitempty"`
+
+	// IsTrue and Explanation belong to true/false cards.
+	IsTrue      *bool  `json:"isTrue,omitempty"`
+	Explanation string `json:"explanation,omitempty"`
+
+	// Evidence quotes the passage of the source that supports the answer
+	// and SourcePages lists the PDF pages it is on.
+	Evidence    string `json:"evidence,omitempty"`
+	SourcePages []int  `json:"sourcePages,omitempty"`
+
+	// Grounded reports whether Evidence was found in the source text. It
+	// is set by the service, never by the model, and is nil when the card
+	// wasn't checked.
+	Grounded *bool `json:"grounded,omitempty"`
+}
+
+// CardType returns the card's type, treating an empty type as basic.
+func (c Flashcard) CardType() string {
+	if c.Type == "" {
+		return CardTypeBasic
+	}
+	return c.Type
+}
+
+// Citation names the card's document and pages, e.g. "notes.pdf, pages
+// 2, 3". It is empty when the card cites neither.
+func (c Flashcard) Citation() string {
+	var parts []string
+	if c.Source != "" {
+		parts = append(parts, c.Source)
+	}
+	if len(c.SourcePages) > 0 {
+		pages := make([]string, len(c.SourcePages))
+		for i, page := range c.SourcePages {
+			pages[i] = strconv.Itoa(page)
+		}
+		label := "page"
+		if len(pages) > 1 {
+			label = "pages"
+		}
+		parts = append(parts, label+" "+strings.Join(pages, ", "))
+	}
+	return strings.Join(parts, ", ")
 }
 
 type FlashcardSet struct {
-	Title      string      `json:"title"`
-	Flashcards []Flashcard `json:"flashcards"`
-	Source     string      `json:"source"`
-	CreatedAt  string      `json:"createdAt"`
-	TotalCards int         `json:"totalCards"`
+	Title      string             `json:"title"`
+	Flashcards []Flashcard        `json:"flashcards"`
+	Source     string             `json:"source"`
+	Sources    []string           `json:"sources,omitempty"`
+	CreatedAt  string             `json:"createdAt"`
+	TotalCards int                `json:"totalCards"`
+	Options    *GenerationOptions `json:"options,omitempty"`
+
+	// UnmetConstraints lists the requested options the generated set
+	// doesn't satisfy.
+	UnmetConstraints []string `json:"unmetConstraints,omitempty"`
+
+	// DeckID is the ID of the deck the set is saved as.
+	DeckID string `json:"deckId,omitempty"`
+
+	// Cached reports that the set was served from the cache of earlier
+	// results rather than generated for this request. CreatedAt is then
+	// when it was first generated.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Difficulty levels accepted in GenerationOptions
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// GenerationOptions shape a generated deck. Zero values leave the choice to
+// the generator.
+type GenerationOptions struct {
+	CardCount     int      `json:"cardCount,omitempty"`
+	Difficulty    string   `json:"difficulty,omitempty"`
+	Audience      string   `json:"audience,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	FocusTopics   []string `json:"focusTopics,omitempty"`
+	ExcludeTopics []string `json:"excludeTopics,omitempty"`
+
+	// CardTypes is the mix of card types to generate. Empty means basic
+	// cards only.
+	CardTypes []string `json:"cardTypes,omitempty" enum:"basic,reverse,cloze,mcq,true_false"`
+
+	// NoCache asks for a freshly generated deck even when an identical
+	// request has been cached. The fresh deck replaces the cached one.
+	NoCache bool `json:"noCache,omitempty"`
+}
+
+// IsZero reports whether no option that shapes the deck is set. NoCache
+// only affects where the deck comes from, so it is ignored.
+func (o GenerationOptions) IsZero() bool {
+	return o.CardCount == 0 && o.Difficulty == "" && o.Audience == "" && o.Language == "" &&
+		len(o.FocusTopics) == 0 && len(o.ExcludeTopics) == 0 && len(o.CardTypes) == 0
 }
 
 type PDFProcessRequest struct {
 	URL      string
 	FilePath string
 	Content  []byte
-}
\ No newline at end of file
+}
+
+// FlashcardSetSchema is the JSON Schema of FlashcardSet, declared on the
+// structured data parts returned to A2A clients.
+var FlashcardSetSchema = map[string]interface{}{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title":   "FlashcardSet",
+	"type":    "object",
+	"properties": map[string]interface{}{
+		"title": map[string]interface{}{"type": "string"},
+		"flashcards": map[string]interface{}{
+			"type":  "array",
+			"items": FlashcardSchema,
+		},
+		"source":     map[string]interface{}{"type": "string"},
+		"sources":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"createdAt":  map[string]interface{}{"type": "string", "format": "date-time"},
+		"totalCards": map[string]interface{}{"type": "integer", "minimum": 0},
+		"options":    SchemaOf(GenerationOptions{}),
+		"unmetConstraints": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+		"deckId": map[string]interface{}{"type": "string"},
+		"cached": map[string]interface{}{"type": "boolean"},
+	},
+	"required": []string{"title", "flashcards", "source", "createdAt", "totalCards"},
+}
+
+// FlashcardSchema is the JSON Schema of a single Flashcard, derived from
+// its json tags so it can't drift from the struct.
+var FlashcardSchema = SchemaOf(Flashcard{})
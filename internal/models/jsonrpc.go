@@ -27,4 +27,13 @@ const (
 	MethodNotFound = -32601
 	InvalidParams  = -32602
 	InternalError  = -32603
-)
\ No newline at end of file
+)
+
+// A2A-specific error codes
+const (
+	TaskNotFoundError                 = -32001
+	TaskNotCancelableError            = -32002
+	PushNotificationNotSupportedError = -32003
+	UnsupportedOperationError         = -32004
+	ContentTypeNotSupportedError      = -32005
+)
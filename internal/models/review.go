@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// ReviewState is one user's spaced repetition schedule for one card. Ease
+// belongs to the SM-2 scheduler and Stability and Difficulty to FSRS;
+// the other fields are shared.
+type ReviewState struct {
+	Owner  string `json:"owner,omitempty"`
+	CardID string `json:"cardId"`
+	DeckID string `json:"deckId"`
+	// Scheduler names the scheduler that last updated the state.
+	Scheduler string `json:"scheduler"`
+
+	// Ease multiplies the interval after each successful review.
+	Ease float64 `json:"ease,omitempty"`
+	// Stability is the interval, in days, after which the card is
+	// recalled with 90% probability.
+	Stability float64 `json:"stability,omitempty"`
+	// Difficulty runs from 1 (easy) to 10 (hard).
+	Difficulty float64 `json:"difficulty,omitempty"`
+
+	// Interval is the number of days from LastReview to Due.
+	Interval int `json:"interval"`
+	// Reps counts the successful reviews since the card was last
+	// forgotten.
+	Reps int `json:"reps"`
+	// Lapses counts the times a learned card was forgotten.
+	Lapses     int       `json:"lapses"`
+	LastGrade  int       `json:"lastGrade"`
+	LastReview time.Time `json:"lastReview"`
+	Due        time.Time `json:"due"`
+}
+
+// ReviewItem is a card due for review, with its schedule so far. State is
+// nil for a card that has never been reviewed.
+type ReviewItem struct {
+	DeckID    string       `json:"deckId"`
+	DeckTitle string       `json:"deckTitle"`
+	Card      Flashcard    `json:"card"`
+	State     *ReviewState `json:"state,omitempty"`
+}
+
+// ReviewQueue lists the cards to review next. Due and New count every
+// due card and every new card, including those beyond the queue's limit.
+type ReviewQueue struct {
+	Cards []ReviewItem `json:"cards"`
+	Due   int          `json:"due"`
+	New   int          `json:"new"`
+}
+
+// How an answer was marked
+const (
+	// MarkedExact means the answer matched the card's answer once case,
+	// punctuation and articles were ignored.
+	MarkedExact = "exact"
+	// MarkedChoice means a multiple choice or true/false answer was
+	// checked against the card.
+	MarkedChoice = "choice"
+	// MarkedModel means the LLM compared the answer with the card's.
+	MarkedModel = "model"
+)
+
+// AnswerMark is the marking of a learner's own answer to a card.
+type AnswerMark struct {
+	// Score is how much of the card's answer was given, from 0 to 1.
+	Score float64 `json:"score"`
+	// MissingPoints are the key points of the card's answer that were
+	// left out or got wrong.
+	MissingPoints []string `json:"missingPoints"`
+	Feedback      string   `json:"feedback"`
+	// Method is how the answer was marked, one of the Marked constants.
+	Method string `json:"method"`
+}
+
+// AnswerResult is a marked answer and the review it was recorded as.
+type AnswerResult struct {
+	CardID   string `json:"cardId"`
+	Answer   string `json:"answer"`
+	Expected string `json:"expected"`
+	AnswerMark
+	// Grade is the review grade the score was turned into, from 1 (Again)
+	// to 4 (Easy).
+	Grade int          `json:"grade"`
+	State *ReviewState `json:"state"`
+}
@@ -0,0 +1,120 @@
+// Package netguard keeps requests the server makes to user-supplied URLs,
+// such as PDF downloads and webhook deliveries, away from loopback,
+// private and other addresses that aren't publicly routable.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrPrivateAddress is returned when a connection would reach an
+	// address that isn't publicly routable.
+	ErrPrivateAddress = errors.New("address is not publicly routable")
+	// ErrTooManyRedirects is returned when a redirect limit is reached.
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrUnsupportedScheme is returned for URLs that aren't http or https.
+	ErrUnsupportedScheme = errors.New("only http and https URLs are supported")
+)
+
+// reservedPrefixes are the special-purpose ranges that netip.Addr has no
+// predicate for.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach private IPv4
+}
+
+// ParseNetworks parses a comma-separated list of networks in CIDR
+// notation, or single IP addresses, to allow despite being private.
+func ParseNetworks(list string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+			}
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		networks = append(networks, prefix.Masked())
+	}
+	return networks, nil
+}
+
+// CheckAddress refuses a dialed address that isn't publicly routable,
+// unless it is in allowed. Call it from a net.Dialer's Control function,
+// so it sees every address once DNS has been resolved and a hostname, a
+// redirect or a DNS answer that changes between lookups can't get past it.
+func CheckAddress(address string, allowed []netip.Prefix) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, network := range allowed {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	if IsPrivate(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// IsPrivate reports whether addr is loopback, private, link-local or
+// otherwise not reachable on the public internet.
+func IsPrivate(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL returns an error unless u is an absolute http or https URL.
+func CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	if u.Hostname() == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// CheckRedirect returns an http.Client CheckRedirect function that follows
+// at most maxRedirects redirects, and only to http and https URLs.
+func CheckRedirect(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, maxRedirects)
+		}
+		return CheckURL(req.URL)
+	}
+}
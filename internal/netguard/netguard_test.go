@@ -0,0 +1,114 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"255.255.255.255", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"::ffff:10.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+		{"8.8.8.8", false},
+		{"93.184.216.34", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPrivate(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks(" 10.1.0.0/16, 192.168.1.7 ,,fd00::1/8")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := []string{"10.1.0.0/16", "192.168.1.7/32", "fd00::/8"}
+	if len(networks) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, networks)
+	}
+	for i, network := range networks {
+		if network.String() != want[i] {
+			t.Errorf("Expected %s, got %s", want[i], network)
+		}
+	}
+
+	if _, err := ParseNetworks("10.0.0.0/33"); err == nil {
+		t.Error("Expected an error for an invalid network")
+	}
+	if networks, _ := ParseNetworks(""); len(networks) != 0 {
+		t.Errorf("Expected no networks, got %v", networks)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}
+
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:4700:4700::1111]:443", false},
+		{"127.0.0.1:8080", true},
+		{"[::ffff:127.0.0.1]:8080", true},
+		{"169.254.169.254:80", true},
+		{"10.2.0.1:80", true},
+		{"10.1.2.3:80", false},
+		{"not-an-address", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := CheckAddress(tt.address, allowed)
+			if tt.wantErr && !errors.Is(err, ErrPrivateAddress) {
+				t.Errorf("Expected ErrPrivateAddress, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	check := CheckRedirect(2)
+	request := func(rawURL string) *http.Request {
+		u, _ := url.Parse(rawURL)
+		return &http.Request{URL: u}
+	}
+	via := []*http.Request{request("https://example.com/a"), request("https://example.com/b")}
+
+	if err := check(request("https://example.com/c"), via); err != nil {
+		t.Errorf("Expected the redirect to be followed, got %v", err)
+	}
+	if err := check(request("https://example.com/d"), append(via, request("https://example.com/c"))); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Expected ErrTooManyRedirects, got %v", err)
+	}
+	if err := check(request("file:///etc/passwd"), via[:1]); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Errorf("Expected ErrUnsupportedScheme, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package push
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// ErrConfigNotFound is returned when a task has no matching push config.
+var ErrConfigNotFound = errors.New("push notification config not found")
+
+// ConfigStore keeps the webhook configs registered for each task.
+type ConfigStore interface {
+	Set(taskID string, config models.PushNotificationConfig) (models.PushNotificationConfig, error)
+	Get(taskID, configID string) (models.PushNotificationConfig, error)
+	List(taskID string) ([]models.PushNotificationConfig, error)
+	Delete(taskID, configID string) error
+}
+
+// MemoryConfigStore is the default ConfigStore.
+type MemoryConfigStore struct {
+	mu      sync.RWMutex
+	configs map[string][]models.PushNotificationConfig
+}
+
+func NewMemoryConfigStore() *MemoryConfigStore {
+	return &MemoryConfigStore{
+		configs: make(map[string][]models.PushNotificationConfig),
+	}
+}
+
+// Set adds config to the task, replacing any config with the same ID. A
+// config without an ID is assigned one.
+func (s *MemoryConfigStore) Set(taskID string, config models.PushNotificationConfig) (models.PushNotificationConfig, error) {
+	if config.ID == "" {
+		config.ID = uuid.New().String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configs := s.configs[taskID]
+	for i := range configs {
+		if configs[i].ID == config.ID {
+			configs[i] = config
+			return config, nil
+		}
+	}
+	s.configs[taskID] = append(configs, config)
+	return config, nil
+}
+
+// Get returns the task's config with configID, or its first config when
+// configID is empty.
+func (s *MemoryConfigStore) Get(taskID, configID string) (models.PushNotificationConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, config := range s.configs[taskID] {
+		if configID == "" || config.ID == configID {
+			return config, nil
+		}
+	}
+	return models.PushNotificationConfig{}, ErrConfigNotFound
+}
+
+func (s *MemoryConfigStore) List(taskID string) ([]models.PushNotificationConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.PushNotificationConfig(nil), s.configs[taskID]...), nil
+}
+
+func (s *MemoryConfigStore) Delete(taskID, configID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configs := s.configs[taskID]
+	for i := range configs {
+		if configs[i].ID == configID {
+			s.configs[taskID] = append(configs[:i:i], configs[i+1:]...)
+			if len(s.configs[taskID]) == 0 {
+				delete(s.configs, taskID)
+			}
+			return nil
+		}
+	}
+	return ErrConfigNotFound
+}
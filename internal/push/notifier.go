@@ -0,0 +1,351 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
+)
+
+// maxRecordedAttempts bounds the delivery log kept per task.
+const maxRecordedAttempts = 100
+
+// finishedLogTTL is how long a task's delivery log is kept once its final
+// state has been delivered.
+const finishedLogTTL = time.Hour
+
+// maxRedirects is the most redirects followed when delivering to a webhook.
+const maxRedirects = 5
+
+// Delivery attempt outcomes
+const (
+	DeliverySucceeded = "succeeded"
+	DeliveryRetrying  = "retrying"
+	DeliveryFailed    = "failed"
+)
+
+// DeliveryAttempt records one POST of a task to a webhook.
+type DeliveryAttempt struct {
+	TaskID     string `json:"taskId"`
+	ConfigID   string `json:"configId"`
+	URL        string `json:"url"`
+	State      string `json:"state"`
+	TaskState  string `json:"taskState"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Notifier posts tasks to their registered webhooks whenever the task state
+// changes. Deliveries for one task are sent in order; failed deliveries are
+// retried with exponential backoff until they succeed, attempts run out or
+// Shutdown gives up waiting for them.
+type Notifier struct {
+	configs     ConfigStore
+	httpClient  *http.Client
+	maxAttempts int
+	backoff     time.Duration
+
+	mu        sync.Mutex
+	lastState map[string]string
+	queues    map[string][]*models.TaskResult
+	attempts  map[string][]DeliveryAttempt
+	finished  map[string]time.Time
+	pruned    time.Time
+	wg        sync.WaitGroup
+	stop      chan struct{}
+	stopOnce  sync.Once
+
+	// AllowedNetworks are private networks that webhooks may be
+	// delivered to anyway. All other loopback, private and link-local
+	// addresses are refused, so a webhook URL can't be used to make the
+	// server POST to internal services.
+	AllowedNetworks []netip.Prefix
+
+	// Now returns the current time; tests replace it to expire logs.
+	Now func() time.Time
+}
+
+// NewNotifier creates a Notifier that tries each delivery up to maxAttempts
+// times, waiting backoff, 2*backoff, 4*backoff... between attempts.
+func NewNotifier(configs ConfigStore, maxAttempts int, backoff time.Duration) *Notifier {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	n := &Notifier{
+		configs:     configs,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		lastState:   make(map[string]string),
+		queues:      make(map[string][]*models.TaskResult),
+		attempts:    make(map[string][]DeliveryAttempt),
+		finished:    make(map[string]time.Time),
+		stop:        make(chan struct{}),
+		Now:         time.Now,
+	}
+	// Every address dialed is checked once DNS has been resolved, and
+	// proxies are ignored, since the address dialed would be the proxy's
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: n.checkDial,
+	}
+	n.httpClient = &http.Client{
+		Timeout:       10 * time.Second,
+		Transport:     &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: netguard.CheckRedirect(maxRedirects),
+	}
+	return n
+}
+
+// checkDial refuses connections to addresses that aren't publicly
+// routable, unless they are in AllowedNetworks.
+func (n *Notifier) checkDial(network, address string, _ syscall.RawConn) error {
+	return netguard.CheckAddress(address, n.AllowedNetworks)
+}
+
+// Configs returns the store the notifier reads webhook configs from.
+func (n *Notifier) Configs() ConfigStore {
+	return n.configs
+}
+
+// Notify queues task for delivery if its state changed since the last
+// notification. It never blocks on the network.
+func (n *Notifier) Notify(task *models.TaskResult) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pruneLogs()
+	if n.lastState[task.ID] == task.Status.State {
+		return
+	}
+	n.lastState[task.ID] = task.Status.State
+	delete(n.finished, task.ID)
+
+	snapshot := *task
+	pending, active := n.queues[task.ID]
+	n.queues[task.ID] = append(pending, &snapshot)
+	if active {
+		return
+	}
+
+	n.wg.Add(1)
+	go n.drain(task.ID)
+}
+
+// drain delivers queued notifications for one task until its queue is empty.
+func (n *Notifier) drain(taskID string) {
+	defer n.wg.Done()
+
+	for {
+		n.mu.Lock()
+		queue := n.queues[taskID]
+		if len(queue) == 0 {
+			delete(n.queues, taskID)
+			n.mu.Unlock()
+			return
+		}
+		task := queue[0]
+		n.queues[taskID] = queue[1:]
+		n.mu.Unlock()
+
+		configs, err := n.configs.List(taskID)
+		if err != nil {
+			log.Printf("Error loading push configs for task %s: %v", taskID, err)
+			continue
+		}
+		for _, config := range configs {
+			n.deliver(task, config)
+		}
+
+		// A task in a final state won't be notified again, so forget it,
+		// and drop its delivery log once that has been kept for a while
+		if models.IsTerminalState(task.Status.State) {
+			n.mu.Lock()
+			if n.lastState[taskID] == task.Status.State {
+				delete(n.lastState, taskID)
+			}
+			n.finished[taskID] = n.Now()
+			n.mu.Unlock()
+		}
+	}
+}
+
+func (n *Notifier) deliver(task *models.TaskResult, config models.PushNotificationConfig) {
+	body, err := json.Marshal(task)
+	if err != nil {
+		log.Printf("Error encoding task %s for push notification: %v", task.ID, err)
+		return
+	}
+
+	delay := n.backoff
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		statusCode, err := n.post(config, body)
+
+		record := DeliveryAttempt{
+			TaskID:     task.ID,
+			ConfigID:   config.ID,
+			URL:        config.URL,
+			TaskState:  task.Status.State,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		retry := err != nil && retryable(statusCode, err) && attempt < n.maxAttempts
+		switch {
+		case err == nil:
+			record.State = DeliverySucceeded
+		case retry:
+			record.State = DeliveryRetrying
+		default:
+			record.State = DeliveryFailed
+		}
+		n.record(record)
+
+		if !retry {
+			if err != nil {
+				log.Printf("Push notification for task %s to %s failed: %v", task.ID, config.URL, err)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-n.stop:
+			log.Printf("Push notification for task %s to %s abandoned at shutdown", task.ID, config.URL)
+			n.abandon(record)
+			return
+		}
+		delay *= 2
+	}
+}
+
+func (n *Notifier) post(config models.PushNotificationConfig, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.Token != "" {
+		req.Header.Set("X-A2A-Notification-Token", config.Token)
+	}
+	if auth := config.Authentication; auth != nil && auth.Credentials != "" {
+		for _, scheme := range auth.Schemes {
+			if strings.EqualFold(scheme, "bearer") {
+				req.Header.Set("Authorization", "Bearer "+auth.Credentials)
+				break
+			}
+		}
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed delivery is worth retrying. Network
+// errors (status 0), rate limiting and server errors are; other client
+// errors and refused addresses won't change on retry.
+func retryable(statusCode int, err error) bool {
+	if errors.Is(err, netguard.ErrPrivateAddress) || errors.Is(err, netguard.ErrTooManyRedirects) || errors.Is(err, netguard.ErrUnsupportedScheme) {
+		return false
+	}
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func (n *Notifier) record(attempt DeliveryAttempt) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	attempts := append(n.attempts[attempt.TaskID], attempt)
+	if len(attempts) > maxRecordedAttempts {
+		attempts = attempts[len(attempts)-maxRecordedAttempts:]
+	}
+	n.attempts[attempt.TaskID] = attempts
+}
+
+// abandon marks a recorded attempt that was waiting to be retried as failed.
+func (n *Notifier) abandon(attempt DeliveryAttempt) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	attempts := n.attempts[attempt.TaskID]
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i] == attempt {
+			attempts[i].State = DeliveryFailed
+			attempts[i].Error += " (not retried: shutting down)"
+			return
+		}
+	}
+}
+
+// pruneLogs drops the delivery logs of tasks whose final state was
+// delivered more than finishedLogTTL ago, checking at most once a minute.
+// The caller must hold n.mu.
+func (n *Notifier) pruneLogs() {
+	now := n.Now()
+	if now.Sub(n.pruned) < time.Minute {
+		return
+	}
+	n.pruned = now
+	for taskID, finished := range n.finished {
+		if now.Sub(finished) >= finishedLogTTL {
+			delete(n.attempts, taskID)
+			delete(n.finished, taskID)
+		}
+	}
+}
+
+// Attempts returns the recorded delivery attempts for a task, oldest first.
+// A task's attempts are kept for an hour after its final state is
+// delivered.
+func (n *Notifier) Attempts(taskID string) []DeliveryAttempt {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pruneLogs()
+	return append([]DeliveryAttempt(nil), n.attempts[taskID]...)
+}
+
+// Shutdown waits for queued deliveries to finish, retries included, or for
+// ctx to expire. Once ctx expires, pending retries are canceled and
+// deliveries still queued are tried only once.
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n.stopOnce.Do(func() { close(n.stop) })
+		return ctx.Err()
+	}
+}
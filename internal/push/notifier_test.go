@@ -0,0 +1,296 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// loopback lets tests deliver to httptest servers.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func TestNotifier_DeliversStateChangesWithRetry(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	calls := 0
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		// Fail the very first delivery to exercise the retry path
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if got := r.Header.Get("X-A2A-Notification-Token"); got != "secret-token" {
+			t.Errorf("Expected notification token header, got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer creds" {
+			t.Errorf("Expected bearer credentials, got %q", got)
+		}
+
+		var task models.TaskResult
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			t.Errorf("Failed to decode pushed task: %v", err)
+		}
+		received = append(received, task.Status.State)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	configs := NewMemoryConfigStore()
+	configs.Set("task-001", models.PushNotificationConfig{
+		ID:    "cfg-001",
+		URL:   receiver.URL,
+		Token: "secret-token",
+		Authentication: &models.PushNotificationAuthenticationInfo{
+			Schemes:     []string{"Bearer"},
+			Credentials: "creds",
+		},
+	})
+
+	notifier := NewNotifier(configs, 3, 10*time.Millisecond)
+	notifier.AllowedNetworks = loopback
+	task := &models.TaskResult{ID: "task-001", Kind: models.KindTask}
+	for _, state := range []string{models.StateSubmitted, models.StateWorking, models.StateWorking, models.StateCompleted} {
+		task.Status = models.Status{State: state}
+		notifier.Notify(task)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Shutdown(ctx); err != nil {
+		t.Fatalf("Deliveries did not finish: %v", err)
+	}
+
+	expected := []string{models.StateSubmitted, models.StateWorking, models.StateCompleted}
+	if len(received) != len(expected) {
+		t.Fatalf("Expected states %v, got %v", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Errorf("Expected state %s at position %d, got %s", expected[i], i, received[i])
+		}
+	}
+
+	attempts := notifier.Attempts("task-001")
+	if len(attempts) != 4 {
+		t.Fatalf("Expected 4 recorded attempts, got %d: %+v", len(attempts), attempts)
+	}
+	if attempts[0].State != DeliveryRetrying || attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected first attempt to be retried after a 503, got %+v", attempts[0])
+	}
+	if attempts[1].State != DeliverySucceeded || attempts[1].Attempt != 2 {
+		t.Errorf("Expected second attempt to succeed, got %+v", attempts[1])
+	}
+}
+
+func TestNotifier_GivesUpOnClientErrors(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer receiver.Close()
+
+	configs := NewMemoryConfigStore()
+	configs.Set("task-001", models.PushNotificationConfig{URL: receiver.URL})
+
+	notifier := NewNotifier(configs, 3, 10*time.Millisecond)
+	notifier.AllowedNetworks = loopback
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateCompleted}})
+	notifier.Shutdown(context.Background())
+
+	attempts := notifier.Attempts("task-001")
+	if len(attempts) != 1 || attempts[0].State != DeliveryFailed {
+		t.Errorf("Expected a single failed attempt, got %+v", attempts)
+	}
+}
+
+func TestNotifier_ShutdownCancelsRetries(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	configs := NewMemoryConfigStore()
+	configs.Set("task-001", models.PushNotificationConfig{URL: receiver.URL})
+
+	notifier := NewNotifier(configs, 3, time.Hour)
+	notifier.AllowedNetworks = loopback
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateCompleted}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := notifier.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// The retry waiting an hour is canceled rather than left running
+	done := make(chan struct{})
+	go func() {
+		notifier.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the pending retry to be canceled")
+	}
+
+	attempts := notifier.Attempts("task-001")
+	if len(attempts) != 1 || attempts[0].State != DeliveryFailed {
+		t.Errorf("Expected a single failed attempt, got %+v", attempts)
+	}
+}
+
+func TestNotifier_ForgetsFinishedTasks(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	configs := NewMemoryConfigStore()
+	configs.Set("task-001", models.PushNotificationConfig{URL: receiver.URL})
+
+	notifier := NewNotifier(configs, 1, 0)
+	notifier.AllowedNetworks = loopback
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateWorking}})
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateCompleted}})
+	notifier.Notify(&models.TaskResult{ID: "task-002", Status: models.Status{State: models.StateWorking}})
+	notifier.Shutdown(context.Background())
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if _, ok := notifier.lastState["task-001"]; ok {
+		t.Error("Expected the completed task to be forgotten")
+	}
+	if notifier.lastState["task-002"] != models.StateWorking {
+		t.Errorf("Expected the working task to be remembered, got %q", notifier.lastState["task-002"])
+	}
+}
+
+func TestNotifier_ExpiresFinishedLogs(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	configs := NewMemoryConfigStore()
+	configs.Set("task-001", models.PushNotificationConfig{URL: receiver.URL})
+	configs.Set("task-002", models.PushNotificationConfig{URL: receiver.URL})
+
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	notifier := NewNotifier(configs, 1, 0)
+	notifier.AllowedNetworks = loopback
+	notifier.Now = func() time.Time { return now }
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateCompleted}})
+	notifier.Notify(&models.TaskResult{ID: "task-002", Status: models.Status{State: models.StateWorking}})
+	notifier.Shutdown(context.Background())
+
+	now = now.Add(59 * time.Minute)
+	if len(notifier.Attempts("task-001")) != 1 {
+		t.Error("Expected the finished task's log to be kept for an hour")
+	}
+
+	now = now.Add(time.Minute)
+	if attempts := notifier.Attempts("task-001"); len(attempts) != 0 {
+		t.Errorf("Expected the finished task's log to be dropped, got %+v", attempts)
+	}
+	if len(notifier.Attempts("task-002")) != 1 {
+		t.Error("Expected the working task's log to be kept")
+	}
+}
+
+func TestNotifier_RefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	port := receiver.URL[strings.LastIndex(receiver.URL, ":")+1:]
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusTemporaryRedirect)
+	})
+	redirector := httptest.NewServer(mux)
+	defer redirector.Close()
+
+	configs := NewMemoryConfigStore()
+	for _, url := range []string{receiver.URL, "http://localhost:" + port, "http://[::1]:" + port, "http://169.254.169.254/hook"} {
+		configs.Set("task-001", models.PushNotificationConfig{URL: url})
+	}
+
+	notifier := NewNotifier(configs, 3, time.Hour)
+	notifier.Notify(&models.TaskResult{ID: "task-001", Status: models.Status{State: models.StateCompleted}})
+	notifier.Shutdown(context.Background())
+
+	if hits.Load() != 0 {
+		t.Errorf("Expected no delivery to reach a loopback server, got %d", hits.Load())
+	}
+	attempts := notifier.Attempts("task-001")
+	if len(attempts) != 4 {
+		t.Fatalf("Expected one attempt per webhook without retries, got %+v", attempts)
+	}
+	for _, attempt := range attempts {
+		if attempt.State != DeliveryFailed || !strings.Contains(attempt.Error, "not publicly routable") {
+			t.Errorf("Expected a refused delivery, got %+v", attempt)
+		}
+	}
+
+	// An allowed webhook can't redirect to a private address either
+	configs = NewMemoryConfigStore()
+	configs.Set("task-002", models.PushNotificationConfig{URL: redirector.URL + "/hook"})
+	notifier = NewNotifier(configs, 3, time.Hour)
+	notifier.AllowedNetworks = loopback
+	notifier.Notify(&models.TaskResult{ID: "task-002", Status: models.Status{State: models.StateCompleted}})
+	notifier.Shutdown(context.Background())
+
+	attempts = notifier.Attempts("task-002")
+	if len(attempts) != 1 || attempts[0].State != DeliveryFailed || !strings.Contains(attempts[0].Error, "not publicly routable") {
+		t.Errorf("Expected the redirect to be refused, got %+v", attempts)
+	}
+}
+
+func TestMemoryConfigStore(t *testing.T) {
+	configs := NewMemoryConfigStore()
+
+	first, _ := configs.Set("task-001", models.PushNotificationConfig{URL: "https://example.com/a"})
+	if first.ID == "" {
+		t.Fatal("Expected an ID to be assigned")
+	}
+	configs.Set("task-001", models.PushNotificationConfig{ID: "second", URL: "https://example.com/b"})
+	configs.Set("task-001", models.PushNotificationConfig{ID: "second", URL: "https://example.com/c"})
+
+	list, _ := configs.List("task-001")
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 configs, got %d", len(list))
+	}
+
+	got, err := configs.Get("task-001", "second")
+	if err != nil || got.URL != "https://example.com/c" {
+		t.Errorf("Expected replaced config, got %+v (%v)", got, err)
+	}
+
+	if err := configs.Delete("task-001", first.ID); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+	if err := configs.Delete("task-001", first.ID); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+	if _, err := configs.Get("task-002", ""); err != ErrConfigNotFound {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package review
+
+import (
+	"math"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// FSRS forgetting curve: R(t, S) = (1 + fsrsFactor*t/S)^fsrsDecay, so
+// that R(S, S) = 0.9.
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+// DefaultFSRSWeights are the FSRS-4.5 default parameters, fitted on a
+// large corpus of Anki reviews.
+var DefaultFSRSWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474,
+	0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// FSRS is the Free Spaced Repetition Scheduler (version 4.5). It models
+// each card's memory with a stability, the days until recall drops to
+// 90%, and a difficulty from 1 to 10, and schedules the next review for
+// when the predicted recall falls to Retention. It schedules in whole
+// days, without same-day learning steps.
+type FSRS struct {
+	Weights [17]float64
+	// Retention is the recall probability reviews are scheduled at.
+	Retention float64
+}
+
+// NewFSRS returns an FSRS scheduler with the default weights and 90%
+// retention.
+func NewFSRS() FSRS {
+	return FSRS{Weights: DefaultFSRSWeights, Retention: 0.9}
+}
+
+func (FSRS) Name() string {
+	return SchedulerFSRS
+}
+
+func (f FSRS) Review(state models.ReviewState, grade Grade, now time.Time) models.ReviewState {
+	w := f.Weights
+	g := float64(grade)
+
+	// Cards scheduled by SM-2 have no memory state yet and start afresh
+	if isNew(state) || state.Stability == 0 {
+		state.Stability = w[grade-1]
+		state.Difficulty = f.initialDifficulty(g)
+	} else {
+		elapsed := math.Max(now.Sub(state.LastReview).Hours()/24, 0)
+		r := retrievability(elapsed, state.Stability)
+		d := state.Difficulty
+
+		if grade == Again {
+			forget := w[11] * math.Pow(d, -w[12]) * (math.Pow(state.Stability+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+			state.Stability = math.Min(forget, state.Stability)
+		} else {
+			bonus := 1.0
+			if grade == Hard {
+				bonus = w[15]
+			} else if grade == Easy {
+				bonus = w[16]
+			}
+			growth := math.Exp(w[8]) * (11 - d) * math.Pow(state.Stability, -w[9]) * (math.Exp(w[10]*(1-r)) - 1)
+			state.Stability *= 1 + growth*bonus
+		}
+
+		// Difficulty moves with the grade and reverts towards that of a
+		// card first graded Good
+		d -= w[6] * (g - 3)
+		state.Difficulty = clampDifficulty(w[7]*f.initialDifficulty(3) + (1-w[7])*d)
+	}
+	state.Stability = math.Max(state.Stability, 0.01)
+
+	if grade == Again {
+		if state.Reps > 0 {
+			state.Lapses++
+		}
+		state.Reps = 0
+	} else {
+		state.Reps++
+	}
+	return finish(state, SchedulerFSRS, grade, f.interval(state.Stability), now)
+}
+
+// initialDifficulty is the difficulty of a card first graded g.
+func (f FSRS) initialDifficulty(g float64) float64 {
+	return clampDifficulty(f.Weights[4] - (g-3)*f.Weights[5])
+}
+
+// interval is the number of days until recall of a card with the given
+// stability falls to Retention.
+func (f FSRS) interval(stability float64) int {
+	days := stability / fsrsFactor * (math.Pow(f.Retention, 1/fsrsDecay) - 1)
+	return int(math.Round(days))
+}
+
+// retrievability is the probability of recalling a card with the given
+// stability after elapsed days.
+func retrievability(elapsed, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Min(math.Max(d, 1), 10)
+}
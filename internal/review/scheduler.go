@@ -0,0 +1,77 @@
+// Package review schedules flashcard reviews with spaced repetition and
+// builds each user's queue of due cards.
+package review
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// Grade rates how well a card was recalled.
+type Grade int
+
+// Review grades, as in Anki
+const (
+	// Again means the card was forgotten.
+	Again Grade = 1
+	// Hard means it was recalled with serious difficulty.
+	Hard Grade = 2
+	// Good means it was recalled after some thought.
+	Good Grade = 3
+	// Easy means it was recalled effortlessly.
+	Easy Grade = 4
+)
+
+// Valid reports whether g is one of the four grades.
+func (g Grade) Valid() bool {
+	return g >= Again && g <= Easy
+}
+
+// maxInterval caps intervals at about a century.
+const maxInterval = 36500
+
+// Scheduler decides when a card is next due after a review.
+type Scheduler interface {
+	// Name identifies the scheduler in ReviewState.Scheduler.
+	Name() string
+	// Review returns state updated for a review graded grade at now.
+	Review(state models.ReviewState, grade Grade, now time.Time) models.ReviewState
+}
+
+// Scheduler names
+const (
+	SchedulerSM2  = "sm2"
+	SchedulerFSRS = "fsrs"
+)
+
+// NewScheduler returns the scheduler with the given name, using default
+// parameters.
+func NewScheduler(name string) (Scheduler, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case SchedulerSM2:
+		return SM2{}, nil
+	case SchedulerFSRS, "":
+		return NewFSRS(), nil
+	default:
+		return nil, fmt.Errorf("unknown review scheduler %q, expected %s or %s", name, SchedulerSM2, SchedulerFSRS)
+	}
+}
+
+// finish records the review on state and schedules it interval days
+// after now.
+func finish(state models.ReviewState, name string, grade Grade, interval int, now time.Time) models.ReviewState {
+	state.Scheduler = name
+	state.Interval = min(max(interval, 1), maxInterval)
+	state.LastGrade = int(grade)
+	state.LastReview = now
+	state.Due = now.AddDate(0, 0, state.Interval)
+	return state
+}
+
+// isNew reports whether the card has never been reviewed.
+func isNew(state models.ReviewState) bool {
+	return state.LastReview.IsZero()
+}
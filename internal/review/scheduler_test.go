@@ -0,0 +1,262 @@
+package review
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+var start = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// reviewOnDue grades a new card with each grade in turn, reviewing it
+// exactly when it falls due, and returns the state after each review.
+func reviewOnDue(scheduler Scheduler, grades ...Grade) []models.ReviewState {
+	var states []models.ReviewState
+	state := models.ReviewState{CardID: "card-001"}
+	now := start
+	for _, grade := range grades {
+		state = scheduler.Review(state, grade, now)
+		states = append(states, state)
+		now = state.Due
+	}
+	return states
+}
+
+func intervals(states []models.ReviewState) []int {
+	days := make([]int, len(states))
+	for i, state := range states {
+		days[i] = state.Interval
+	}
+	return days
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSM2_Review(t *testing.T) {
+	tests := []struct {
+		name          string
+		grades        []Grade
+		wantIntervals []int
+		wantEase      float64
+		wantReps      int
+		wantLapses    int
+	}{
+		{"Always good", []Grade{Good, Good, Good, Good, Good, Good}, []int{1, 6, 15, 38, 95, 238}, 2.5, 6, 0},
+		{"Easy raises ease", []Grade{Easy, Easy, Easy}, []int{1, 6, 16}, 2.8, 3, 0},
+		{"Hard lowers ease", []Grade{Hard, Hard, Hard}, []int{1, 6, 13}, 2.08, 3, 0},
+		{"Forgotten card restarts", []Grade{Good, Good, Good, Again, Good, Good}, []int{1, 6, 15, 1, 1, 6}, 2.5, 2, 1},
+		{"Failing a new card is not a lapse", []Grade{Again, Again, Good}, []int{1, 1, 1}, 2.5, 1, 0},
+		{"Ease bottoms out", []Grade{Hard, Hard, Hard, Hard, Hard, Hard, Hard, Hard, Hard, Hard}, []int{1, 6, 13, 27, 52, 94, 156, 237, 327, 425}, minEase, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := reviewOnDue(SM2{}, tt.grades...)
+			last := states[len(states)-1]
+
+			if got := intervals(states); !equalInts(got, tt.wantIntervals) {
+				t.Errorf("Expected intervals %v, got %v", tt.wantIntervals, got)
+			}
+			if math.Abs(last.Ease-tt.wantEase) > 1e-9 {
+				t.Errorf("Expected ease %.2f, got %.4f", tt.wantEase, last.Ease)
+			}
+			if last.Reps != tt.wantReps || last.Lapses != tt.wantLapses {
+				t.Errorf("Expected %d reps and %d lapses, got %d and %d", tt.wantReps, tt.wantLapses, last.Reps, last.Lapses)
+			}
+			if last.Scheduler != SchedulerSM2 || last.LastGrade != int(tt.grades[len(tt.grades)-1]) {
+				t.Errorf("Expected the review to be recorded, got %+v", last)
+			}
+		})
+	}
+}
+
+func TestFSRS_FirstReview(t *testing.T) {
+	tests := []struct {
+		grade          Grade
+		wantStability  float64
+		wantDifficulty float64
+		wantInterval   int
+	}{
+		{Again, 0.4872, 7.6214, 1},
+		{Hard, 1.4003, 6.3916, 1},
+		{Good, 3.7145, 5.1618, 4},
+		{Easy, 13.8206, 3.932, 14},
+	}
+
+	for _, tt := range tests {
+		state := NewFSRS().Review(models.ReviewState{}, tt.grade, start)
+		if math.Abs(state.Stability-tt.wantStability) > 1e-9 || math.Abs(state.Difficulty-tt.wantDifficulty) > 1e-9 {
+			t.Errorf("Grade %d: expected stability %v and difficulty %v, got %v and %v", tt.grade, tt.wantStability, tt.wantDifficulty, state.Stability, state.Difficulty)
+		}
+		if state.Interval != tt.wantInterval {
+			t.Errorf("Grade %d: expected interval %d, got %d", tt.grade, tt.wantInterval, state.Interval)
+		}
+		if state.Lapses != 0 {
+			t.Errorf("Grade %d: expected no lapse on a new card, got %d", tt.grade, state.Lapses)
+		}
+	}
+}
+
+func TestFSRS_Review(t *testing.T) {
+	tests := []struct {
+		name          string
+		grades        []Grade
+		wantIntervals []int
+		wantLapses    int
+	}{
+		{"Always good", []Grade{Good, Good, Good, Good, Good}, []int{4, 15, 49, 146, 393}, 0},
+		{"Always easy", []Grade{Easy, Easy, Easy}, []int{14, 127, 979}, 0},
+		{"Always hard", []Grade{Hard, Hard, Hard, Hard}, []int{1, 2, 3, 4}, 0},
+		{"Forgotten card", []Grade{Good, Good, Good, Again, Good}, []int{4, 15, 49, 6, 17}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := reviewOnDue(NewFSRS(), tt.grades...)
+			if got := intervals(states); !equalInts(got, tt.wantIntervals) {
+				t.Errorf("Expected intervals %v, got %v", tt.wantIntervals, got)
+			}
+			if last := states[len(states)-1]; last.Lapses != tt.wantLapses {
+				t.Errorf("Expected %d lapses, got %d", tt.wantLapses, last.Lapses)
+			}
+		})
+	}
+}
+
+func TestFSRS_Retention(t *testing.T) {
+	strict := NewFSRS()
+	strict.Retention = 0.95
+	relaxed := NewFSRS()
+	relaxed.Retention = 0.8
+
+	state := models.ReviewState{}
+	if a, b := strict.Review(state, Easy, start).Interval, relaxed.Review(state, Easy, start).Interval; a >= b {
+		t.Errorf("Expected higher retention to schedule sooner, got %d and %d days", a, b)
+	}
+	// At 90% retention the interval is the stability
+	if r := retrievability(10, 10); math.Abs(r-0.9) > 1e-9 {
+		t.Errorf("Expected 90%% recall after the stability, got %v", r)
+	}
+}
+
+func TestFSRS_TakesOverSM2State(t *testing.T) {
+	state := reviewOnDue(SM2{}, Good, Good)[1]
+	next := NewFSRS().Review(state, Good, state.Due)
+
+	if next.Scheduler != SchedulerFSRS || next.Stability == 0 || next.Difficulty == 0 {
+		t.Errorf("Expected FSRS to start a memory state, got %+v", next)
+	}
+	if next.Reps != 3 || next.Ease != state.Ease {
+		t.Errorf("Expected the shared fields to carry over, got %+v", next)
+	}
+}
+
+// simulatedLearner recalls each review with the probability FSRS
+// predicts for a typical learner, using a fixed seed so runs repeat
+// exactly.
+type simulatedLearner struct {
+	rng *rand.Rand
+}
+
+func (l *simulatedLearner) grade(state models.ReviewState, now time.Time) Grade {
+	recall := 0.9
+	if !state.LastReview.IsZero() && state.Stability > 0 {
+		recall = retrievability(now.Sub(state.LastReview).Hours()/24, state.Stability)
+	}
+	roll := l.rng.Float64()
+	switch {
+	case roll > recall:
+		return Again
+	case roll > recall*0.85:
+		return Hard
+	case roll < recall*0.1:
+		return Easy
+	default:
+		return Good
+	}
+}
+
+// simulateYear reviews 50 cards every day for a year with scheduler,
+// checking the schedule after each review, and returns the number of
+// reviews and lapses.
+func simulateYear(t *testing.T, scheduler Scheduler) (reviews, lapses int) {
+	t.Helper()
+
+	learner := &simulatedLearner{rng: rand.New(rand.NewPCG(1, 2))}
+	// FSRS memory of each card for the learner, so SM-2 runs are graded
+	// by the same model of memory
+	memory := NewFSRS()
+	cards := make([]models.ReviewState, 50)
+	memories := make([]models.ReviewState, 50)
+
+	for day := 0; day < 365; day++ {
+		now := start.AddDate(0, 0, day)
+		for i := range cards {
+			if !cards[i].LastReview.IsZero() && cards[i].Due.After(now) {
+				continue
+			}
+			grade := learner.grade(memories[i], now)
+			memories[i] = memory.Review(memories[i], grade, now)
+
+			previous := cards[i]
+			cards[i] = scheduler.Review(cards[i], grade, now)
+			reviews++
+
+			state := cards[i]
+			if !state.Due.After(now) || state.Interval < 1 || state.Interval > maxInterval {
+				t.Fatalf("Day %d: card %d scheduled badly: %+v", day, i, state)
+			}
+			if grade == Again && previous.Reps > 0 && state.Lapses != previous.Lapses+1 {
+				t.Fatalf("Day %d: card %d lapse not counted: %+v", day, i, state)
+			}
+			if state.Ease != 0 && state.Ease < minEase {
+				t.Fatalf("Day %d: card %d ease below %v: %v", day, i, minEase, state.Ease)
+			}
+			if state.Difficulty != 0 && (state.Difficulty < 1 || state.Difficulty > 10) {
+				t.Fatalf("Day %d: card %d difficulty out of range: %v", day, i, state.Difficulty)
+			}
+		}
+	}
+	for _, card := range cards {
+		lapses += card.Lapses
+	}
+	return reviews, lapses
+}
+
+func TestSchedulers_SimulateYear(t *testing.T) {
+	tests := []struct {
+		scheduler   Scheduler
+		wantReviews int
+		wantLapses  int
+	}{
+		{SM2{}, 455, 32},
+		{NewFSRS(), 432, 33},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheduler.Name(), func(t *testing.T) {
+			reviews, lapses := simulateYear(t, tt.scheduler)
+			if reviews != tt.wantReviews || lapses != tt.wantLapses {
+				t.Errorf("Expected %d reviews and %d lapses, got %d and %d", tt.wantReviews, tt.wantLapses, reviews, lapses)
+			}
+
+			// The same seed replays the same year
+			if again, _ := simulateYear(t, tt.scheduler); again != reviews {
+				t.Errorf("Expected a repeat run to make %d reviews, got %d", reviews, again)
+			}
+		})
+	}
+}
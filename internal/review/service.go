@@ -0,0 +1,217 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// Queue limits
+const (
+	DefaultQueueLimit = 20
+	MaxQueueLimit     = 100
+)
+
+// AnswerMarker marks a learner's own answer to a card.
+type AnswerMarker interface {
+	MarkAnswer(ctx context.Context, card models.Flashcard, answer string) (*models.AnswerMark, error)
+}
+
+// Service reviews the cards in each user's deck library.
+type Service struct {
+	decks     store.DeckStore
+	states    store.ReviewStore
+	scheduler Scheduler
+
+	// Marker marks answers given to Answer. Answer fails without one.
+	Marker AnswerMarker
+
+	// Now returns the current time; tests replace it to simulate days
+	// of reviews.
+	Now func() time.Time
+}
+
+// NewService creates a service that schedules the cards in decks with
+// scheduler, keeping their schedules in states.
+func NewService(decks store.DeckStore, states store.ReviewStore, scheduler Scheduler) *Service {
+	return &Service{
+		decks:     decks,
+		states:    states,
+		scheduler: scheduler,
+		Now:       time.Now,
+	}
+}
+
+// Scheduler returns the scheduler reviews are graded with.
+func (s *Service) Scheduler() Scheduler {
+	return s.scheduler
+}
+
+// Next returns up to limit of the owner's cards to review, from one deck
+// when deckID is set. Cards already due come first, most overdue first,
+// followed by new cards in deck order.
+func (s *Service) Next(owner, deckID string, limit int) (*models.ReviewQueue, error) {
+	if limit <= 0 {
+		limit = DefaultQueueLimit
+	}
+	limit = min(limit, MaxQueueLimit)
+
+	decks, err := s.ownerDecks(owner, deckID)
+	if err != nil {
+		return nil, err
+	}
+	states, err := s.states.List(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.Now()
+	var due, fresh []models.ReviewItem
+	for _, deck := range decks {
+		for _, card := range deck.Set.Flashcards {
+			item := models.ReviewItem{DeckID: deck.ID, DeckTitle: deck.Set.Title, Card: card}
+			state, ok := states[card.ID]
+			switch {
+			case !ok:
+				fresh = append(fresh, item)
+			case !state.Due.After(now):
+				item.State = state
+				due = append(due, item)
+			}
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].State.Due.Before(due[j].State.Due)
+	})
+
+	queue := &models.ReviewQueue{
+		Cards: append(due, fresh...),
+		Due:   len(due),
+		New:   len(fresh),
+	}
+	if len(queue.Cards) > limit {
+		queue.Cards = queue.Cards[:limit]
+	}
+	if queue.Cards == nil {
+		queue.Cards = []models.ReviewItem{}
+	}
+	return queue, nil
+}
+
+// Review records the owner's review of a card and returns its new
+// schedule.
+func (s *Service) Review(owner, cardID string, grade Grade) (*models.ReviewState, error) {
+	if !grade.Valid() {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			fmt.Sprintf("grade must be between %d and %d", Again, Easy),
+			nil,
+		)
+	}
+
+	deck, _, err := store.FindCard(s.decks, owner, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.states.Get(owner, cardID)
+	if errors.Is(err, store.ErrReviewStateNotFound) {
+		state = &models.ReviewState{Owner: owner, CardID: cardID}
+	} else if err != nil {
+		return nil, err
+	}
+	state.DeckID = deck.ID
+
+	next := s.scheduler.Review(*state, grade, s.Now().UTC())
+	if err := s.states.Save(&next); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// Answer marks the owner's answer to a card and records the review with
+// the grade the score earns.
+func (s *Service) Answer(ctx context.Context, owner, cardID, answer string) (*models.AnswerResult, error) {
+	if s.Marker == nil {
+		return nil, apperrors.NewAppError(models.InternalError, "answer marking is not configured", nil)
+	}
+
+	deck, index, err := store.FindCard(s.decks, owner, cardID)
+	if err != nil {
+		return nil, err
+	}
+	card := deck.Set.Flashcards[index]
+
+	mark, err := s.Marker.MarkAnswer(ctx, card, answer)
+	if err != nil {
+		return nil, err
+	}
+	grade := GradeForScore(mark.Score)
+	state, err := s.Review(owner, cardID, grade)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AnswerResult{
+		CardID:     cardID,
+		Answer:     strings.TrimSpace(answer),
+		Expected:   card.Answer,
+		AnswerMark: *mark,
+		Grade:      int(grade),
+		State:      state,
+	}, nil
+}
+
+// GradeForScore turns the score of a marked answer into a review grade. A
+// typed answer says nothing about how much effort it took, so the best it
+// earns is Good.
+func GradeForScore(score float64) Grade {
+	switch {
+	case score >= 0.9:
+		return Good
+	case score >= 0.5:
+		return Hard
+	default:
+		return Again
+	}
+}
+
+// ownerDecks loads the owner's decks, or just the one with deckID.
+func (s *Service) ownerDecks(owner, deckID string) ([]*models.Deck, error) {
+	if deckID != "" {
+		deck, err := s.decks.Get(deckID)
+		if err != nil {
+			return nil, err
+		}
+		if deck.Owner != owner {
+			return nil, store.ErrDeckNotFound
+		}
+		return []*models.Deck{deck}, nil
+	}
+
+	summaries, err := s.decks.List(owner)
+	if err != nil {
+		return nil, err
+	}
+	// Oldest decks first, so new cards are introduced in the order the
+	// decks were made
+	decks := make([]*models.Deck, 0, len(summaries))
+	for i := len(summaries) - 1; i >= 0; i-- {
+		deck, err := s.decks.Get(summaries[i].ID)
+		if errors.Is(err, store.ErrDeckNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, deck)
+	}
+	return decks, nil
+}
@@ -0,0 +1,345 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/store"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// clock is a fake time source that tests move forward.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+// newTestService returns a service over alice's deck of count cards,
+// with its clock at start.
+func newTestService(t *testing.T, scheduler Scheduler, count int) (*Service, *models.Deck, *clock) {
+	t.Helper()
+
+	set := &models.FlashcardSet{Title: "Biology"}
+	for i := 0; i < count; i++ {
+		set.Flashcards = append(set.Flashcards, models.Flashcard{Question: "Q", Answer: "A"})
+	}
+	decks := store.NewMemoryDeckStore()
+	deck := store.NewDeck("alice", set)
+	if err := decks.Create(deck); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &clock{now: start}
+	service := NewService(decks, store.NewMemoryReviewStore(), scheduler)
+	service.Now = c.Now
+	return service, deck, c
+}
+
+func cardIDs(queue *models.ReviewQueue) []string {
+	ids := make([]string, len(queue.Cards))
+	for i, item := range queue.Cards {
+		ids[i] = item.Card.ID
+	}
+	return ids
+}
+
+func TestService_Next(t *testing.T) {
+	service, deck, c := newTestService(t, SM2{}, 4)
+	cards := deck.Set.Flashcards
+
+	queue, err := service.Next("alice", "", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if queue.New != 4 || queue.Due != 0 || len(queue.Cards) != 4 || queue.Cards[0].Card.ID != cards[0].ID {
+		t.Errorf("Expected four new cards in deck order, got %+v", queue)
+	}
+	if queue.Cards[0].DeckID != deck.ID || queue.Cards[0].DeckTitle != "Biology" || queue.Cards[0].State != nil {
+		t.Errorf("Expected a new card from the deck, got %+v", queue.Cards[0])
+	}
+
+	// Card 3 is due tomorrow and card 1 in six days
+	service.Review("alice", cards[3].ID, Good)
+	service.Review("alice", cards[1].ID, Good)
+	c.now = c.now.AddDate(0, 0, 1)
+	service.Review("alice", cards[1].ID, Good)
+	c.now = c.now.AddDate(0, 0, 6)
+
+	queue, _ = service.Next("alice", "", 0)
+	want := []string{cards[3].ID, cards[1].ID, cards[0].ID, cards[2].ID}
+	if got := cardIDs(queue); !equalStrings(got, want) {
+		t.Errorf("Expected overdue cards before new cards %v, got %v", want, got)
+	}
+	if queue.Due != 2 || queue.New != 2 || queue.Cards[0].State.Interval != 1 {
+		t.Errorf("Expected two due and two new cards, got %+v", queue)
+	}
+
+	queue, _ = service.Next("alice", "", 3)
+	if len(queue.Cards) != 3 || queue.Due != 2 || queue.New != 2 {
+		t.Errorf("Expected three cards with full counts, got %+v", queue)
+	}
+
+	// Reviewing card 3 again puts it out of the queue until it's due
+	service.Review("alice", cards[3].ID, Good)
+	queue, _ = service.Next("alice", deck.ID, 0)
+	if got := cardIDs(queue); len(got) != 3 || got[0] != cards[1].ID {
+		t.Errorf("Expected the reviewed card to leave the queue, got %v", got)
+	}
+}
+
+func TestService_Next_Decks(t *testing.T) {
+	service, deck, _ := newTestService(t, NewFSRS(), 2)
+	other := store.NewDeck("alice", &models.FlashcardSet{Title: "Chemistry", Flashcards: []models.Flashcard{{Question: "Q", Answer: "A"}}})
+	service.decks.Create(other)
+
+	queue, _ := service.Next("alice", "", 0)
+	if len(queue.Cards) != 3 || queue.Cards[0].DeckID != deck.ID || queue.Cards[2].DeckID != other.ID {
+		t.Errorf("Expected older decks' new cards first, got %+v", queue.Cards)
+	}
+
+	queue, _ = service.Next("alice", other.ID, 0)
+	if len(queue.Cards) != 1 || queue.Cards[0].DeckTitle != "Chemistry" {
+		t.Errorf("Expected one deck's cards, got %+v", queue.Cards)
+	}
+
+	queue, _ = service.Next("bob", "", 0)
+	if len(queue.Cards) != 0 || queue.Cards == nil {
+		t.Errorf("Expected an empty queue for bob, got %+v", queue)
+	}
+
+	for _, deckID := range []string{"missing", deck.ID} {
+		if _, err := service.Next("bob", deckID, 0); !errors.Is(err, store.ErrDeckNotFound) {
+			t.Errorf("Expected ErrDeckNotFound for deck %q, got %v", deckID, err)
+		}
+	}
+}
+
+func TestService_Review(t *testing.T) {
+	service, deck, _ := newTestService(t, NewFSRS(), 1)
+	cardID := deck.Set.Flashcards[0].ID
+
+	state, err := service.Review("alice", cardID, Good)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if state.Owner != "alice" || state.CardID != cardID || state.DeckID != deck.ID || state.Reps != 1 {
+		t.Errorf("Expected alice's first review of the card, got %+v", state)
+	}
+	if !state.Due.Equal(start.AddDate(0, 0, 4)) || state.Scheduler != SchedulerFSRS {
+		t.Errorf("Expected the card due in 4 days, got %+v", state)
+	}
+
+	tests := []struct {
+		name    string
+		owner   string
+		cardID  string
+		grade   Grade
+		wantErr error
+	}{
+		{"Unknown card", "alice", "missing", Good, store.ErrCardNotFound},
+		{"Another user's card", "bob", cardID, Good, store.ErrCardNotFound},
+		{"Grade too low", "alice", cardID, 0, nil},
+		{"Grade too high", "alice", cardID, 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Review(tt.owner, tt.cardID, tt.grade)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) || appErr.Code != models.InvalidParams {
+				t.Errorf("Expected an invalid params error, got %v", err)
+			}
+		})
+	}
+
+	if state, _ := service.states.Get("alice", cardID); state.Reps != 1 {
+		t.Errorf("Expected failed reviews to leave the state alone, got %+v", state)
+	}
+}
+
+// fixedMarker gives every answer the same score.
+type fixedMarker struct {
+	score   float64
+	err     error
+	answers []string
+}
+
+func (m *fixedMarker) MarkAnswer(ctx context.Context, card models.Flashcard, answer string) (*models.AnswerMark, error) {
+	m.answers = append(m.answers, card.ID+"="+answer)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &models.AnswerMark{Score: m.score, MissingPoints: []string{}, Feedback: "Marked", Method: models.MarkedModel}, nil
+}
+
+func TestService_Answer(t *testing.T) {
+	service, deck, _ := newTestService(t, SM2{}, 1)
+	card := deck.Set.Flashcards[0]
+
+	if _, err := service.Answer(context.Background(), "alice", card.ID, "A"); err == nil {
+		t.Error("Expected an error without a marker")
+	}
+
+	marker := &fixedMarker{score: 0.7}
+	service.Marker = marker
+	result, err := service.Answer(context.Background(), "alice", card.ID, "  My answer ")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.CardID != card.ID || result.Answer != "My answer" || result.Expected != "A" || result.Score != 0.7 || result.Feedback != "Marked" {
+		t.Errorf("Expected the marked answer, got %+v", result)
+	}
+	if result.Grade != int(Hard) || result.State == nil || result.State.LastGrade != int(Hard) || result.State.Reps != 1 {
+		t.Errorf("Expected the answer reviewed as Hard, got grade %d and %+v", result.Grade, result.State)
+	}
+	if saved, _ := service.states.Get("alice", card.ID); saved.LastGrade != int(Hard) {
+		t.Errorf("Expected the review to be saved, got %+v", saved)
+	}
+
+	if _, err := service.Answer(context.Background(), "bob", card.ID, "A"); !errors.Is(err, store.ErrCardNotFound) {
+		t.Errorf("Expected ErrCardNotFound for bob, got %v", err)
+	}
+	if len(marker.answers) != 1 {
+		t.Errorf("Expected only alice's answer to be marked, got %v", marker.answers)
+	}
+
+	// A failed marking isn't recorded as a review
+	marker.err = errors.New("model down")
+	if _, err := service.Answer(context.Background(), "alice", card.ID, "A"); err == nil {
+		t.Error("Expected the marker's error")
+	}
+	if saved, _ := service.states.Get("alice", card.ID); saved.Reps != 1 {
+		t.Errorf("Expected no review after a failed marking, got %+v", saved)
+	}
+}
+
+func TestGradeForScore(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  Grade
+	}{
+		{1, Good},
+		{0.9, Good},
+		{0.89, Hard},
+		{0.5, Hard},
+		{0.49, Again},
+		{0, Again},
+	}
+
+	for _, tt := range tests {
+		if got := GradeForScore(tt.score); got != tt.want {
+			t.Errorf("Score %v: expected grade %d, got %d", tt.score, tt.want, got)
+		}
+	}
+}
+
+// studyDays reviews the whole queue each day for days days, grading
+// each card with grade, and returns the number of reviews made each day.
+func studyDays(t *testing.T, service *Service, c *clock, days int, grade func(day int, item models.ReviewItem) Grade) []int {
+	t.Helper()
+
+	reviews := make([]int, days)
+	for day := 0; day < days; day++ {
+		queue, err := service.Next("alice", "", MaxQueueLimit)
+		if err != nil {
+			t.Fatalf("Day %d: %v", day, err)
+		}
+		for _, item := range queue.Cards {
+			if _, err := service.Review("alice", item.Card.ID, grade(day, item)); err != nil {
+				t.Fatalf("Day %d: %v", day, err)
+			}
+			reviews[day]++
+		}
+		c.now = c.now.AddDate(0, 0, 1)
+	}
+	return reviews
+}
+
+func TestService_SimulateDays(t *testing.T) {
+	t.Run("sm2", func(t *testing.T) {
+		service, deck, c := newTestService(t, SM2{}, 10)
+		reviews := studyDays(t, service, c, 100, func(int, models.ReviewItem) Grade { return Good })
+
+		// Cards are seen on days 0, 1, 7, 22 and 60
+		for day, count := range reviews {
+			want := 0
+			switch day {
+			case 0, 1, 7, 22, 60:
+				want = 10
+			}
+			if count != want {
+				t.Errorf("Day %d: expected %d reviews, got %d", day, want, count)
+			}
+		}
+
+		state, _ := service.states.Get("alice", deck.Set.Flashcards[0].ID)
+		if state.Reps != 5 || state.Interval != 95 || !state.Due.Equal(start.AddDate(0, 0, 155)) {
+			t.Errorf("Expected the fifth review to schedule 95 days out, got %+v", state)
+		}
+	})
+
+	t.Run("fsrs", func(t *testing.T) {
+		run := func() ([]int, map[string]*models.ReviewState) {
+			service, deck, c := newTestService(t, NewFSRS(), 10)
+			positions := make(map[string]int)
+			for i, card := range deck.Set.Flashcards {
+				positions[card.ID] = i
+			}
+
+			// Card i is forgotten on its first review after day 10 + 10i
+			forgotten := make(map[string]bool)
+			reviews := studyDays(t, service, c, 365, func(day int, item models.ReviewItem) Grade {
+				if item.State != nil && day >= 10+10*positions[item.Card.ID] && !forgotten[item.Card.ID] {
+					forgotten[item.Card.ID] = true
+					return Again
+				}
+				return Good
+			})
+			states, _ := service.states.List("alice")
+			return reviews, states
+		}
+		reviews, states := run()
+
+		total := 0
+		for _, count := range reviews {
+			total += count
+		}
+		// Every card is seen on days 0, 4 and 19 before the first lapse
+		if reviews[0] != 10 || reviews[4] != 10 || reviews[19] != 10 || total != 80 {
+			t.Errorf("Expected 80 reviews starting on days 0, 4 and 19, got %d: %v", total, reviews)
+		}
+		for cardID, state := range states {
+			if state.Lapses != 1 || state.Reps < 2 || !state.Due.After(start.AddDate(0, 0, 365)) {
+				t.Errorf("Card %s: expected one lapse and a schedule past the year, got %+v", cardID, state)
+			}
+		}
+
+		if again, _ := run(); !equalInts(again, reviews) {
+			t.Errorf("Expected a repeat run to review on the same days, got %v and %v", reviews, again)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
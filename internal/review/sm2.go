@@ -0,0 +1,61 @@
+package review
+
+import (
+	"math"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// SM-2 ease factors
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+)
+
+// sm2Quality maps grades onto SM-2's 0-5 response quality. Anything below
+// 3 is a failed recall.
+var sm2Quality = map[Grade]float64{
+	Again: 1,
+	Hard:  3,
+	Good:  4,
+	Easy:  5,
+}
+
+// SM2 is the SuperMemo 2 algorithm. Each successful review multiplies the
+// interval by the card's ease, which grows with easy recalls and shrinks
+// with hard ones. A failed review restarts the card at one day and leaves
+// its ease alone.
+type SM2 struct{}
+
+func (SM2) Name() string {
+	return SchedulerSM2
+}
+
+func (SM2) Review(state models.ReviewState, grade Grade, now time.Time) models.ReviewState {
+	if state.Ease == 0 {
+		state.Ease = defaultEase
+	}
+	q := sm2Quality[grade]
+
+	if q < 3 {
+		if state.Reps > 0 {
+			state.Lapses++
+		}
+		state.Reps = 0
+		return finish(state, SchedulerSM2, grade, 1, now)
+	}
+
+	state.Reps++
+	var interval int
+	switch state.Reps {
+	case 1:
+		interval = 1
+	case 2:
+		interval = 6
+	default:
+		interval = int(math.Round(float64(max(state.Interval, 1)) * state.Ease))
+	}
+	state.Ease = math.Max(minEase, state.Ease+0.1-(5-q)*(0.08+(5-q)*0.02))
+	return finish(state, SchedulerSM2, grade, interval, now)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+func TestFlashcardService_MarkAnswer_WithoutModel(t *testing.T) {
+	basic := models.Flashcard{Question: "Where does photosynthesis take place?", Answer: "In the chloroplasts"}
+	mcq := models.Flashcard{Type: models.CardTypeMCQ, Question: "Which gas is released?", Options: []string{"Carbon dioxide", "Oxygen", "Nitrogen"}, CorrectIndex: intPtr(1), Answer: "Oxygen"}
+	trueFalse := models.Flashcard{Type: models.CardTypeTrueFalse, Question: "Photosynthesis produces glucose.", IsTrue: boolPtr(true), Answer: "True", Explanation: "Glucose stores the captured energy."}
+
+	tests := []struct {
+		name        string
+		card        models.Flashcard
+		answer      string
+		wantScore   float64
+		wantMethod  string
+		wantMissing string
+	}{
+		{"Exact", basic, "In the chloroplasts", 1, models.MarkedExact, ""},
+		{"Case, punctuation and articles", basic, "  in chloroplasts!", 1, models.MarkedExact, ""},
+		{"Option letter", mcq, "B)", 1, models.MarkedChoice, ""},
+		{"Option number", mcq, "2", 1, models.MarkedChoice, ""},
+		{"Option text", mcq, "oxygen.", 1, models.MarkedChoice, ""},
+		{"Wrong option", mcq, "a", 0, models.MarkedChoice, "B) Oxygen"},
+		{"True", trueFalse, "Yes", 1, models.MarkedChoice, ""},
+		{"False", trueFalse, "false", 0, models.MarkedChoice, "Glucose stores the captured energy."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFakeProvider(FakeResponse{Err: errors.New("model should not be called")})
+			svc := NewFlashcardService(NewPDFService(), provider)
+
+			mark, err := svc.MarkAnswer(context.Background(), tt.card, tt.answer)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if mark.Score != tt.wantScore || mark.Method != tt.wantMethod {
+				t.Errorf("Expected score %v by %q, got %v by %q", tt.wantScore, tt.wantMethod, mark.Score, mark.Method)
+			}
+			if tt.wantMissing != "" && (len(mark.MissingPoints) != 1 || mark.MissingPoints[0] != tt.wantMissing) {
+				t.Errorf("Expected missing point %q, got %v", tt.wantMissing, mark.MissingPoints)
+			}
+			if mark.Feedback == "" || mark.MissingPoints == nil {
+				t.Errorf("Expected feedback and a missing points list, got %+v", mark)
+			}
+			if n := len(provider.Requests()); n != 0 {
+				t.Errorf("Expected no model calls, got %d", n)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_MarkAnswer_WithModel(t *testing.T) {
+	basic := models.Flashcard{Question: "What is photosynthesis?", Answer: "Plants turning light energy into chemical energy", Evidence: "Photosynthesis is the process..."}
+	dated := models.Flashcard{Question: "When did the war end?", Answer: "In 1945"}
+	trueFalse := models.Flashcard{Type: models.CardTypeTrueFalse, Question: "Photosynthesis produces glucose.", IsTrue: boolPtr(true), Answer: "True"}
+	hormones := models.Flashcard{Question: "How do the pancreatic hormones interact?", Answer: "Insulin inhibits glucagon"}
+	pressure := models.Flashcard{Question: "What do ACE inhibitors do?", Answer: "Decrease blood pressure"}
+	thyroid := models.Flashcard{Question: "Which condition does levothyroxine treat?", Answer: "Hypothyroidism"}
+	division := models.Flashcard{Question: "Which division gives two identical cells?", Answer: "Mitosis produces two identical daughter cells"}
+	wrong := FakeResponse{Text: `{"score": 0, "missingPoints": ["the correct answer"], "feedback": "Not quite."}`}
+
+	tests := []struct {
+		name        string
+		card        models.Flashcard
+		answer      string
+		reply       FakeResponse
+		wantScore   float64
+		wantMissing []string
+		wantErr     string
+	}{
+		{
+			name:        "Partial answer",
+			card:        basic,
+			answer:      "Plants making food from light",
+			reply:       FakeResponse{Text: `{"score": 0.6, "missingPoints": ["chemical energy", " "], "feedback": "Close. "}`},
+			wantScore:   0.6,
+			wantMissing: []string{"chemical energy"},
+		},
+		{
+			name:      "A typo in a number is left to the model",
+			card:      dated,
+			answer:    "In 1946",
+			reply:     FakeResponse{Text: "```json\n{\"score\": 0, \"missingPoints\": [\"1945\"], \"feedback\": \"Wrong year.\"}\n```"},
+			wantScore: 0, wantMissing: []string{"1945"},
+		},
+		{name: "Words swapped", card: hormones, answer: "Glucagon inhibits insulin", reply: wrong, wantScore: 0, wantMissing: []string{"the correct answer"}},
+		{name: "Opposite verb", card: pressure, answer: "Increases blood pressure", reply: wrong, wantScore: 0, wantMissing: []string{"the correct answer"}},
+		{name: "Opposite condition", card: thyroid, answer: "hyperthyroidism", reply: wrong, wantScore: 0, wantMissing: []string{"the correct answer"}},
+		{name: "Similar term", card: division, answer: "Meiosis produces two identical daughter cells", reply: wrong, wantScore: 0, wantMissing: []string{"the correct answer"}},
+		{
+			name:      "Score out of 100",
+			card:      basic,
+			answer:    "Light to energy",
+			reply:     FakeResponse{Text: `{"score": 75, "missingPoints": [], "feedback": "Good."}`},
+			wantScore: 0.75, wantMissing: []string{},
+		},
+		{
+			name:      "True/false answer in a sentence",
+			card:      trueFalse,
+			answer:    "It does, as sugar",
+			reply:     FakeResponse{Text: `{"score": 1, "missingPoints": [], "feedback": "Right."}`},
+			wantScore: 1, wantMissing: []string{},
+		},
+		{name: "Model error", card: basic, answer: "Light", reply: FakeResponse{Err: errors.New("quota exceeded")}, wantErr: "failed to mark answer"},
+		{name: "Not JSON", card: basic, answer: "Light", reply: FakeResponse{Text: "Score: 5/10"}, wantErr: "failed to mark answer"},
+		{name: "No score", card: basic, answer: "Light", reply: FakeResponse{Text: `{"feedback": "Hmm"}`}, wantErr: "failed to mark answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFakeProvider(tt.reply)
+			svc := NewFlashcardService(NewPDFService(), provider)
+
+			mark, err := svc.MarkAnswer(context.Background(), tt.card, tt.answer)
+			if tt.wantErr != "" {
+				var appErr *apperrors.AppError
+				if !errors.As(err, &appErr) || appErr.Code != models.InternalError || appErr.Message != tt.wantErr {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if mark.Score != tt.wantScore || mark.Method != models.MarkedModel {
+				t.Errorf("Expected score %v by the model, got %v by %q", tt.wantScore, mark.Score, mark.Method)
+			}
+			if strings.Join(mark.MissingPoints, "|") != strings.Join(tt.wantMissing, "|") || mark.MissingPoints == nil {
+				t.Errorf("Expected missing points %q, got %q", tt.wantMissing, mark.MissingPoints)
+			}
+
+			requests := provider.Requests()
+			if len(requests) != 1 || requests[0].Schema == nil {
+				t.Fatalf("Expected one structured request, got %+v", requests)
+			}
+			prompt := requests[0].Prompt
+			if !strings.Contains(prompt, "Expected answer: "+tt.card.Answer) || !strings.Contains(prompt, "-----\n"+tt.answer+"\n-----") {
+				t.Errorf("Expected the prompt to hold both answers, got %q", prompt)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_MarkAnswer_InvalidAnswer(t *testing.T) {
+	svc := NewFlashcardService(NewPDFService(), NewFakeProvider())
+	card := models.Flashcard{Question: "Q", Answer: "A"}
+
+	for _, answer := range []string{"", "   ", strings.Repeat("x", maxAnswerLength+1)} {
+		_, err := svc.MarkAnswer(context.Background(), card, answer)
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.Code != models.InvalidParams {
+			t.Errorf("Expected an invalid params error for a %d character answer, got %v", len(answer), err)
+		}
+	}
+}
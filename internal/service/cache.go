@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/cache"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// cacheVersion is part of every cache key. Bump it when a change to
+// extraction or generation should stop earlier results being served.
+const cacheVersion = "1"
+
+// extractCachedText extracts the text of a PDF, reusing the text cached
+// for the same bytes unless noCache is set.
+func (s *FlashcardService) extractCachedText(ctx context.Context, pdfData []byte, noCache bool, progress ProgressFunc) (string, error) {
+	key := cache.Key("text", cacheVersion, cache.Hash(pdfData))
+	if text, ok := s.cacheGet(key, noCache); ok {
+		progress.report(Progress{Stage: StageExtracting, Message: "Using cached text"})
+		return string(text), nil
+	}
+
+	text, err := s.extractText(ctx, pdfData, progress)
+	if err != nil {
+		return "", err
+	}
+	s.cacheSet(key, []byte(text))
+	return text, nil
+}
+
+// setKey is the cache key of a set generated with opts from texts, named
+// by sources. Texts are compared with their whitespace normalized, and the
+// key changes with the model and the chunk size, which both change the
+// cards generated.
+func (s *FlashcardService) setKey(sources, texts []string, opts models.GenerationOptions) string {
+	opts.NoCache = false
+	encoded, _ := json.Marshal(opts)
+
+	parts := []string{"set", cacheVersion, s.provider.Model(), strconv.Itoa(s.chunkTokens()), string(encoded)}
+	for i, text := range texts {
+		normalized := strings.Join(strings.Fields(text), " ")
+		parts = append(parts, sources[i], cache.Hash([]byte(normalized)))
+	}
+	return cache.Key(parts...)
+}
+
+// cachedSet returns the set cached under key, reporting its cards as
+// progress, or generates and caches a new one. With opts.NoCache a new set
+// is always generated, and replaces the cached one.
+func (s *FlashcardService) cachedSet(key string, opts models.GenerationOptions, progress ProgressFunc, generate func(models.GenerationOptions) (*models.FlashcardSet, error)) (*models.FlashcardSet, error) {
+	if value, ok := s.cacheGet(key, opts.NoCache); ok {
+		var set models.FlashcardSet
+		err := json.Unmarshal(value, &set)
+		if err == nil {
+			log.Printf("Serving %d cached flashcards generated at %s", set.TotalCards, set.CreatedAt)
+			set.Cached = true
+			progress.report(Progress{Stage: StageGenerating, Message: "Using cached flashcards"})
+			for _, card := range set.Flashcards {
+				progress.report(Progress{Stage: StageCard, Card: &card})
+			}
+			return &set, nil
+		}
+		log.Printf("Ignoring unreadable cached flashcards: %v", err)
+	}
+
+	opts.NoCache = false
+	set, err := generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Stored before the set is returned, so a deck ID given to it later
+	// isn't cached
+	if value, err := json.Marshal(set); err == nil {
+		s.cacheSet(key, value)
+	}
+	return set, nil
+}
+
+func (s *FlashcardService) cacheGet(key string, noCache bool) ([]byte, bool) {
+	if s.Cache == nil || noCache {
+		return nil, false
+	}
+	return s.Cache.Get(key)
+}
+
+func (s *FlashcardService) cacheSet(key string, value []byte) {
+	if s.Cache != nil {
+		s.Cache.Set(key, value)
+	}
+}
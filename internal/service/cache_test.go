@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/cache"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+const lectureText = "Photosynthesis converts light energy into chemical energy in the chloroplasts."
+
+func TestFlashcardService_GenerateFromText_Cached(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck})
+	service := NewFlashcardService(NewPDFService(), provider)
+	service.Cache = cache.NewLRU(10, time.Hour)
+	ctx := context.Background()
+
+	first, err := service.GenerateFromText(ctx, lectureText, models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if first.Cached {
+		t.Error("Expected the first set to be generated")
+	}
+	first.DeckID = "deck-1"
+	requests := len(provider.Requests())
+
+	// Whitespace doesn't change the key
+	var progress []Progress
+	second, err := service.GenerateFromText(ctx, "  "+strings.ReplaceAll(lectureText, " ", "\n"), models.GenerationOptions{}, func(p Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !second.Cached {
+		t.Error("Expected the second set to come from the cache")
+	}
+	if got := len(provider.Requests()); got != requests {
+		t.Errorf("Expected no more model requests, got %d after %d", got, requests)
+	}
+	if second.TotalCards != first.TotalCards || second.CreatedAt != first.CreatedAt {
+		t.Errorf("Expected the cached set, got %+v", second)
+	}
+	if second.DeckID != "" {
+		t.Errorf("Expected no deck ID on the cached set, got %q", second.DeckID)
+	}
+
+	cards := 0
+	for _, p := range progress {
+		if p.Stage == StageCard {
+			cards++
+		}
+	}
+	if cards != second.TotalCards {
+		t.Errorf("Expected %d card events, got %d", second.TotalCards, cards)
+	}
+
+	if !strings.Contains(service.FormatAsText(second), "Served from the cache") {
+		t.Error("Expected the text to say the set came from the cache")
+	}
+}
+
+func TestFlashcardService_GenerateFromText_CacheKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		cached models.GenerationOptions
+		text   string
+		opts   models.GenerationOptions
+		model  string
+		hit    bool
+	}{
+		{"Same request", models.GenerationOptions{}, lectureText, models.GenerationOptions{}, "", true},
+		{"Normalized options", models.GenerationOptions{Difficulty: "hard"}, lectureText, models.GenerationOptions{Difficulty: " Hard "}, "", true},
+		{"Different text", models.GenerationOptions{}, lectureText + " Oxygen is released.", models.GenerationOptions{}, "", false},
+		{"Different options", models.GenerationOptions{}, lectureText, models.GenerationOptions{CardCount: 5}, "", false},
+		{"Different model", models.GenerationOptions{}, lectureText, models.GenerationOptions{}, "other-model", false},
+		{"No cache", models.GenerationOptions{}, lectureText, models.GenerationOptions{NoCache: true}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFakeProvider(FakeResponse{Text: testDeck})
+			service := NewFlashcardService(NewPDFService(), provider)
+			service.Cache = cache.NewLRU(10, time.Hour)
+			ctx := context.Background()
+
+			if _, err := service.GenerateFromText(ctx, lectureText, tt.cached, nil); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if tt.model != "" {
+				service.provider = &namedProvider{FakeProvider: provider, model: tt.model}
+			}
+			set, err := service.GenerateFromText(ctx, tt.text, tt.opts, nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if set.Cached != tt.hit {
+				t.Errorf("Expected cached %v, got %v", tt.hit, set.Cached)
+			}
+			if set.Options != nil && set.Options.NoCache {
+				t.Error("Expected noCache not to be recorded on the set")
+			}
+		})
+	}
+}
+
+func TestFlashcardService_GenerateFromText_NoCacheRefreshes(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck}, FakeResponse{Text: "Q: What is ATP?\nA: Energy currency\nT: Biology"})
+	service := NewFlashcardService(NewPDFService(), provider)
+	service.Cache = cache.NewLRU(10, time.Hour)
+	ctx := context.Background()
+
+	service.GenerateFromText(ctx, lectureText, models.GenerationOptions{}, nil)
+	fresh, err := service.GenerateFromText(ctx, lectureText, models.GenerationOptions{NoCache: true}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fresh.Cached || fresh.TotalCards != 1 {
+		t.Errorf("Expected a freshly generated set of 1 card, got %d cards (cached %v)", fresh.TotalCards, fresh.Cached)
+	}
+
+	// The fresh set replaces the cached one
+	cached, _ := service.GenerateFromText(ctx, lectureText, models.GenerationOptions{}, nil)
+	if !cached.Cached || cached.Flashcards[0].Question != "What is ATP?" {
+		t.Errorf("Expected the refreshed set from the cache, got %+v", cached)
+	}
+}
+
+func TestFlashcardService_GenerateFromPDFData_CachedText(t *testing.T) {
+	// Not a PDF the extractor can read, so only cached text can be used
+	pdfData := []byte("%PDF-1.4\nrest of pdf content")
+	service := newTestFlashcardService(t, NewPDFService())
+	service.Cache = cache.NewLRU(10, time.Hour)
+	service.Cache.Set(cache.Key("text", cacheVersion, cache.Hash(pdfData)), []byte(lectureText))
+
+	set, err := service.GenerateFromPDFData(context.Background(), pdfData, models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected the cached text to be used, got: %v", err)
+	}
+	if set.TotalCards == 0 {
+		t.Error("Expected flashcards")
+	}
+
+	_, err = service.GenerateFromPDFData(context.Background(), pdfData, models.GenerationOptions{NoCache: true}, nil)
+	if err == nil {
+		t.Error("Expected noCache to extract the text again")
+	}
+}
+
+// namedProvider reports a different model name for the same replies.
+type namedProvider struct {
+	*FakeProvider
+	model string
+}
+
+func (p *namedProvider) Model() string {
+	return p.model
+}
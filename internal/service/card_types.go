@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// clozePattern matches a cloze deletion, {{c1::answer}} or
+// {{c1::answer::hint}}.
+var clozePattern = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// cardTypeFormats shows the model the JSON of each card type.
+var cardTypeFormats = map[string]string{
+	models.CardTypeBasic:     `{"type": "basic", "question": "What do mitochondria produce?", "answer": "ATP", "topic": "Cells"}`,
+	models.CardTypeReverse:   `{"type": "reverse", "question": "Mitochondrion", "answer": "The organelle that produces ATP", "topic": "Cells"} (question is a term, answer its definition; studied both ways)`,
+	models.CardTypeCloze:     `{"type": "cloze", "cloze": "{{c1::Mitochondria}} produce {{c2::ATP}}.", "question": "[...] produce [...].", "answer": "Mitochondria; ATP", "topic": "Cells"}`,
+	models.CardTypeMCQ:       `{"type": "mcq", "question": "What do mitochondria produce?", "options": ["ATP", "DNA", "Glucose", "Oxygen"], "correctIndex": 0, "rationales": ["Correct: ...", "Why DNA is wrong", "Why glucose is wrong", "Why oxygen is wrong"], "answer": "ATP", "topic": "Cells"}`,
+	models.CardTypeTrueFalse: `{"type": "true_false", "question": "Mitochondria produce DNA.", "isTrue": false, "explanation": "They produce ATP; DNA is replicated in the nucleus.", "answer": "False", "topic": "Cells"}`,
+}
+
+// cardTypesPrompt asks for a mix of types and shows the JSON of each. It
+// returns "" for basic cards only, which the default format covers.
+func cardTypesPrompt(types []string) string {
+	if len(types) == 0 || (len(types) == 1 && types[0] == models.CardTypeBasic) {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Use a mix of these card types, setting \"type\" on each flashcard: %s\n", strings.Join(types, ", ")))
+	for _, cardType := range types {
+		builder.WriteString(fmt.Sprintf("- %s\n", cardTypeFormats[cardType]))
+	}
+	return builder.String()
+}
+
+// isCardType reports whether cardType is one of models.CardTypes.
+func isCardType(cardType string) bool {
+	for _, known := range models.CardTypes {
+		if cardType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCardType checks the fields specific to the card's type and
+// fills in the Question and Answer of cloze, multiple choice and
+// true/false cards from them, so every card reads as a question and answer.
+func normalizeCardType(card *models.Flashcard) error {
+	card.Type = strings.ToLower(strings.TrimSpace(card.Type))
+	if card.Type != "" && !isCardType(card.Type) {
+		return fmt.Errorf("unknown card type %q", card.Type)
+	}
+
+	switch card.Type {
+	case models.CardTypeCloze:
+		return normalizeCloze(card)
+	case models.CardTypeMCQ:
+		return normalizeMCQ(card)
+	case models.CardTypeTrueFalse:
+		return normalizeTrueFalse(card)
+	}
+	return nil
+}
+
+func normalizeCloze(card *models.Flashcard) error {
+	card.Cloze = strings.TrimSpace(card.Cloze)
+	if card.Cloze == "" && clozePattern.MatchString(card.Question) {
+		card.Cloze = card.Question
+	}
+
+	deletions := clozePattern.FindAllStringSubmatch(card.Cloze, -1)
+	if len(deletions) == 0 {
+		return errors.New("cloze card has no {{c1::...}} deletion")
+	}
+
+	answers := make([]string, len(deletions))
+	for i, deletion := range deletions {
+		answers[i] = strings.TrimSpace(deletion[2])
+		if answers[i] == "" {
+			return errors.New("cloze card has an empty deletion")
+		}
+	}
+	card.Question = maskCloze(card.Cloze)
+	card.Answer = strings.Join(answers, "; ")
+	return nil
+}
+
+// maskCloze replaces each deletion with its hint in brackets, or [...].
+func maskCloze(text string) string {
+	return clozePattern.ReplaceAllStringFunc(text, func(deletion string) string {
+		if hint := clozePattern.FindStringSubmatch(deletion)[3]; hint != "" {
+			return "[" + hint + "]"
+		}
+		return "[...]"
+	})
+}
+
+func normalizeMCQ(card *models.Flashcard) error {
+	var options []string
+	for _, option := range card.Options {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	if len(options) != len(card.Options) || len(options) < 2 {
+		return errors.New("multiple choice card needs at least two non-empty options")
+	}
+	card.Options = options
+
+	if card.CorrectIndex == nil || *card.CorrectIndex < 0 || *card.CorrectIndex >= len(options) {
+		return errors.New("multiple choice card has no valid correctIndex")
+	}
+	if len(card.Rationales) > 0 && len(card.Rationales) != len(options) {
+		return fmt.Errorf("multiple choice card has %d rationales for %d options", len(card.Rationales), len(options))
+	}
+
+	card.Answer = options[*card.CorrectIndex]
+	return nil
+}
+
+func normalizeTrueFalse(card *models.Flashcard) error {
+	card.Explanation = strings.TrimSpace(card.Explanation)
+	if card.IsTrue == nil {
+		// Accept the verdict in the answer when isTrue is missing
+		switch strings.ToLower(strings.TrimRight(strings.TrimSpace(card.Answer), ".")) {
+		case "true":
+			card.IsTrue = boolPtr(true)
+		case "false":
+			card.IsTrue = boolPtr(false)
+		default:
+			return errors.New("true/false card has no isTrue")
+		}
+	}
+
+	card.Answer = "False"
+	if *card.IsTrue {
+		card.Answer = "True"
+	}
+	return nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// optionLabel is the letter shown before a multiple choice option.
+func optionLabel(index int) string {
+	return string(rune('A' + index))
+}
+
+// formatCardBody renders the question and answer lines of a card in
+// Markdown, laid out for its type.
+func formatCardBody(card models.Flashcard) string {
+	var builder strings.Builder
+
+	switch card.Type {
+	case models.CardTypeMCQ:
+		builder.WriteString(fmt.Sprintf("Q: %s\n", card.Question))
+		for i, option := range card.Options {
+			builder.WriteString(fmt.Sprintf("- %s) %s\n", optionLabel(i), option))
+		}
+		builder.WriteString(fmt.Sprintf("A: %s) %s\n", optionLabel(*card.CorrectIndex), card.Answer))
+		for i, rationale := range card.Rationales {
+			if rationale != "" {
+				builder.WriteString(fmt.Sprintf("Why %s: %s\n", optionLabel(i), rationale))
+			}
+		}
+	case models.CardTypeTrueFalse:
+		builder.WriteString(fmt.Sprintf("Q: True or false: %s\n", card.Question))
+		builder.WriteString(fmt.Sprintf("A: %s\n", card.Answer))
+		if card.Explanation != "" {
+			builder.WriteString(fmt.Sprintf("Explanation: %s\n", card.Explanation))
+		}
+	case models.CardTypeReverse:
+		builder.WriteString(fmt.Sprintf("Q: %s\n", card.Question))
+		builder.WriteString(fmt.Sprintf("A: %s\n", card.Answer))
+		builder.WriteString(fmt.Sprintf("Reverse: %s → %s\n", card.Answer, card.Question))
+	default:
+		builder.WriteString(fmt.Sprintf("Q: %s\n", card.Question))
+		builder.WriteString(fmt.Sprintf("A: %s\n", card.Answer))
+	}
+	return builder.String()
+}
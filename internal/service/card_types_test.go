@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNormalizeCardType(t *testing.T) {
+	tests := []struct {
+		name         string
+		card         models.Flashcard
+		wantQuestion string
+		wantAnswer   string
+		wantErr      string
+	}{
+		{
+			name:         "Basic",
+			card:         models.Flashcard{Question: "What is ATP?", Answer: "Energy currency"},
+			wantQuestion: "What is ATP?",
+			wantAnswer:   "Energy currency",
+		},
+		{
+			name:         "Cloze",
+			card:         models.Flashcard{Type: "Cloze", Cloze: "{{c1::Mitochondria}} produce {{c2::ATP::molecule}}."},
+			wantQuestion: "[...] produce [molecule].",
+			wantAnswer:   "Mitochondria; ATP",
+		},
+		{
+			name:         "Cloze in question",
+			card:         models.Flashcard{Type: "cloze", Question: "DNA is stored in the {{c1::nucleus}}."},
+			wantQuestion: "DNA is stored in the [...].",
+			wantAnswer:   "nucleus",
+		},
+		{
+			name:    "Cloze without deletion",
+			card:    models.Flashcard{Type: "cloze", Cloze: "Mitochondria produce ATP."},
+			wantErr: "no {{c1::...}} deletion",
+		},
+		{
+			name:         "Multiple choice",
+			card:         models.Flashcard{Type: "mcq", Question: "What do mitochondria produce?", Options: []string{"DNA", "ATP"}, CorrectIndex: intPtr(1)},
+			wantQuestion: "What do mitochondria produce?",
+			wantAnswer:   "ATP",
+		},
+		{
+			name:    "Multiple choice index out of range",
+			card:    models.Flashcard{Type: "mcq", Question: "Q?", Options: []string{"DNA", "ATP"}, CorrectIndex: intPtr(2)},
+			wantErr: "correctIndex",
+		},
+		{
+			name:    "Multiple choice missing index",
+			card:    models.Flashcard{Type: "mcq", Question: "Q?", Options: []string{"DNA", "ATP"}},
+			wantErr: "correctIndex",
+		},
+		{
+			name:    "Multiple choice rationale count",
+			card:    models.Flashcard{Type: "mcq", Question: "Q?", Options: []string{"DNA", "ATP"}, CorrectIndex: intPtr(0), Rationales: []string{"Only one"}},
+			wantErr: "1 rationales for 2 options",
+		},
+		{
+			name:         "True/false",
+			card:         models.Flashcard{Type: "true_false", Question: "The sun is a star.", IsTrue: boolPtr(true)},
+			wantQuestion: "The sun is a star.",
+			wantAnswer:   "True",
+		},
+		{
+			name:         "True/false from answer",
+			card:         models.Flashcard{Type: "true_false", Question: "The moon is a star.", Answer: "false."},
+			wantQuestion: "The moon is a star.",
+			wantAnswer:   "False",
+		},
+		{
+			name:    "True/false without verdict",
+			card:    models.Flashcard{Type: "true_false", Question: "The moon is a star."},
+			wantErr: "isTrue",
+		},
+		{
+			name:    "Unknown type",
+			card:    models.Flashcard{Type: "essay", Question: "Q?", Answer: "A"},
+			wantErr: `unknown card type "essay"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := tt.card
+			err := validateFlashcard(&card)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if card.Question != tt.wantQuestion {
+				t.Errorf("Expected question %q, got %q", tt.wantQuestion, card.Question)
+			}
+			if card.Answer != tt.wantAnswer {
+				t.Errorf("Expected answer %q, got %q", tt.wantAnswer, card.Answer)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_FormatCard_Types(t *testing.T) {
+	service := NewFlashcardService(NewPDFService(), NewFakeProvider(FakeResponse{Text: testDeck}))
+
+	tests := []struct {
+		name string
+		card models.Flashcard
+		want string
+	}{
+		{
+			name: "Multiple choice",
+			card: models.Flashcard{
+				Type: models.CardTypeMCQ, Question: "What do mitochondria produce?", Answer: "ATP", Topic: "Cells",
+				Options: []string{"DNA", "ATP"}, CorrectIndex: intPtr(1), Rationales: []string{"DNA is stored, not produced", ""},
+			},
+			want: "**Card 1** (Cells) [mcq]\nQ: What do mitochondria produce?\n- A) DNA\n- B) ATP\nA: B) ATP\nWhy A: DNA is stored, not produced\n",
+		},
+		{
+			name: "True/false",
+			card: models.Flashcard{
+				Type: models.CardTypeTrueFalse, Question: "The moon is a star.", Answer: "False", Topic: "Space",
+				IsTrue: boolPtr(false), Explanation: "It reflects sunlight.",
+			},
+			want: "**Card 1** (Space) [true_false]\nQ: True or false: The moon is a star.\nA: False\nExplanation: It reflects sunlight.\n",
+		},
+		{
+			name: "Cloze",
+			card: models.Flashcard{
+				Type: models.CardTypeCloze, Question: "[...] produce ATP.", Answer: "Mitochondria", Topic: "Cells",
+				Cloze: "{{c1::Mitochondria}} produce ATP.",
+			},
+			want: "**Card 1** (Cells) [cloze]\nQ: [...] produce ATP.\nA: Mitochondria\n",
+		},
+		{
+			name: "Reverse",
+			card: models.Flashcard{Type: models.CardTypeReverse, Question: "Osmosis", Answer: "Diffusion of water", Topic: "Cells"},
+			want: "**Card 1** (Cells) [reverse]\nQ: Osmosis\nA: Diffusion of water\nReverse: Diffusion of water → Osmosis\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.FormatCard(1, tt.card); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_GenerateFromText_CardTypes(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: `{"flashcards": [
+  {"type": "cloze", "cloze": "{{c1::Mitochondria}} produce ATP.", "question": "", "answer": "", "topic": "Cells"},
+  {"type": "mcq", "question": "What do mitochondria produce?", "options": ["DNA", "ATP"], "correctIndex": 1, "answer": "", "topic": "Cells"}
+]}`})
+	service := NewFlashcardService(NewPDFService(), provider)
+	opts := models.GenerationOptions{CardTypes: []string{"Cloze", "mcq", "true_false"}}
+
+	set, err := service.GenerateFromText(context.Background(), "Cell biology notes", opts, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if set.Flashcards[0].Answer != "Mitochondria" || set.Flashcards[1].Answer != "ATP" {
+		t.Errorf("Expected answers derived from the card types, got %+v", set.Flashcards)
+	}
+	if strings.Join(set.UnmetConstraints, "|") != `no cards of type "true_false"` {
+		t.Errorf("Expected the missing true/false cards to be reported, got %v", set.UnmetConstraints)
+	}
+
+	prompt := provider.Requests()[0].Prompt
+	for _, want := range []string{"Use a mix of these card types", `"type": "cloze"`, `"type": "true_false"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, `"type": "reverse"`) {
+		t.Error("Expected only the requested card types in the prompt")
+	}
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// Defaults for FlashcardService.ChunkTokens and Concurrency
+const (
+	DefaultChunkTokens = 8000
+	DefaultConcurrency = 4
+)
+
+const (
+	// charsPerToken is a rough estimate that holds for English prose
+	// across the models we support.
+	charsPerToken = 4
+
+	// tokensPerCard sets how fast the deck grows with document length.
+	tokensPerCard = 400
+
+	minDeckSize = 5
+	maxDeckSize = 150
+
+	// duplicateSimilarity is the share of question words two cards need in
+	// common to count as the same card.
+	duplicateSimilarity = 0.8
+)
+
+// chunkSeparators are the boundaries text is split on, most preferred
+// first: pages, sections (blank lines), lines, then words.
+var chunkSeparators = []string{PageBreak, "\n\n", "\n", " "}
+
+// textChunk is one piece of a document generated from on its own.
+type textChunk struct {
+	Source string
+	Text   string
+	Tokens int
+}
+
+// estimateTokens approximates how many model tokens text takes.
+func estimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// deckSize is the number of cards to aim for from a document of tokens
+// tokens. The deck may hold up to twice as many.
+func deckSize(tokens int) int {
+	size := tokens / tokensPerCard
+	if size < minDeckSize {
+		return minDeckSize
+	}
+	if size > maxDeckSize {
+		return maxDeckSize
+	}
+	return size
+}
+
+// cardRange renders a target card count as the range used in prompts,
+// e.g. "5-10".
+func cardRange(target int) string {
+	return fmt.Sprintf("%d-%d", target, 2*target)
+}
+
+// chunkText splits text into chunks of at most maxTokens tokens. Chunks end
+// on a page break where possible, then on a section, line or word
+// boundary. Text that fits is returned as a single chunk.
+func chunkText(text string, maxTokens int) []string {
+	maxChars := maxTokens * charsPerToken
+	if maxChars <= 0 || len(text) <= maxChars {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range splitPieces(text, maxChars, 0) {
+		if current.Len() > 0 && current.Len()+len(piece) > maxChars {
+			chunks = appendChunk(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(piece)
+	}
+	return appendChunk(chunks, current.String())
+}
+
+// splitPieces splits text on chunkSeparators[level], splitting any piece
+// still too long on the next separator. Separators stay attached so the
+// pieces join back into text.
+func splitPieces(text string, maxChars, level int) []string {
+	if len(text) <= maxChars {
+		return []string{text}
+	}
+	if level == len(chunkSeparators) {
+		return splitRunes(text, maxChars)
+	}
+
+	var pieces []string
+	for _, part := range strings.SplitAfter(text, chunkSeparators[level]) {
+		if part == "" {
+			continue
+		}
+		pieces = append(pieces, splitPieces(part, maxChars, level+1)...)
+	}
+	return pieces
+}
+
+// splitRunes cuts text with no usable boundary into maxChars pieces
+// without breaking a UTF-8 sequence.
+func splitRunes(text string, maxChars int) []string {
+	var pieces []string
+	for len(text) > maxChars {
+		cut := maxChars
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = maxChars
+		}
+		pieces = append(pieces, text[:cut])
+		text = text[cut:]
+	}
+	return append(pieces, text)
+}
+
+func appendChunk(chunks []string, chunk string) []string {
+	if strings.TrimSpace(chunk) == "" {
+		return chunks
+	}
+	return append(chunks, strings.TrimSpace(chunk))
+}
+
+// chunkDocuments splits each document into chunks of at most s.ChunkTokens
+// tokens, keeping the document name on each chunk.
+func (s *FlashcardService) chunkDocuments(sources, texts []string) []textChunk {
+	var chunks []textChunk
+	for i, text := range texts {
+		for _, chunk := range chunkText(text, s.chunkTokens()) {
+			chunks = append(chunks, textChunk{Source: sources[i], Text: chunk, Tokens: estimateTokens(chunk)})
+		}
+	}
+	return chunks
+}
+
+func (s *FlashcardService) chunkTokens() int {
+	if s.ChunkTokens > 0 {
+		return s.ChunkTokens
+	}
+	return DefaultChunkTokens
+}
+
+func (s *FlashcardService) concurrency() int {
+	if s.Concurrency > 0 {
+		return s.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// generateFromChunks generates cards for each chunk, at most s.Concurrency
+// at a time, then merges them into one deck sized for the whole document.
+// Chunks that fail are skipped unless every chunk fails or ctx is done.
+func (s *FlashcardService) generateFromChunks(ctx context.Context, chunks []textChunk, opts models.GenerationOptions, progress ProgressFunc) ([]models.Flashcard, error) {
+	totalTokens := 0
+	for _, chunk := range chunks {
+		totalTokens += chunk.Tokens
+	}
+	target := deckSize(totalTokens)
+	limit := 2 * target
+	if opts.CardCount > 0 {
+		target, limit = opts.CardCount, opts.CardCount
+	}
+	log.Printf("Generating flashcards from %d chunks (about %d tokens, target %d cards)", len(chunks), totalTokens, target)
+
+	progress.report(Progress{Stage: StageGenerating, Message: fmt.Sprintf("Generating flashcards from %d parts", len(chunks))})
+
+	results := make([][]models.Flashcard, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	finished := 0
+	sem := make(chan struct{}, s.concurrency())
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk textChunk) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			share := int(math.Ceil(float64(target) * float64(chunk.Tokens) / float64(totalTokens)))
+			prompt := chunkPrompt(chunk, i+1, len(chunks), share, opts)
+			cards, err := s.runPrompt(ctx, prompt, nil)
+			if err != nil {
+				log.Printf("Chunk %d/%d failed: %v", i+1, len(chunks), err)
+				errs[i] = err
+			}
+			// Excluded cards are dropped before merging so they don't take
+			// up the chunk's share of the deck
+			for _, card := range cards {
+				if mentionsAny(card, opts.ExcludeTopics) {
+					continue
+				}
+				if chunk.Source != "" {
+					card.Source = chunk.Source
+				}
+				results[i] = append(results[i], card)
+			}
+
+			mu.Lock()
+			finished++
+			progress.report(Progress{Stage: StageGenerating, Message: fmt.Sprintf("Generated flashcards for part %d/%d", finished, len(chunks))})
+			mu.Unlock()
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, firstError(errs, ctx.Err())
+	}
+
+	weights := make([]int, len(chunks))
+	for i, chunk := range chunks {
+		weights[i] = chunk.Tokens
+	}
+	flashcards := mergeFlashcards(results, weights, limit)
+	if len(flashcards) == 0 {
+		return nil, firstError(errs, nil)
+	}
+
+	for i := range flashcards {
+		card := flashcards[i]
+		progress.report(Progress{Stage: StageCard, Card: &card})
+	}
+	return flashcards, nil
+}
+
+// chunkPrompt asks for share to 2*share cards from one chunk.
+func chunkPrompt(chunk textChunk, part, parts, share int, opts models.GenerationOptions) string {
+	document := "a longer document"
+	if chunk.Source != "" {
+		document = fmt.Sprintf("the document %q", chunk.Source)
+	}
+
+	return fmt.Sprintf(`Create a set of %s high-quality flashcards from the following text, which is
+part %d of %d of %s. Only cover what this part says; the other parts are
+handled separately.
+Each flashcard should:
+- Have a clear, specific question
+- Include a concise but comprehensive answer
+- Be categorized with an appropriate topic
+- Cover key concepts, definitions, and applications
+
+%sText to process:
+%s
+
+%s`, cardRange(share), part, parts, document, optionsPrompt(opts), chunk.Text, flashcardJSONFormat)
+}
+
+// firstError returns the first non-nil error in errs, or fallback.
+func firstError(errs []error, fallback error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return fallback
+}
+
+// mergeFlashcards combines the cards generated for each chunk. Duplicate
+// questions are dropped, keeping the earliest. If more than limit cards
+// remain, each chunk keeps a share in proportion to its weight so the deck
+// covers the whole document. Cards stay in document order.
+func mergeFlashcards(results [][]models.Flashcard, weights []int, limit int) []models.Flashcard {
+	var kept []questionWords
+	unique := make([][]models.Flashcard, len(results))
+	total := 0
+	for i, cards := range results {
+		for _, card := range cards {
+			words := newQuestionWords(card.Question)
+			if isDuplicate(words, kept) {
+				continue
+			}
+			kept = append(kept, words)
+			unique[i] = append(unique[i], card)
+			total++
+		}
+	}
+
+	counts := make([]int, len(unique))
+	if total <= limit {
+		for i := range unique {
+			counts[i] = len(unique[i])
+		}
+	} else {
+		// Repeatedly give the next slot to the chunk furthest below its
+		// share, lowest index first on ties
+		for picked := 0; picked < limit; picked++ {
+			best := -1
+			for i := range unique {
+				if counts[i] == len(unique[i]) {
+					continue
+				}
+				if best < 0 || float64(counts[i])*float64(weight(weights, best)) < float64(counts[best])*float64(weight(weights, i)) {
+					best = i
+				}
+			}
+			counts[best]++
+		}
+	}
+
+	var merged []models.Flashcard
+	for i := range unique {
+		merged = append(merged, unique[i][:counts[i]]...)
+	}
+	return merged
+}
+
+func weight(weights []int, i int) int {
+	if i < len(weights) && weights[i] > 0 {
+		return weights[i]
+	}
+	return 1
+}
+
+// questionWords is the normalized set of words in a question.
+type questionWords map[string]bool
+
+func newQuestionWords(question string) questionWords {
+	words := make(questionWords)
+	for _, word := range strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// isDuplicate reports whether words is close enough to an earlier
+// question, by Jaccard similarity, to be the same card.
+func isDuplicate(words questionWords, kept []questionWords) bool {
+	for _, other := range kept {
+		if similarity(words, other) >= duplicateSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+func similarity(a, b questionWords) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
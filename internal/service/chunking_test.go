@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestChunkText(t *testing.T) {
+	page := strings.Repeat("word ", 50) // 250 chars, about 63 tokens
+
+	tests := []struct {
+		name       string
+		text       string
+		maxTokens  int
+		wantChunks int
+	}{
+		{"Fits in one chunk", "short text", 100, 1},
+		{"Whole pages per chunk", strings.Join([]string{page, page, page, page}, PageBreak), 130, 2},
+		{"Page split on sections", page + "\n\n" + page, 70, 2},
+		{"No boundaries", strings.Repeat("x", 1000), 50, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkText(tt.text, tt.maxTokens)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("Expected %d chunks, got %d", tt.wantChunks, len(chunks))
+			}
+			for i, chunk := range chunks {
+				if estimateTokens(chunk) > tt.maxTokens {
+					t.Errorf("Chunk %d has %d tokens, over the limit of %d", i, estimateTokens(chunk), tt.maxTokens)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkText_PreferPageBoundaries(t *testing.T) {
+	pages := []string{"Page one.\n\nIntro", "Page two.\n\nMore", "Page three.\n\nEnd"}
+	chunks := chunkText(strings.Join(pages, PageBreak), 9) // 36 chars: two pages
+
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d: %q", len(chunks), chunks)
+	}
+	if chunks[0] != pages[0]+PageBreak+pages[1] {
+		t.Errorf("Expected the first chunk to end on a page break, got %q", chunks[0])
+	}
+	if chunks[1] != pages[2] {
+		t.Errorf("Expected the last page on its own, got %q", chunks[1])
+	}
+}
+
+func TestDeckSize(t *testing.T) {
+	tests := []struct {
+		tokens int
+		want   int
+	}{
+		{100, minDeckSize},
+		{8000, 20},
+		{150000, 150},
+		{1000000, maxDeckSize},
+	}
+
+	for _, tt := range tests {
+		if got := deckSize(tt.tokens); got != tt.want {
+			t.Errorf("deckSize(%d): expected %d, got %d", tt.tokens, tt.want, got)
+		}
+	}
+}
+
+func card(question string) models.Flashcard {
+	return models.Flashcard{Question: question, Answer: "answer", Topic: "Topic"}
+}
+
+func questions(cards []models.Flashcard) []string {
+	var out []string
+	for _, card := range cards {
+		out = append(out, card.Question)
+	}
+	return out
+}
+
+func TestMergeFlashcards_Dedupes(t *testing.T) {
+	results := [][]models.Flashcard{
+		{card("What is photosynthesis?"), card("Where does photosynthesis occur?")},
+		{card("what is Photosynthesis"), card("What is ATP?")},
+	}
+
+	got := questions(mergeFlashcards(results, []int{1, 1}, 10))
+	want := []string{"What is photosynthesis?", "Where does photosynthesis occur?", "What is ATP?"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestMergeFlashcards_BalancesCoverage(t *testing.T) {
+	var first, second []models.Flashcard
+	for i := 1; i <= 10; i++ {
+		first = append(first, card(fmt.Sprintf("Chapter one question %d", i)))
+		second = append(second, card(fmt.Sprintf("Chapter two item %d", i)))
+	}
+
+	// The second chunk is three times as long, so it gets three times the cards
+	merged := mergeFlashcards([][]models.Flashcard{first, second}, []int{100, 300}, 8)
+	got := questions(merged)
+	if len(got) != 8 {
+		t.Fatalf("Expected 8 cards, got %d", len(got))
+	}
+	fromFirst := 0
+	for _, q := range got {
+		if strings.HasPrefix(q, "Chapter one") {
+			fromFirst++
+		}
+	}
+	if fromFirst != 2 {
+		t.Errorf("Expected 2 cards from the shorter chunk, got %d: %q", fromFirst, got)
+	}
+	if got[0] != "Chapter one question 1" || got[2] != "Chapter two item 1" {
+		t.Errorf("Expected cards in document order, got %q", got)
+	}
+}
+
+// chunkProvider answers each chunk prompt with cards naming its part and
+// records how many requests ran at once.
+type chunkProvider struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	release     chan struct{}
+}
+
+var partPattern = regexp.MustCompile(`part (\d+) of (\d+)`)
+
+func (p *chunkProvider) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+
+	<-p.release
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+
+	part := partPattern.FindStringSubmatch(req.Prompt)[1]
+	return fmt.Sprintf(`{"flashcards": [
+  {"question": "What does part %[1]s cover?", "answer": "Part %[1]s", "topic": "Overview"},
+  {"question": "What is the main idea?", "answer": "Repeated in every part", "topic": "Overview"}
+]}`, part), nil
+}
+
+func (p *chunkProvider) GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error {
+	text, err := p.Generate(ctx, req)
+	if err == nil {
+		onChunk(text)
+	}
+	return err
+}
+
+func (p *chunkProvider) Model() string {
+	return "fake/chunks"
+}
+
+func TestFlashcardService_GenerateFromText_Chunked(t *testing.T) {
+	provider := &chunkProvider{release: make(chan struct{})}
+	service := NewFlashcardService(NewPDFService(), provider)
+	service.ChunkTokens = 30
+	service.Concurrency = 2
+
+	pages := make([]string, 5)
+	for i := range pages {
+		pages[i] = fmt.Sprintf("Page %d. %s", i+1, strings.Repeat("text ", 20))
+	}
+
+	var mu sync.Mutex
+	var streamed int
+	progress := func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Stage == StageCard {
+			streamed++
+		}
+	}
+
+	go func() {
+		for i := 0; i < len(pages); i++ {
+			provider.release <- struct{}{}
+		}
+	}()
+	set, err := service.GenerateFromText(context.Background(), strings.Join(pages, PageBreak), models.GenerationOptions{}, progress)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if provider.maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", provider.maxInFlight)
+	}
+	// One card per part plus the shared card, kept once
+	if set.TotalCards != len(pages)+1 {
+		t.Errorf("Expected %d cards, got %d: %q", len(pages)+1, set.TotalCards, questions(set.Flashcards))
+	}
+	if set.Flashcards[0].Question != "What does part 1 cover?" {
+		t.Errorf("Expected cards in document order, got %q", questions(set.Flashcards))
+	}
+	if streamed != set.TotalCards {
+		t.Errorf("Expected every merged card to be reported, got %d", streamed)
+	}
+}
+
+func TestFlashcardService_GenerateFromDocuments_Chunked(t *testing.T) {
+	provider := &chunkProvider{release: make(chan struct{}, 10)}
+	for i := 0; i < 10; i++ {
+		provider.release <- struct{}{}
+	}
+	service := NewFlashcardService(NewPDFService(), provider)
+	service.ChunkTokens = 30
+
+	long := strings.Repeat("text ", 40) + PageBreak + strings.Repeat("more ", 20)
+	set, err := service.generateFromDocuments(context.Background(), []string{"a.pdf", "b.pdf"}, []string{long, "A short document."}, models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	sources := make(map[string]int)
+	for _, card := range set.Flashcards {
+		sources[card.Source]++
+	}
+	if sources["a.pdf"] == 0 || sources["b.pdf"] == 0 || len(sources) != 2 {
+		t.Errorf("Expected cards sourced from both documents, got %v", sources)
+	}
+	if strings.Join(set.Sources, ",") != "a.pdf,b.pdf" {
+		t.Errorf("Expected both sources on the set, got %v", set.Sources)
+	}
+}
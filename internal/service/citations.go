@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// pageMarkerFormat introduces each page of PDF text sent to the model so
+// it can cite page numbers.
+const pageMarkerFormat = "[Page %d]\n"
+
+// minEvidenceChars is the fewest letters and digits a quote needs to count
+// as evidence. Shorter quotes turn up almost anywhere.
+const minEvidenceChars = 12
+
+var (
+	pageMarkerPattern = regexp.MustCompile(`^\[Page (\d+)\]\n`)
+
+	// evidenceGap separates the fragments of a quote that skips text.
+	evidenceGap = regexp.MustCompile(`\.\.\.|…`)
+)
+
+// markPages prefixes each non-empty page of extracted PDF text with a
+// [Page N] marker. Pages stay separated by PageBreak, so chunks still end
+// on page boundaries and carry their page numbers with them.
+func markPages(text string) string {
+	pages := strings.Split(text, PageBreak)
+	for i, page := range pages {
+		if strings.TrimSpace(page) != "" {
+			pages[i] = fmt.Sprintf(pageMarkerFormat, i+1) + page
+		}
+	}
+	return strings.Join(pages, PageBreak)
+}
+
+// sourceText is a document normalized for finding quotes in it.
+type sourceText struct {
+	text  string
+	pages []pageSpan
+}
+
+// pageSpan is where a page starts in sourceText.text. Number is 0 for
+// text without page markers.
+type pageSpan struct {
+	number int
+	start  int
+}
+
+func newSourceText(text string) *sourceText {
+	var builder strings.Builder
+	source := &sourceText{}
+	for _, page := range strings.Split(text, PageBreak) {
+		number := 0
+		if match := pageMarkerPattern.FindStringSubmatch(page); match != nil {
+			number, _ = strconv.Atoi(match[1])
+			page = page[len(match[0]):]
+		}
+		source.pages = append(source.pages, pageSpan{number: number, start: builder.Len()})
+		builder.WriteString(normalizeEvidence(page))
+	}
+	source.text = builder.String()
+	return source
+}
+
+// find looks for evidence in the text, allowing "..." where the quote
+// skips text, and returns the numbered pages the quote spans.
+func (s *sourceText) find(evidence string) ([]int, bool) {
+	start, end, matched := -1, 0, 0
+	for _, fragment := range evidenceGap.Split(evidence, -1) {
+		fragment = normalizeEvidence(fragment)
+		if fragment == "" {
+			continue
+		}
+		i := strings.Index(s.text[end:], fragment)
+		if i < 0 {
+			return nil, false
+		}
+		if start < 0 {
+			start = end + i
+		}
+		end += i + len(fragment)
+		matched += len(fragment)
+	}
+	if matched < minEvidenceChars {
+		return nil, false
+	}
+
+	var pages []int
+	for i, page := range s.pages {
+		pageEnd := len(s.text)
+		if i+1 < len(s.pages) {
+			pageEnd = s.pages[i+1].start
+		}
+		if page.number > 0 && page.start < pageEnd && page.start < end && pageEnd > start {
+			pages = append(pages, page.number)
+		}
+	}
+	return pages, true
+}
+
+// normalizeEvidence keeps only the lowercased letters and digits of text,
+// so quotes match despite the spacing, line breaks and hyphenation PDF
+// extraction leaves behind.
+func normalizeEvidence(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// sourceDocuments are the documents a deck is generated from, used to
+// check the evidence quoted on its cards.
+type sourceDocuments []sourceDocument
+
+type sourceDocument struct {
+	Source string
+	Text   *sourceText
+}
+
+func newSourceDocuments(sources, texts []string) sourceDocuments {
+	documents := make(sourceDocuments, len(texts))
+	for i, text := range texts {
+		documents[i] = sourceDocument{Source: sources[i], Text: newSourceText(text)}
+	}
+	return documents
+}
+
+// ground checks the card's evidence against the document named by its
+// source, or every document if it names none of them. Grounded cards get
+// the pages the quote was found on; the others keep the pages the model
+// cited and are flagged.
+func (d sourceDocuments) ground(card *models.Flashcard) {
+	candidates := d
+	for _, document := range d {
+		if document.Source == card.Source {
+			candidates = sourceDocuments{document}
+			break
+		}
+	}
+
+	if strings.TrimSpace(card.Evidence) != "" {
+		for _, document := range candidates {
+			if pages, ok := document.Text.find(card.Evidence); ok {
+				card.SourcePages = pages
+				card.Grounded = boolPtr(true)
+				return
+			}
+		}
+	}
+	card.Grounded = boolPtr(false)
+}
+
+// groundAll grounds every card in place.
+func (d sourceDocuments) groundAll(cards []models.Flashcard) {
+	for i := range cards {
+		d.ground(&cards[i])
+	}
+}
+
+// groundProgress grounds cards before they are reported, so streamed
+// cards carry the same flags as the final deck.
+func (d sourceDocuments) groundProgress(progress ProgressFunc) ProgressFunc {
+	if progress == nil {
+		return nil
+	}
+	return func(p Progress) {
+		if p.Stage == StageCard {
+			card := *p.Card
+			d.ground(&card)
+			p.Card = &card
+		}
+		progress(p)
+	}
+}
+
+// keepGrounding carries the grounding of previous cards over to revised
+// cards that still quote the same evidence. Revised decks have no source
+// text to check new quotes against, so other cards are left unchecked.
+func keepGrounding(cards, previous []models.Flashcard) {
+	grounded := make(map[string]*bool)
+	for _, card := range previous {
+		if card.Evidence != "" && card.Grounded != nil {
+			grounded[card.Evidence] = card.Grounded
+		}
+	}
+	for i := range cards {
+		if flag, ok := grounded[cards[i].Evidence]; ok {
+			cards[i].Grounded = boolPtr(*flag)
+		}
+	}
+}
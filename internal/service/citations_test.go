@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestMarkPages(t *testing.T) {
+	text := "First page" + PageBreak + "  " + PageBreak + "Third page"
+
+	got := markPages(text)
+	want := "[Page 1]\nFirst page" + PageBreak + "  " + PageBreak + "[Page 3]\nThird page"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSourceText_Find(t *testing.T) {
+	source := newSourceText(markPages(
+		"The mitochondrion is the site of cellular\nrespiration." + PageBreak +
+			"" + PageBreak +
+			"The nucleus stores the genetic material of the cell as DNA." + PageBreak +
+			"Ribosomes trans-\nlate messenger RNA into proteins.",
+	))
+
+	tests := []struct {
+		name      string
+		evidence  string
+		wantPages []int
+		wantFound bool
+	}{
+		{"Exact quote", "The nucleus stores the genetic material of the cell as DNA.", []int{3}, true},
+		{"Line breaks and case", "the site of cellular respiration", []int{1}, true},
+		{"Hyphenated word", "Ribosomes translate messenger RNA", []int{4}, true},
+		{"Ellipsis", "The nucleus stores ... as DNA", []int{3}, true},
+		{"Across pages", "genetic material of the cell as DNA. Ribosomes translate", []int{3, 4}, true},
+		{"Fragments out of order", "as DNA ... The nucleus stores", nil, false},
+		{"Not in the text", "Lysosomes digest worn-out organelles.", nil, false},
+		{"Too short", "DNA", nil, false},
+		{"Empty", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, found := source.find(tt.evidence)
+			if found != tt.wantFound {
+				t.Fatalf("Expected found %v, got %v", tt.wantFound, found)
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) {
+				t.Errorf("Expected pages %v, got %v", tt.wantPages, pages)
+			}
+		})
+	}
+}
+
+func TestSourceText_Find_Unpaged(t *testing.T) {
+	source := newSourceText("Photosynthesis converts light energy into chemical energy.")
+
+	pages, found := source.find("converts light energy")
+	if !found {
+		t.Fatal("Expected the quote to be found")
+	}
+	if pages != nil {
+		t.Errorf("Expected no pages for text without page markers, got %v", pages)
+	}
+}
+
+func TestSourceDocuments_Ground(t *testing.T) {
+	documents := newSourceDocuments(
+		[]string{"a.pdf", "b.pdf"},
+		[]string{markPages("Mitochondria produce ATP for the cell."), markPages("Chloroplasts capture light energy.")},
+	)
+
+	tests := []struct {
+		name         string
+		card         models.Flashcard
+		wantGrounded bool
+		wantPages    []int
+	}{
+		{
+			name:         "Quote in its document",
+			card:         models.Flashcard{Source: "a.pdf", Evidence: "Mitochondria produce ATP", SourcePages: []int{7}},
+			wantGrounded: true,
+			wantPages:    []int{1},
+		},
+		{
+			name:         "Quote in another document",
+			card:         models.Flashcard{Source: "a.pdf", Evidence: "Chloroplasts capture light", SourcePages: []int{7}},
+			wantGrounded: false,
+			wantPages:    []int{7},
+		},
+		{
+			name:         "Unknown source searches every document",
+			card:         models.Flashcard{Evidence: "Chloroplasts capture light"},
+			wantGrounded: true,
+			wantPages:    []int{1},
+		},
+		{
+			name:         "No evidence",
+			card:         models.Flashcard{Source: "a.pdf"},
+			wantGrounded: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := tt.card
+			documents.ground(&card)
+			if card.Grounded == nil || *card.Grounded != tt.wantGrounded {
+				t.Errorf("Expected grounded %v, got %v", tt.wantGrounded, card.Grounded)
+			}
+			if !reflect.DeepEqual(card.SourcePages, tt.wantPages) {
+				t.Errorf("Expected pages %v, got %v", tt.wantPages, card.SourcePages)
+			}
+		})
+	}
+}
+
+func TestKeepGrounding(t *testing.T) {
+	previous := []models.Flashcard{
+		{Question: "Old", Evidence: "Mitochondria produce ATP", Grounded: boolPtr(true)},
+		{Question: "Flagged", Evidence: "Made up quote", Grounded: boolPtr(false)},
+	}
+	cards := []models.Flashcard{
+		{Question: "Old, reworded", Evidence: "Mitochondria produce ATP"},
+		{Question: "Flagged", Evidence: "Made up quote"},
+		{Question: "New", Evidence: "Something else"},
+	}
+
+	keepGrounding(cards, previous)
+
+	if cards[0].Grounded == nil || !*cards[0].Grounded {
+		t.Errorf("Expected the reworded card to stay grounded, got %v", cards[0].Grounded)
+	}
+	if cards[1].Grounded == nil || *cards[1].Grounded {
+		t.Errorf("Expected the flagged card to stay flagged, got %v", cards[1].Grounded)
+	}
+	if cards[2].Grounded != nil {
+		t.Errorf("Expected the new card to be unchecked, got %v", *cards[2].Grounded)
+	}
+}
+
+func TestDecodeFlashcards_IgnoresModelGrounding(t *testing.T) {
+	cards, err := decodeFlashcards(`{"flashcards": [{"question": "Q?", "answer": "A", "evidence": " quote ", "sourcePages": [0, 2], "grounded": true}]}`)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	card := cards[0]
+	if card.Grounded != nil {
+		t.Errorf("Expected the model's grounded flag to be dropped, got %v", *card.Grounded)
+	}
+	if card.Evidence != "quote" {
+		t.Errorf("Expected %q, got %q", "quote", card.Evidence)
+	}
+	if !reflect.DeepEqual(card.SourcePages, []int{2}) {
+		t.Errorf("Expected pages [2], got %v", card.SourcePages)
+	}
+	items := flashcardResponseSchema["properties"].(map[string]interface{})["flashcards"].(map[string]interface{})["items"].(map[string]interface{})
+	if _, ok := items["properties"].(map[string]interface{})["grounded"]; ok {
+		t.Error("Expected grounded to be left out of the model's schema")
+	}
+	if _, ok := models.FlashcardSchema["properties"].(map[string]interface{})["grounded"]; !ok {
+		t.Error("Expected grounded in the published flashcard schema")
+	}
+}
+
+func TestFlashcardService_GenerateFromText_Grounding(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: `{"flashcards": [
+  {"question": "What do mitochondria produce?", "answer": "ATP", "evidence": "Mitochondria produce ATP"},
+  {"question": "What do lysosomes do?", "answer": "Digest waste", "evidence": "Lysosomes digest worn-out organelles"}
+]}`})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	var streamed []models.Flashcard
+	set, err := service.GenerateFromText(context.Background(), "Mitochondria produce ATP for the cell.", models.GenerationOptions{}, func(p Progress) {
+		if p.Stage == StageCard {
+			streamed = append(streamed, *p.Card)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i, want := range []bool{true, false} {
+		if got := set.Flashcards[i].Grounded; got == nil || *got != want {
+			t.Errorf("Card %d: expected grounded %v, got %v", i+1, want, got)
+		}
+		if got := streamed[i].Grounded; got == nil || *got != want {
+			t.Errorf("Streamed card %d: expected grounded %v, got %v", i+1, want, got)
+		}
+	}
+
+	text := service.FormatAsText(set)
+	for _, want := range []string{"1 of 2 cards quote evidence that was not found", "Evidence: \"Lysosomes digest worn-out organelles\"\nUnverified:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if !strings.Contains(provider.Requests()[0].Prompt, `"evidence"`) {
+		t.Error("Expected the prompt to ask for evidence")
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/netguard"
+)
+
+// MaxRedirects is the most redirects followed when downloading a PDF.
+const MaxRedirects = 5
+
+// DefaultDownloadTimeout bounds a PDF download unless DownloadTimeout is
+// changed or the caller's context ends sooner.
+const DefaultDownloadTimeout = 30 * time.Second
+
+// pdfContentTypes are the media types a PDF download may be served as.
+// Servers that don't know the type send a generic one, or none at all.
+var pdfContentTypes = map[string]bool{
+	"application/pdf":          true,
+	"application/x-pdf":        true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+}
+
+// newDownloadClient returns the client DownloadPDF uses. Every address it
+// connects to is checked with netguard once DNS has been resolved.
+// Proxies are ignored, since the address dialed would be the proxy's.
+func (s *PDFService) newDownloadClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: s.checkDial,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: netguard.CheckRedirect(MaxRedirects),
+	}
+}
+
+// checkDial refuses connections to addresses that aren't publicly
+// routable, unless they are in AllowedNetworks.
+func (s *PDFService) checkDial(network, address string, _ syscall.RawConn) error {
+	return netguard.CheckAddress(address, s.AllowedNetworks)
+}
+
+// checkContentType returns an error unless the response is declared as a
+// PDF, or as generic binary data. A missing Content-Type is allowed; the
+// body is still checked for the PDF header.
+func checkContentType(resp *http.Response) error {
+	header := resp.Header.Get("Content-Type")
+	if header == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || !pdfContentTypes[mediaType] {
+		return fmt.Errorf("content type %q is not a PDF", header)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// loopback lets tests download from httptest servers.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+const testPDF = "%PDF-1.4\nrest of pdf content"
+
+func errorCode(err error) int {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return 0
+}
+
+func TestPDFService_DownloadPDF_BlocksPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(testPDF))
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"Loopback", server.URL + "/doc.pdf"},
+		{"Loopback after DNS", "http://localhost:" + port + "/doc.pdf"},
+		{"IPv6 loopback", "http://[::1]:" + port + "/doc.pdf"},
+		{"IPv4-mapped loopback", "http://[::ffff:127.0.0.1]:" + port + "/doc.pdf"},
+		{"Unspecified", "http://0.0.0.0:" + port + "/doc.pdf"},
+		{"Cloud metadata", "http://169.254.169.254/latest/meta-data/doc.pdf"},
+		{"Private", "http://10.0.0.1/doc.pdf"},
+		{"Carrier-grade NAT", "http://100.64.0.1/doc.pdf"},
+	}
+
+	service := NewPDFService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.DownloadPDF(context.Background(), tt.url)
+			if errorCode(err) != models.InvalidParams {
+				t.Errorf("Expected an InvalidParams error, got %v", err)
+			}
+		})
+	}
+	if hits.Load() != 0 {
+		t.Errorf("Expected no request to reach the server, got %d", hits.Load())
+	}
+}
+
+func TestPDFService_DownloadPDF_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPDF))
+	})
+	mux.HandleFunc("/hop/{n}", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscan(r.PathValue("n"), &n)
+		if n == 0 {
+			http.Redirect(w, r, "/doc.pdf", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{"Within the limit", fmt.Sprintf("/hop/%d", MaxRedirects-1), 0},
+		{"Too many", fmt.Sprintf("/hop/%d", MaxRedirects), models.InvalidParams},
+		{"To a private address", "/metadata", models.InvalidParams},
+		{"To another scheme", "/file", models.InvalidParams},
+	}
+
+	service := NewPDFService()
+	service.AllowedNetworks = loopback
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := service.DownloadPDF(context.Background(), server.URL+tt.path)
+			if tt.wantCode == 0 {
+				if err != nil || string(data) != testPDF {
+					t.Errorf("Expected the PDF, got %q, %v", data, err)
+				}
+				return
+			}
+			if errorCode(err) != tt.wantCode {
+				t.Errorf("Expected error code %d, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestPDFService_DownloadPDF_Responses(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+	}{
+		{"PDF", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte(testPDF))
+		}, 0},
+		{"PDF with parameters", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "Application/PDF; name=doc.pdf")
+			w.Write([]byte(testPDF))
+		}, 0},
+		{"Generic binary", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(testPDF))
+		}, 0},
+		{"HTML", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>" + testPDF))
+		}, models.InvalidParams},
+		{"Declared too large", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Length", "4096")
+			w.WriteHeader(http.StatusOK)
+		}, models.InvalidParams},
+		{"Streamed too large", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/pdf")
+			for i := 0; i < 64; i++ {
+				w.Write([]byte(testPDF))
+				w.(http.Flusher).Flush()
+			}
+		}, models.InvalidParams},
+		{"Not found", func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		}, models.InternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			service := NewPDFService()
+			service.AllowedNetworks = loopback
+			service.MaxSize = 1024
+
+			data, err := service.DownloadPDF(context.Background(), server.URL+"/doc.pdf")
+			if tt.wantCode == 0 {
+				if err != nil || string(data) != testPDF {
+					t.Errorf("Expected the PDF, got %q, %v", data, err)
+				}
+				return
+			}
+			if errorCode(err) != tt.wantCode {
+				t.Errorf("Expected error code %d, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestPDFService_DownloadPDF_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4\n"))
+		w.(http.Flusher).Flush()
+		// Then stall partway through the body
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	service := NewPDFService()
+	service.AllowedNetworks = loopback
+	service.DownloadTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := service.DownloadPDF(context.Background(), server.URL+"/slow.pdf")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the download to stop at its deadline, took %v", elapsed)
+	}
+
+	// The caller's deadline applies when it is sooner
+	service.DownloadTimeout = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := service.DownloadPDF(ctx, server.URL+"/slow.pdf"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPDFService_DownloadPDF_InvalidURL(t *testing.T) {
+	service := NewPDFService()
+	for _, url := range []string{"ftp://example.com/doc.pdf", "file:///etc/passwd", "http:///doc.pdf", "http://exa mple.com/doc.pdf"} {
+		if _, err := service.DownloadPDF(context.Background(), url); errorCode(err) != models.InvalidParams {
+			t.Errorf("Expected an InvalidParams error for %q, got %v", url, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderFake prefixes the model name of FakeProvider.
+const ProviderFake = "fake"
+
+// FakeResponse is one scripted reply of a FakeProvider.
+type FakeResponse struct {
+	// Text is the complete reply. When Chunks is set it is ignored and the
+	// reply is the chunks joined together.
+	Text string
+
+	// Chunks are the pieces GenerateStream reports, in order.
+	Chunks []string
+
+	// Err is returned instead of a reply, after any chunks have been sent.
+	Err error
+
+	// Latency is waited before replying, or between streamed chunks.
+	Latency time.Duration
+}
+
+// FakeProvider is a deterministic LLMProvider that replays scripted
+// responses, one per call. Once the script runs out the last response
+// repeats. It records every request it receives.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []FakeResponse
+	calls     int
+	requests  []GenerateRequest
+}
+
+// NewFakeProvider creates a provider that replies with responses in order.
+func NewFakeProvider(responses ...FakeResponse) *FakeProvider {
+	return &FakeProvider{responses: responses}
+}
+
+func (p *FakeProvider) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	resp, err := p.next(req)
+	if err != nil {
+		return "", err
+	}
+
+	if err := sleep(ctx, resp.Latency); err != nil {
+		return "", err
+	}
+	if resp.Err != nil {
+		return "", resp.Err
+	}
+	return resp.text(), nil
+}
+
+func (p *FakeProvider) GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error {
+	resp, err := p.next(req)
+	if err != nil {
+		return err
+	}
+
+	chunks := resp.Chunks
+	if len(chunks) == 0 && resp.Text != "" {
+		chunks = []string{resp.Text}
+	}
+	for _, chunk := range chunks {
+		if err := sleep(ctx, resp.Latency); err != nil {
+			return err
+		}
+		onChunk(chunk)
+	}
+	return resp.Err
+}
+
+func (p *FakeProvider) Model() string {
+	return ProviderFake + "/scripted"
+}
+
+// Requests returns the requests received so far, oldest first.
+func (p *FakeProvider) Requests() []GenerateRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]GenerateRequest(nil), p.requests...)
+}
+
+func (p *FakeProvider) next(req GenerateRequest) (FakeResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return FakeResponse{}, errors.New("fake provider has no scripted responses")
+	}
+
+	i := p.calls
+	if i >= len(p.responses) {
+		i = len(p.responses) - 1
+	}
+	p.calls++
+	return p.responses[i], nil
+}
+
+func (r FakeResponse) text() string {
+	if len(r.Chunks) > 0 {
+		return strings.Join(r.Chunks, "")
+	}
+	return r.Text
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
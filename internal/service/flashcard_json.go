@@ -0,0 +1,314 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// flashcardResponseSchema is the JSON Schema the model's reply must match.
+// Providers with structured output enforce it; the others are told about
+// it in the prompt.
+var flashcardResponseSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"flashcards": map[string]interface{}{
+			"type":  "array",
+			"items": withoutProperties(models.FlashcardSchema, "id", "grounded"),
+		},
+	},
+	"required": []string{"flashcards"},
+}
+
+// withoutProperties returns a copy of an object schema without the named
+// properties, for fields the service fills in itself.
+func withoutProperties(schema map[string]interface{}, names ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		out[key] = value
+	}
+	properties := make(map[string]interface{})
+	for name, property := range schema["properties"].(map[string]interface{}) {
+		properties[name] = property
+	}
+	for _, name := range names {
+		delete(properties, name)
+	}
+	out["properties"] = properties
+	return out
+}
+
+// Reply formats appended to generation prompts
+const (
+	flashcardJSONFormat = `Respond with only a JSON object of this form:
+{"flashcards": [{"question": "...", "answer": "...", "topic": "...", "evidence": "...", "sourcePages": [1]}]}
+` + evidenceFormat
+
+	sourcedFlashcardJSONFormat = `Respond with only a JSON object of this form, where "source" is the name
+of the document the flashcard comes from:
+{"flashcards": [{"question": "...", "answer": "...", "topic": "...", "source": "...", "evidence": "...", "sourcePages": [1]}]}
+` + evidenceFormat
+
+	evidenceFormat = `"evidence" is a short passage copied word for word from the text that
+supports the answer. If the text marks its pages with [Page N], list the
+pages the evidence is on in "sourcePages"; otherwise leave it out.
+`
+)
+
+// decodeFlashcards parses a JSON reply, either {"flashcards": [...]} or a
+// bare array, and validates each card. It returns the valid cards along
+// with an error describing everything that was wrong, so a partly valid
+// reply can still be used.
+func decodeFlashcards(text string) ([]models.Flashcard, error) {
+	text = stripCodeFence(text)
+
+	var raw []json.RawMessage
+	if strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		var reply struct {
+			Flashcards []json.RawMessage `json:"flashcards"`
+		}
+		if err := json.Unmarshal([]byte(text), &reply); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if reply.Flashcards == nil {
+			return nil, errors.New(`reply has no "flashcards" array`)
+		}
+		raw = reply.Flashcards
+	}
+
+	var cards []models.Flashcard
+	var problems []string
+	for i, data := range raw {
+		var card models.Flashcard
+		if err := json.Unmarshal(data, &card); err != nil {
+			problems = append(problems, fmt.Sprintf("flashcard %d: %v", i+1, err))
+			continue
+		}
+		if err := validateFlashcard(&card); err != nil {
+			problems = append(problems, fmt.Sprintf("flashcard %d: %v", i+1, err))
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	if len(problems) > 0 {
+		return cards, errors.New(strings.Join(problems, "; "))
+	}
+	if len(cards) == 0 {
+		return nil, errors.New("reply has no flashcards")
+	}
+	return cards, nil
+}
+
+// validateFlashcard trims the card's fields and checks the required ones
+// are present, including those of its card type. A missing topic defaults
+// to "Concept", as in the text format. Grounding is left for the service
+// to check, and IDs for the deck store to assign.
+func validateFlashcard(card *models.Flashcard) error {
+	card.ID = ""
+	card.Question = strings.TrimSpace(card.Question)
+	card.Answer = strings.TrimSpace(card.Answer)
+	card.Topic = strings.TrimSpace(card.Topic)
+	card.Source = strings.TrimSpace(card.Source)
+	card.Evidence = strings.TrimSpace(card.Evidence)
+	card.Grounded = nil
+
+	var pages []int
+	for _, page := range card.SourcePages {
+		if page > 0 {
+			pages = append(pages, page)
+		}
+	}
+	card.SourcePages = pages
+
+	if err := normalizeCardType(card); err != nil {
+		return err
+	}
+
+	switch {
+	case card.Question == "":
+		return errors.New("missing question")
+	case card.Answer == "":
+		return errors.New("missing answer")
+	}
+	if card.Topic == "" {
+		card.Topic = "Concept"
+	}
+	return nil
+}
+
+// ValidateCard normalizes an edited card the way generated cards are and
+// returns an InvalidParams error if it is incomplete. The card keeps its
+// ID, but its grounding is cleared as the evidence may have changed.
+func ValidateCard(card *models.Flashcard) error {
+	id := card.ID
+	err := validateFlashcard(card)
+	card.ID = id
+	if err != nil {
+		return apperrors.NewAppError(
+			models.InvalidParams,
+			"invalid flashcard: "+err.Error(),
+			nil,
+		)
+	}
+	return nil
+}
+
+// stripCodeFence removes a Markdown code fence around the reply, which
+// some models add even in JSON mode.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	if newline := strings.Index(text, "\n"); newline >= 0 {
+		text = text[newline+1:]
+	} else {
+		text = strings.TrimPrefix(text, "```")
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// repairPrompt asks the model to fix a reply that failed validation.
+func repairPrompt(prompt, reply string, problem error) string {
+	return fmt.Sprintf(`%s
+Your previous reply could not be used because: %s
+
+Previous reply:
+%s
+
+Reply again with only the corrected JSON object.
+`, prompt, problem, reply)
+}
+
+// jsonCardStreamParser reports flashcards from a streamed JSON reply as
+// soon as each card object inside the flashcards array is complete.
+type jsonCardStreamParser struct {
+	buf       strings.Builder
+	stack     []byte
+	inString  bool
+	escaped   bool
+	start     int
+	cardDepth int
+	cards     []models.Flashcard
+	onCard    func(models.Flashcard)
+}
+
+func newJSONCardStreamParser(onCard func(models.Flashcard)) *jsonCardStreamParser {
+	return &jsonCardStreamParser{start: -1, onCard: onCard}
+}
+
+func (p *jsonCardStreamParser) Write(chunk string) {
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+		p.buf.WriteByte(c)
+
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case c == '\\':
+				p.escaped = true
+			case c == '"':
+				p.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			p.inString = true
+		case '{', '[':
+			// A card is an object directly inside an array, outside any
+			// card already open
+			if c == '{' && p.start < 0 && len(p.stack) > 0 && p.stack[len(p.stack)-1] == '[' {
+				p.start = p.buf.Len() - 1
+				p.cardDepth = len(p.stack)
+			}
+			p.stack = append(p.stack, c)
+		case '}', ']':
+			if len(p.stack) == 0 {
+				continue
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+			if c == '}' && p.start >= 0 && len(p.stack) == p.cardDepth {
+				p.emit(p.buf.String()[p.start:])
+				p.start = -1
+			}
+		}
+	}
+}
+
+func (p *jsonCardStreamParser) emit(object string) {
+	var card models.Flashcard
+	if err := json.Unmarshal([]byte(object), &card); err != nil {
+		return
+	}
+	if validateFlashcard(&card) != nil {
+		return
+	}
+	p.cards = append(p.cards, card)
+	p.onCard(card)
+}
+
+// Flush returns every card reported so far.
+func (p *jsonCardStreamParser) Flush() []models.Flashcard {
+	return p.cards
+}
+
+// cardParser is implemented by the JSON and legacy text stream parsers.
+type cardParser interface {
+	Write(chunk string)
+	Flush() []models.Flashcard
+}
+
+// replyStreamParser picks the JSON or the legacy Q:/A:/T: stream parser
+// from the first non-space character of the reply, so models that ignore
+// the requested format still stream cards.
+type replyStreamParser struct {
+	pending strings.Builder
+	parser  cardParser
+	onCard  func(models.Flashcard)
+}
+
+func newReplyStreamParser(onCard func(models.Flashcard)) *replyStreamParser {
+	return &replyStreamParser{onCard: onCard}
+}
+
+func (p *replyStreamParser) Write(chunk string) {
+	if p.parser != nil {
+		p.parser.Write(chunk)
+		return
+	}
+
+	p.pending.WriteString(chunk)
+	head := strings.TrimSpace(p.pending.String())
+	if head == "" {
+		return
+	}
+
+	switch head[0] {
+	case '{', '[', '`':
+		p.parser = newJSONCardStreamParser(p.onCard)
+	default:
+		p.parser = newCardStreamParser(p.onCard)
+	}
+	p.parser.Write(p.pending.String())
+	p.pending.Reset()
+}
+
+// Flush returns every card reported so far.
+func (p *replyStreamParser) Flush() []models.Flashcard {
+	if p.parser == nil {
+		return nil
+	}
+	return p.parser.Flush()
+}
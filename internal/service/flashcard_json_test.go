@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestDecodeFlashcards(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantCards int
+		wantErr   string
+	}{
+		{
+			name:      "Object",
+			input:     `{"flashcards": [{"question": "What is 2+2?", "answer": "4", "topic": "Math"}]}`,
+			wantCards: 1,
+		},
+		{
+			name:      "Bare array",
+			input:     `[{"question": "What is 2+2?", "answer": "4"}]`,
+			wantCards: 1,
+		},
+		{
+			name:      "Code fence",
+			input:     "```json\n{\"flashcards\": [{\"question\": \"What is 2+2?\", \"answer\": \"4\"}]}\n```",
+			wantCards: 1,
+		},
+		{
+			name:      "Partly valid",
+			input:     `{"flashcards": [{"question": "What is 2+2?", "answer": "4"}, {"question": "What is 3+3?", "answer": " "}]}`,
+			wantCards: 1,
+			wantErr:   "flashcard 2: missing answer",
+		},
+		{
+			name:    "Wrong field type",
+			input:   `{"flashcards": [{"question": 7, "answer": "4"}]}`,
+			wantErr: "flashcard 1:",
+		},
+		{
+			name:    "No flashcards array",
+			input:   `{"cards": []}`,
+			wantErr: `no "flashcards" array`,
+		},
+		{
+			name:    "Empty array",
+			input:   `{"flashcards": []}`,
+			wantErr: "no flashcards",
+		},
+		{
+			name:    "Legacy text",
+			input:   "Q: What is 2+2?\nA: 4\nT: Math",
+			wantErr: "invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cards, err := decodeFlashcards(tt.input)
+			if len(cards) != tt.wantCards {
+				t.Errorf("Expected %d cards, got %d", tt.wantCards, len(cards))
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDecodeFlashcards_DefaultsTopic(t *testing.T) {
+	cards, err := decodeFlashcards(`{"flashcards": [{"question": "  What is 2+2? ", "answer": "4"}]}`)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cards[0].Question != "What is 2+2?" {
+		t.Errorf("Expected trimmed question, got %q", cards[0].Question)
+	}
+	if cards[0].Topic != "Concept" {
+		t.Errorf("Expected topic %q, got %q", "Concept", cards[0].Topic)
+	}
+}
+
+func TestJSONCardStreamParser(t *testing.T) {
+	reply := `{"flashcards": [
+  {"question": "What does {x} mean in \"set\" notation?", "answer": "A set containing x", "topic": "Sets"},
+  {"question": "Steps?", "answer": "1. Mix\n2. Bake\n\n3. Serve", "topic": "Cooking"},
+  {"question": "", "answer": "dropped"}
+]}`
+
+	var got []models.Flashcard
+	parser := newJSONCardStreamParser(func(card models.Flashcard) {
+		got = append(got, card)
+	})
+	// Feed one byte at a time so cards straddle every chunk boundary
+	for i := 0; i < len(reply); i++ {
+		parser.Write(reply[i : i+1])
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 cards, got %d: %+v", len(got), got)
+	}
+	if got[0].Question != `What does {x} mean in "set" notation?` {
+		t.Errorf("Unexpected first question %q", got[0].Question)
+	}
+	if got[1].Answer != "1. Mix\n2. Bake\n\n3. Serve" {
+		t.Errorf("Expected the multi-line answer intact, got %q", got[1].Answer)
+	}
+	if len(parser.Flush()) != 2 {
+		t.Errorf("Expected Flush to return 2 cards, got %d", len(parser.Flush()))
+	}
+}
+
+func TestReplyStreamParser_Legacy(t *testing.T) {
+	var got []models.Flashcard
+	parser := newReplyStreamParser(func(card models.Flashcard) {
+		got = append(got, card)
+	})
+	for _, chunk := range []string{"\n", "Q: What is 2+2?\nA: 4\n", "T: Math\n\n", "Q: What is 3+3?\nA: 6"} {
+		parser.Write(chunk)
+	}
+	parser.Flush()
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 cards from the legacy format, got %d", len(got))
+	}
+}
+
+func TestFlashcardService_StructuredOutput(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{
+		Text: `{"flashcards": [{"question": "How do you print in Go?", "answer": "Use fmt:\n\n` + "```go\\nfmt.Println(\\\"hi\\\")\\n```" + `", "topic": "Go"}]}`,
+	})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	set, err := service.GenerateFromText(context.Background(), "Go basics", models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if set.TotalCards != 1 {
+		t.Fatalf("Expected 1 card, got %d", set.TotalCards)
+	}
+	if want := "Use fmt:\n\n```go\nfmt.Println(\"hi\")\n```"; set.Flashcards[0].Answer != want {
+		t.Errorf("Expected answer %q, got %q", want, set.Flashcards[0].Answer)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 model request, got %d", len(requests))
+	}
+	if requests[0].Schema == nil {
+		t.Error("Expected the request to carry the flashcard schema")
+	}
+}
+
+func TestFlashcardService_RepairsInvalidReply(t *testing.T) {
+	provider := NewFakeProvider(
+		FakeResponse{Text: `{"flashcards": [{"question": "What is 2+2?"}]}`},
+		FakeResponse{Text: `{"flashcards": [{"question": "What is 2+2?", "answer": "4", "topic": "Math"}]}`},
+	)
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	var streamed []string
+	progress := func(p Progress) {
+		if p.Stage == StageCard {
+			streamed = append(streamed, p.Card.Question)
+		}
+	}
+
+	set, err := service.GenerateFromText(context.Background(), "Arithmetic", models.GenerationOptions{}, progress)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if set.TotalCards != 1 || set.Flashcards[0].Answer != "4" {
+		t.Errorf("Expected the repaired card, got %+v", set.Flashcards)
+	}
+	if len(streamed) != 1 {
+		t.Errorf("Expected the repaired card to be reported once, got %v", streamed)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 model requests, got %d", len(requests))
+	}
+	if !strings.Contains(requests[1].Prompt, "flashcard 1: missing answer") {
+		t.Errorf("Expected the repair prompt to explain the problem, got:\n%s", requests[1].Prompt)
+	}
+}
+
+func TestFlashcardService_LegacyFallback(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	set, err := service.GenerateFromText(context.Background(), "Photosynthesis", models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if set.TotalCards != 2 {
+		t.Errorf("Expected 2 cards from the legacy parser, got %d", set.TotalCards)
+	}
+	if len(provider.Requests()) != 2 {
+		t.Errorf("Expected one repair attempt before falling back, got %d requests", len(provider.Requests()))
+	}
+}
+
+func TestGeminiSchema(t *testing.T) {
+	schema := geminiSchema(flashcardResponseSchema)
+
+	if schema.Type != genai.TypeObject || len(schema.Required) != 1 || schema.Required[0] != "flashcards" {
+		t.Fatalf("Unexpected top-level schema %+v", schema)
+	}
+	items := schema.Properties["flashcards"].Items
+	if items == nil || items.Type != genai.TypeObject {
+		t.Fatalf("Expected flashcard items to be objects, got %+v", items)
+	}
+	if items.Properties["question"].Type != genai.TypeString {
+		t.Errorf("Expected question to be a string, got %+v", items.Properties["question"])
+	}
+	if strings.Join(items.Required, ",") != "question,answer" {
+		t.Errorf("Expected question and answer to be required, got %v", items.Required)
+	}
+}
+
+func TestValidateCard(t *testing.T) {
+	card := models.Flashcard{ID: "card-001", Question: " Edited? ", Answer: "Yes", Grounded: boolPtr(true)}
+	if err := ValidateCard(&card); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if card.ID != "card-001" || card.Question != "Edited?" || card.Topic != "Concept" {
+		t.Errorf("Expected a normalized card keeping its ID, got %+v", card)
+	}
+	if card.Grounded != nil {
+		t.Errorf("Expected grounding to be cleared, got %v", *card.Grounded)
+	}
+
+	card = models.Flashcard{Type: "essay", Question: "Q?", Answer: "A"}
+	if err := ValidateCard(&card); err == nil || !strings.Contains(err.Error(), "invalid flashcard") {
+		t.Errorf("Expected an invalid flashcard error, got %v", err)
+	}
+
+	// Only the deck store assigns IDs, never the model
+	cards, err := decodeFlashcards(`{"flashcards": [{"id": "made-up", "question": "Q?", "answer": "A"}]}`)
+	if err != nil || cards[0].ID != "" {
+		t.Errorf("Expected the model's ID to be dropped, got %+v (%v)", cards, err)
+	}
+}
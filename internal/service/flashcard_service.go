@@ -2,84 +2,190 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
-	"github.com/google/generative-ai-go/genai"
+	"github.com/tobey0x/lagbaja/internal/cache"
 	"github.com/tobey0x/lagbaja/internal/models"
 	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
-	"google.golang.org/api/option"
 )
 
 type FlashcardService struct {
 	pdfService *PDFService
-	client     *genai.Client
-	model      *genai.GenerativeModel
+	provider   LLMProvider
+
+	// ChunkTokens is the largest piece of a document, in estimated tokens,
+	// sent to the model in one prompt. Longer documents are split and
+	// generated from in parallel.
+	ChunkTokens int
+
+	// Concurrency is how many chunks of one document are generated from
+	// at the same time.
+	Concurrency int
+
+	// Cache, when set, keeps extracted text and generated sets so that
+	// repeated requests for the same content are served without the model.
+	Cache cache.Cache
 }
 
-func NewFlashcardService(pdfService *PDFService, apiKey string) *FlashcardService {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
-	if err != nil {
-		log.Printf("Error creating Gemini client: %v", err)
-		return nil
+// NewFlashcardService creates a service that generates flashcards with
+// provider.
+func NewFlashcardService(pdfService *PDFService, provider LLMProvider) *FlashcardService {
+	return &FlashcardService{
+		pdfService:  pdfService,
+		provider:    provider,
+		ChunkTokens: DefaultChunkTokens,
+		Concurrency: DefaultConcurrency,
 	}
+}
 
-	model := client.GenerativeModel("gemini-2.0-flash-lite")
-	
-	return &FlashcardService{
-		pdfService: pdfService,
-		client:     client,
-		model:      model,
+// Model identifies the LLM backend and model used for generation.
+func (s *FlashcardService) Model() string {
+	return s.provider.Model()
+}
+
+// CheckHealth reports whether the LLM backend is reachable. Providers that
+// can't be probed are assumed healthy.
+func (s *FlashcardService) CheckHealth(ctx context.Context) error {
+	if checker, ok := s.provider.(HealthChecker); ok {
+		return checker.CheckHealth(ctx)
 	}
+	return nil
 }
 
+func (s *FlashcardService) GenerateFromURL(ctx context.Context, url string, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	return s.GenerateFromFiles(ctx, []PDFFile{{URI: url}}, opts, progress)
+}
 
-func (s *FlashcardService) GenerateFromURL(url string) (*models.FlashcardSet, error) {
-	// Download PDF
-	pdfData, err := s.pdfService.DownloadPDF(url)
-	if err != nil {
-		return nil, err
+func (s *FlashcardService) GenerateFromPDFData(ctx context.Context, pdfData []byte, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	return s.GenerateFromFiles(ctx, []PDFFile{{Data: pdfData}}, opts, progress)
+}
+
+// PDFFile is one PDF to generate flashcards from, given inline as Data or
+// by URI.
+type PDFFile struct {
+	Name string
+	Data []byte
+	URI  string
+}
+
+// source names the file in the flashcard set and on its cards.
+func (f PDFFile) source() string {
+	switch {
+	case f.Name != "":
+		return f.Name
+	case f.URI != "":
+		return f.URI
+	default:
+		return "uploaded_pdf"
 	}
+}
 
-	// Validate PDF
-	if err := s.pdfService.ValidatePDF(pdfData); err != nil {
+// GenerateFromFiles generates one deck covering every file. When there is
+// more than one file, each card records the file it came from.
+func (s *FlashcardService) GenerateFromFiles(ctx context.Context, files []PDFFile, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	if err := ValidateOptions(&opts); err != nil {
 		return nil, err
 	}
+	if len(files) == 0 {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			"no files to generate flashcards from",
+			nil,
+		)
+	}
 
-	// Extract text
-	text, err := s.pdfService.ExtractText(pdfData)
-	if err != nil {
-		return nil, err
+	if len(files) == 1 {
+		text, err := s.loadFile(ctx, files[0], opts.NoCache, progress)
+		if err != nil {
+			return nil, err
+		}
+		source := files[0].source()
+		key := s.setKey([]string{source}, []string{text}, opts)
+		return s.cachedSet(key, opts, progress, func(opts models.GenerationOptions) (*models.FlashcardSet, error) {
+			return s.generateFlashcards(ctx, text, source, opts, progress)
+		})
+	}
+
+	sources := make([]string, len(files))
+	texts := make([]string, len(files))
+	for i, file := range files {
+		text, err := s.loadFile(ctx, file, opts.NoCache, progress)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.source(), err)
+		}
+		sources[i] = file.source()
+		texts[i] = text
 	}
 
-	// Generate flashcards from text
-	return s.generateFlashcards(text, url)
+	key := s.setKey(sources, texts, opts)
+	return s.cachedSet(key, opts, progress, func(opts models.GenerationOptions) (*models.FlashcardSet, error) {
+		return s.generateFromDocuments(ctx, sources, texts, opts, progress)
+	})
+}
+
+// MaxPDFSize is the largest PDF, in bytes, that is processed. Zero means
+// no limit.
+func (s *FlashcardService) MaxPDFSize() int64 {
+	return s.pdfService.MaxSize
 }
 
-func (s *FlashcardService) GenerateFromPDFData(pdfData []byte) (*models.FlashcardSet, error) {
-	// Validate PDF
+// CheckPDFSize returns an error if a PDF of size bytes is too large to
+// process.
+func (s *FlashcardService) CheckPDFSize(size int64) error {
+	return s.pdfService.CheckSize(size)
+}
+
+// loadFile downloads the file if needed, validates it and extracts its
+// text with each page marked for citation. Text already extracted from the
+// same bytes is reused unless noCache is set.
+func (s *FlashcardService) loadFile(ctx context.Context, file PDFFile, noCache bool, progress ProgressFunc) (string, error) {
+	pdfData := file.Data
+	if file.URI != "" {
+		progress.report(Progress{Stage: StageDownloading, Message: fmt.Sprintf("Downloading PDF from %s", file.URI)})
+		var err error
+		pdfData, err = s.pdfService.DownloadPDF(ctx, file.URI)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	if err := s.pdfService.ValidatePDF(pdfData); err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// Extract text
-	text, err := s.pdfService.ExtractText(pdfData)
+	text, err := s.extractCachedText(ctx, pdfData, noCache, progress)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return markPages(text), nil
+}
 
-	// Generate flashcards from text
-	return s.generateFlashcards(text, "uploaded_pdf")
+func (s *FlashcardService) GenerateFromText(ctx context.Context, text string, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	if err := ValidateOptions(&opts); err != nil {
+		return nil, err
+	}
+	key := s.setKey([]string{"user_input"}, []string{text}, opts)
+	return s.cachedSet(key, opts, progress, func(opts models.GenerationOptions) (*models.FlashcardSet, error) {
+		return s.generateFlashcards(ctx, text, "user_input", opts, progress)
+	})
 }
 
-func (s *FlashcardService) GenerateFromText(text string) (*models.FlashcardSet, error) {
-	return s.generateFlashcards(text, "user_input")
+func (s *FlashcardService) extractText(ctx context.Context, pdfData []byte, progress ProgressFunc) (string, error) {
+	return s.pdfService.ExtractTextWithProgress(ctx, pdfData, func(page, total int) {
+		progress.report(Progress{
+			Stage:      StageExtracting,
+			Message:    fmt.Sprintf("Extracting text page %d/%d", page, total),
+			Page:       page,
+			TotalPages: total,
+		})
+	})
 }
 
-func (s *FlashcardService) generateFlashcards(text, source string) (*models.FlashcardSet, error) {
+func (s *FlashcardService) generateFlashcards(ctx context.Context, text, source string, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
 	log.Printf("Generating flashcards from text (length: %d)", len(text))
 
 	if len(strings.TrimSpace(text)) == 0 {
@@ -90,75 +196,259 @@ func (s *FlashcardService) generateFlashcards(text, source string) (*models.Flas
 		)
 	}
 
-	prompt := fmt.Sprintf(`Create a set of 5-10 high-quality flashcards from the following text. 
+	documents := newSourceDocuments([]string{""}, []string{text})
+	progress = documents.groundProgress(filterProgress(progress, opts))
+
+	var flashcards []models.Flashcard
+	var err error
+	if chunks := s.chunkDocuments([]string{""}, []string{text}); len(chunks) > 1 {
+		flashcards, err = s.generateFromChunks(ctx, chunks, opts, progress)
+	} else {
+		prompt := fmt.Sprintf(`Create a set of %s high-quality flashcards from the following text.
+Each flashcard should:
+- Have a clear, specific question
+- Include a concise but comprehensive answer
+- Be categorized with an appropriate topic
+- Cover key concepts, definitions, and applications
+
+%sText to process:
+%s
+
+%s`, cardCountText(estimateTokens(text), opts), optionsPrompt(opts), text, flashcardJSONFormat)
+
+		flashcards, err = s.runPrompt(ctx, prompt, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	documents.groundAll(flashcards)
+
+	return newSet(models.FlashcardSet{
+		Title:  s.generateTitle(source),
+		Source: source,
+	}, flashcards, opts)
+}
+
+// generateFromDocuments generates one deck from several documents. When
+// they fit in one prompt each is introduced by a "=== Document: name ==="
+// header; otherwise each document is chunked on its own and every card
+// takes the name of the document its chunk came from.
+func (s *FlashcardService) generateFromDocuments(ctx context.Context, sources, texts []string, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	var documents strings.Builder
+	for i, text := range texts {
+		documents.WriteString(fmt.Sprintf("=== Document: %s ===\n%s\n\n", sources[i], text))
+	}
+	log.Printf("Generating flashcards from %d documents (length: %d)", len(sources), documents.Len())
+
+	sourceTexts := newSourceDocuments(sources, texts)
+	progress = sourceTexts.groundProgress(filterProgress(progress, opts))
+
+	var flashcards []models.Flashcard
+	var err error
+	if tokens := estimateTokens(documents.String()); tokens > s.chunkTokens() {
+		flashcards, err = s.generateFromChunks(ctx, s.chunkDocuments(sources, texts), opts, progress)
+	} else {
+		prompt := fmt.Sprintf(`Create a set of %s high-quality flashcards from the following documents.
+Cover every document, and for each flashcard name the document it comes from.
 Each flashcard should:
 - Have a clear, specific question
 - Include a concise but comprehensive answer
 - Be categorized with an appropriate topic
 - Cover key concepts, definitions, and applications
 
-Text to process:
+%sDocuments to process:
 %s
+%s`, cardCountText(tokens, opts), optionsPrompt(opts), documents.String(), sourcedFlashcardJSONFormat)
+
+		flashcards, err = s.runPrompt(ctx, prompt, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sourceTexts.groundAll(flashcards)
 
-Format each flashcard as:
-Q: [Question]
-A: [Answer]
-T: [Topic]
+	return newSet(models.FlashcardSet{
+		Title:   "Flashcards from PDFs",
+		Source:  strings.Join(sources, ", "),
+		Sources: sources,
+	}, flashcards, opts)
+}
+
+// maxRefineTurns is how many earlier user messages are repeated in a
+// refine prompt, so the prompt stops growing as a conversation goes on.
+const maxRefineTurns = 10
 
-`, text)
+// RefineFlashcards applies a follow-up instruction such as "make 5 more,
+// harder ones" to the conversation's current deck and returns the complete
+// updated deck.
+func (s *FlashcardService) RefineFlashcards(ctx context.Context, conversation *models.Conversation, instruction string, opts models.GenerationOptions, progress ProgressFunc) (*models.FlashcardSet, error) {
+	if conversation == nil || conversation.Deck == nil {
+		return s.GenerateFromText(ctx, instruction, opts, progress)
+	}
+	if err := ValidateOptions(&opts); err != nil {
+		return nil, err
+	}
+	log.Printf("Refining %d flashcards in context %s", conversation.Deck.TotalCards, conversation.ContextID)
 
-	ctx := context.Background()
-	resp, err := s.model.GenerateContent(ctx, genai.Text(prompt))
+	var turns []models.Message
+	for _, msg := range conversation.History {
+		if msg.Role == models.RoleUser {
+			turns = append(turns, msg)
+		}
+	}
+	if len(turns) > maxRefineTurns {
+		turns = turns[len(turns)-maxRefineTurns:]
+	}
+	var earlier strings.Builder
+	for _, msg := range turns {
+		for _, part := range msg.Parts {
+			if part.Kind == models.KindText && part.Text != "" {
+				earlier.WriteString(fmt.Sprintf("- %s\n", s.truncate(part.Text, 500)))
+			}
+		}
+	}
+
+	// Card IDs mean nothing to the model
+	current := make([]models.Flashcard, len(conversation.Deck.Flashcards))
+	for i, card := range conversation.Deck.Flashcards {
+		card.ID = ""
+		current[i] = card
+	}
+	deck, err := json.MarshalIndent(map[string]interface{}{
+		"flashcards": current,
+	}, "", "  ")
 	if err != nil {
 		return nil, apperrors.NewAppError(
 			models.InternalError,
-			"failed to generate flashcards",
+			"failed to encode current flashcards",
 			err,
 		)
 	}
 
-	// Extract text from response
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+	prompt := fmt.Sprintf(`You are revising an existing set of study flashcards.
+
+Earlier requests in this conversation:
+%s
+Current flashcards:
+%s
+
+New request:
+%s
+
+Apply the new request to the current flashcards. Keep the cards the request
+does not ask to change, add or modify cards as requested, and return the
+complete updated set without any commentary. Keep "source" only on cards
+that already have one, and keep "evidence" and "sourcePages" unchanged on
+the cards you keep.
+%s
+%s%s
+Cards can be of these types; keep each card's type unless the request
+changes it:
+%s`, earlier.String(), deck, instruction, refineCountText(opts), optionsPrompt(opts), sourcedFlashcardJSONFormat, cardTypesPrompt(models.CardTypes))
+
+	flashcards, err := s.runPrompt(ctx, prompt, filterProgress(progress, opts))
+	if err != nil {
+		return nil, err
+	}
+	keepGrounding(flashcards, conversation.Deck.Flashcards)
+	keepCardIDs(flashcards, conversation.Deck.Flashcards)
+
+	return newSet(models.FlashcardSet{
+		Title:   conversation.Deck.Title,
+		Source:  conversation.Deck.Source,
+		Sources: conversation.Deck.Sources,
+		DeckID:  conversation.Deck.DeckID,
+	}, flashcards, opts)
+}
+
+// keepCardIDs gives revised cards that still ask the same question the ID
+// of the previous card, so the saved deck keeps track of them.
+func keepCardIDs(cards, previous []models.Flashcard) {
+	ids := make(map[string]string)
+	for _, card := range previous {
+		if card.ID != "" {
+			ids[card.CardType()+"\x00"+card.Question] = card.ID
+		}
+	}
+	for i := range cards {
+		key := cards[i].CardType() + "\x00" + cards[i].Question
+		if id, ok := ids[key]; ok {
+			cards[i].ID = id
+			delete(ids, key)
+		}
+	}
+}
+
+// refineCountText asks for the requested deck size when revising a deck.
+func refineCountText(opts models.GenerationOptions) string {
+	if opts.CardCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("The updated set must have exactly %d flashcards.\n", opts.CardCount)
+}
+
+// newSet completes set with the generated cards, enforcing opts on them
+// and recording the constraints they don't meet.
+func newSet(set models.FlashcardSet, flashcards []models.Flashcard, opts models.GenerationOptions) (*models.FlashcardSet, error) {
+	flashcards, unmet := applyOptions(flashcards, opts)
+	if len(flashcards) == 0 {
 		return nil, apperrors.NewAppError(
 			models.InternalError,
-			"no response generated from AI model",
+			"could not generate meaningful flashcards from the content",
 			nil,
 		)
 	}
 
-	// Parse Gemini's response into flashcards
-	var flashcards []models.Flashcard
-	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-	cards := strings.Split(responseText, "\n\n")
+	set.Flashcards = flashcards
+	set.TotalCards = len(flashcards)
+	set.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	if !opts.IsZero() {
+		set.Options = &opts
+		set.UnmetConstraints = unmet
+	}
+	return &set, nil
+}
 
-	for _, card := range cards {
-		if !strings.Contains(card, "Q:") || !strings.Contains(card, "A:") {
-			continue
+// runPrompt asks the model for flashcards as JSON matching
+// flashcardResponseSchema, streaming when progress is set. A reply that
+// fails validation gets one repair attempt; a reply that isn't JSON at all
+// falls back to the legacy Q:/A:/T: text parser.
+func (s *FlashcardService) runPrompt(ctx context.Context, prompt string, progress ProgressFunc) ([]models.Flashcard, error) {
+	progress.report(Progress{Stage: StageGenerating, Message: "Generating flashcards"})
+
+	// Streamed cards are reported as they arrive; cards that only turn up
+	// after a repair are reported at the end. Either way each question is
+	// reported once.
+	reported := make(map[string]bool)
+	report := func(card models.Flashcard) {
+		if progress == nil || reported[card.Question] {
+			return
 		}
+		reported[card.Question] = true
+		progress.report(Progress{Stage: StageCard, Card: &card})
+	}
 
-		lines := strings.Split(card, "\n")
-		var question, answer, topic string
-
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "Q:") {
-				question = strings.TrimSpace(strings.TrimPrefix(line, "Q:"))
-			} else if strings.HasPrefix(line, "A:") {
-				answer = strings.TrimSpace(strings.TrimPrefix(line, "A:"))
-			} else if strings.HasPrefix(line, "T:") {
-				topic = strings.TrimSpace(strings.TrimPrefix(line, "T:"))
-			}
-		}
+	req := GenerateRequest{Prompt: prompt, Schema: flashcardResponseSchema}
+	var responseText string
+	var err error
+	if progress != nil {
+		responseText, err = s.streamReply(ctx, req, report)
+	} else {
+		responseText, err = s.provider.Generate(ctx, req)
+	}
+	if err != nil {
+		return nil, apperrors.NewAppError(
+			models.InternalError,
+			"failed to generate flashcards",
+			err,
+		)
+	}
 
-		if question != "" && answer != "" {
-			if topic == "" {
-				topic = "Concept"
-			}
-			flashcards = append(flashcards, models.Flashcard{
-				Question: question,
-				Answer:   answer,
-				Topic:    topic,
-			})
+	flashcards, err := decodeFlashcards(responseText)
+	if err != nil {
+		flashcards, err = s.repairFlashcards(ctx, req, responseText, flashcards, err)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -171,13 +461,161 @@ T: [Topic]
 		)
 	}
 
-	return &models.FlashcardSet{
-		Title:      s.generateTitle(source),
-		Source:     source,
-		Flashcards: flashcards,
-		TotalCards: len(flashcards),
-		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
-	}, nil
+	for _, card := range flashcards {
+		report(card)
+	}
+	return flashcards, nil
+}
+
+// repairFlashcards re-prompts the model once after reply failed
+// validation. It prefers the repaired cards, then whatever was valid in the
+// first reply, then cards parsed from either reply as legacy text.
+func (s *FlashcardService) repairFlashcards(ctx context.Context, req GenerateRequest, reply string, valid []models.Flashcard, problem error) ([]models.Flashcard, error) {
+	log.Printf("Model reply failed validation, asking for a repair: %v", problem)
+
+	repairReq := req
+	repairReq.Prompt = repairPrompt(req.Prompt, reply, problem)
+	repaired, err := s.provider.Generate(ctx, repairReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, apperrors.NewAppError(
+				models.InternalError,
+				"failed to generate flashcards",
+				err,
+			)
+		}
+		log.Printf("Flashcard repair request failed: %v", err)
+	} else {
+		cards, err := decodeFlashcards(repaired)
+		if err != nil {
+			log.Printf("Repaired reply still failed validation: %v", err)
+		}
+		if len(cards) > 0 {
+			return cards, nil
+		}
+	}
+
+	if len(valid) > 0 {
+		return valid, nil
+	}
+	if cards := parseFlashcards(repaired); len(cards) > 0 {
+		return cards, nil
+	}
+	return parseFlashcards(reply), nil
+}
+
+// streamReply streams the model reply, reporting each card as soon as it
+// is complete, and returns the full reply text.
+func (s *FlashcardService) streamReply(ctx context.Context, req GenerateRequest, onCard func(models.Flashcard)) (string, error) {
+	var reply strings.Builder
+	parser := newReplyStreamParser(onCard)
+
+	err := s.provider.GenerateStream(ctx, req, func(chunk string) {
+		reply.WriteString(chunk)
+		parser.Write(chunk)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	parser.Flush()
+	return reply.String(), nil
+}
+
+// parseFlashcards parses a complete response of blank-line separated
+// Q/A/T blocks.
+func parseFlashcards(responseText string) []models.Flashcard {
+	var flashcards []models.Flashcard
+	for _, block := range strings.Split(responseText, "\n\n") {
+		if card, ok := parseFlashcardBlock(block); ok {
+			flashcards = append(flashcards, card)
+		}
+	}
+	return flashcards
+}
+
+func parseFlashcardBlock(block string) (models.Flashcard, bool) {
+	if !strings.Contains(block, "Q:") || !strings.Contains(block, "A:") {
+		return models.Flashcard{}, false
+	}
+
+	lines := strings.Split(block, "\n")
+	var question, answer, topic, source string
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Q:") {
+			question = strings.TrimSpace(strings.TrimPrefix(line, "Q:"))
+		} else if strings.HasPrefix(line, "A:") {
+			answer = strings.TrimSpace(strings.TrimPrefix(line, "A:"))
+		} else if strings.HasPrefix(line, "T:") {
+			topic = strings.TrimSpace(strings.TrimPrefix(line, "T:"))
+		} else if strings.HasPrefix(line, "S:") {
+			source = strings.TrimSpace(strings.TrimPrefix(line, "S:"))
+		}
+	}
+
+	if question == "" || answer == "" {
+		return models.Flashcard{}, false
+	}
+	if topic == "" {
+		topic = "Concept"
+	}
+	return models.Flashcard{
+		Question: question,
+		Answer:   answer,
+		Topic:    topic,
+		Source:   source,
+	}, true
+}
+
+// cardStreamParser turns streamed response chunks into flashcards. A block
+// is only parsed once the blank line after it arrives, so cards are never
+// reported half-written.
+type cardStreamParser struct {
+	buf    strings.Builder
+	cards  []models.Flashcard
+	onCard func(models.Flashcard)
+}
+
+func newCardStreamParser(onCard func(models.Flashcard)) *cardStreamParser {
+	return &cardStreamParser{onCard: onCard}
+}
+
+func (p *cardStreamParser) Write(chunk string) {
+	p.buf.WriteString(chunk)
+
+	pending := p.buf.String()
+	idx := strings.LastIndex(pending, "\n\n")
+	if idx < 0 {
+		return
+	}
+
+	for _, block := range strings.Split(pending[:idx], "\n\n") {
+		p.emit(block)
+	}
+
+	p.buf.Reset()
+	p.buf.WriteString(pending[idx+2:])
+}
+
+// Flush parses whatever is left once the stream ends and returns every
+// card seen.
+func (p *cardStreamParser) Flush() []models.Flashcard {
+	p.emit(p.buf.String())
+	p.buf.Reset()
+	return p.cards
+}
+
+func (p *cardStreamParser) emit(block string) {
+	card, ok := parseFlashcardBlock(block)
+	if !ok {
+		return
+	}
+	p.cards = append(p.cards, card)
+	if p.onCard != nil {
+		p.onCard(card)
+	}
 }
 
 func (s *FlashcardService) generateTitle(source string) string {
@@ -199,15 +637,60 @@ func (s *FlashcardService) FormatAsText(set *models.FlashcardSet) string {
 
 	builder.WriteString(fmt.Sprintf("# %s\n\n", set.Title))
 	builder.WriteString(fmt.Sprintf("Generated %d flashcards from: %s\n\n", set.TotalCards, set.Source))
+	if set.DeckID != "" {
+		builder.WriteString(fmt.Sprintf("Saved to your library as deck %s.\n\n", set.DeckID))
+	}
+	if set.Cached {
+		builder.WriteString(fmt.Sprintf("Served from the cache of a set generated at %s; send noCache to generate a new one.\n\n", set.CreatedAt))
+	}
 
-	for i, card := range set.Flashcards {
-		builder.WriteString(fmt.Sprintf("**Card %d**", i+1))
-		if card.Topic != "" {
-			builder.WriteString(fmt.Sprintf(" (%s)", card.Topic))
+	if len(set.UnmetConstraints) > 0 {
+		builder.WriteString("Some requested options could not be met:\n")
+		for _, constraint := range set.UnmetConstraints {
+			builder.WriteString(fmt.Sprintf("- %s\n", constraint))
+		}
+		builder.WriteString("\n")
+	}
+
+	unverified := 0
+	for _, card := range set.Flashcards {
+		if card.Grounded != nil && !*card.Grounded {
+			unverified++
 		}
+	}
+	if unverified > 0 {
+		builder.WriteString(fmt.Sprintf("%d of %d cards quote evidence that was not found in the source; check them before studying.\n\n", unverified, set.TotalCards))
+	}
+
+	for i, card := range set.Flashcards {
+		builder.WriteString(s.FormatCard(i+1, card))
 		builder.WriteString("\n")
-		builder.WriteString(fmt.Sprintf("Q: %s\n", card.Question))
-		builder.WriteString(fmt.Sprintf("A: %s\n\n", card.Answer))
+	}
+
+	return builder.String()
+}
+
+// FormatCard renders a single numbered flashcard as markdown.
+func (s *FlashcardService) FormatCard(number int, card models.Flashcard) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("**Card %d**", number))
+	if card.Topic != "" {
+		builder.WriteString(fmt.Sprintf(" (%s)", card.Topic))
+	}
+	if cardType := card.CardType(); cardType != models.CardTypeBasic {
+		builder.WriteString(fmt.Sprintf(" [%s]", cardType))
+	}
+	builder.WriteString("\n")
+	builder.WriteString(formatCardBody(card))
+	if citation := card.Citation(); citation != "" {
+		builder.WriteString(fmt.Sprintf("Source: %s\n", citation))
+	}
+	if card.Evidence != "" {
+		builder.WriteString(fmt.Sprintf("Evidence: %q\n", card.Evidence))
+	}
+	if card.Grounded != nil && !*card.Grounded {
+		builder.WriteString("Unverified: the evidence was not found in the source\n")
 	}
 
 	return builder.String()
@@ -222,4 +705,4 @@ func (s *FlashcardService) ExtractPDFURL(text string) string {
 		}
 	}
 	return ""
-}
\ No newline at end of file
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// DefaultGeminiModel is used when no model is configured.
+const DefaultGeminiModel = "gemini-2.0-flash-lite"
+
+// GeminiProvider generates text with Google Gemini.
+type GeminiProvider struct {
+	client    *genai.Client
+	model     *genai.GenerativeModel
+	modelName string
+}
+
+// NewGeminiProvider creates a Gemini client for model, or
+// DefaultGeminiModel if model is empty.
+func NewGeminiProvider(ctx context.Context, apiKey, model string) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, errors.New("the Gemini provider needs an API key (set GEMINI_API_KEY or LLM_API_KEY)")
+	}
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("creating Gemini client: %w", err)
+	}
+
+	return &GeminiProvider{
+		client:    client,
+		model:     client.GenerativeModel(model),
+		modelName: model,
+	}, nil
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	resp, err := p.modelFor(req).GenerateContent(ctx, genai.Text(req.Prompt))
+	if err != nil {
+		return "", err
+	}
+
+	text := responseText(resp)
+	if text == "" {
+		return "", fmt.Errorf("no response generated from %s", p.Model())
+	}
+	return text, nil
+}
+
+func (p *GeminiProvider) GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error {
+	iter := p.modelFor(req).GenerateContentStream(ctx, genai.Text(req.Prompt))
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if text := responseText(resp); text != "" {
+			onChunk(text)
+		}
+	}
+}
+
+func (p *GeminiProvider) Model() string {
+	return ProviderGemini + "/" + p.modelName
+}
+
+// modelFor returns the model configured for req's output format. JSON
+// requests get their own model so the response schema doesn't leak into
+// other requests.
+func (p *GeminiProvider) modelFor(req GenerateRequest) *genai.GenerativeModel {
+	if !req.wantsJSON() {
+		return p.model
+	}
+
+	model := p.client.GenerativeModel(p.modelName)
+	model.ResponseMIMEType = "application/json"
+	if req.Schema != nil {
+		model.ResponseSchema = geminiSchema(req.Schema)
+	}
+	return model
+}
+
+// geminiTypes maps JSON Schema types to Gemini schema types.
+var geminiTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"number":  genai.TypeNumber,
+	"integer": genai.TypeInteger,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
+
+// geminiSchema converts the subset of JSON Schema used by the models
+// package (type, description, enum, items, properties, required) into a
+// Gemini response schema.
+func geminiSchema(schema map[string]interface{}) *genai.Schema {
+	out := &genai.Schema{}
+	if typ, ok := schema["type"].(string); ok {
+		out.Type = geminiTypes[typ]
+	}
+	if description, ok := schema["description"].(string); ok {
+		out.Description = description
+	}
+	out.Enum = stringList(schema["enum"])
+	out.Required = stringList(schema["required"])
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		out.Items = geminiSchema(items)
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		out.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if property, ok := property.(map[string]interface{}); ok {
+				out.Properties[name] = geminiSchema(property)
+			}
+		}
+	}
+	return out
+}
+
+// stringList reads a schema keyword holding a list of strings.
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var out []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// Close releases the underlying client.
+func (p *GeminiProvider) Close() error {
+	return p.client.Close()
+}
+
+// responseText joins the text parts of the first candidate.
+func responseText(resp *genai.GenerateContentResponse) string {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			builder.WriteString(string(text))
+		}
+	}
+	return builder.String()
+}
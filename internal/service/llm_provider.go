@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Supported values of ProviderConfig.Provider
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// GenerateRequest is one prompt sent to an LLMProvider.
+type GenerateRequest struct {
+	Prompt string
+
+	// JSON asks the model to reply with a single JSON object.
+	JSON bool
+
+	// Schema is a JSON Schema the reply must match. Providers with
+	// structured output enforce it; setting it implies JSON.
+	Schema map[string]interface{}
+}
+
+// wantsJSON reports whether the reply must be JSON.
+func (r GenerateRequest) wantsJSON() bool {
+	return r.JSON || r.Schema != nil
+}
+
+// LLMProvider is a text generation backend used by FlashcardService.
+type LLMProvider interface {
+	// Generate returns the complete response to req.
+	Generate(ctx context.Context, req GenerateRequest) (string, error)
+
+	// GenerateStream calls onChunk with each piece of the response as it
+	// arrives.
+	GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error
+
+	// Model identifies the backend and model, e.g. "gemini/gemini-2.0-flash-lite".
+	Model() string
+}
+
+// HealthChecker is implemented by providers whose backend can be probed
+// cheaply, such as a local model server.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// ProviderConfig selects and configures an LLMProvider.
+type ProviderConfig struct {
+	Provider string
+	Model    string
+	APIKey   string
+
+	// BaseURL is the server of an OpenAI-compatible or Ollama provider.
+	BaseURL string
+}
+
+// NewLLMProvider creates the provider named by cfg.Provider. An empty model
+// uses the provider's default.
+func NewLLMProvider(ctx context.Context, cfg ProviderConfig) (LLMProvider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderGemini:
+		return NewGeminiProvider(ctx, cfg.APIKey, cfg.Model)
+	case ProviderOpenAI:
+		return NewOpenAIProvider(cfg.BaseURL, cfg.APIKey, cfg.Model)
+	case ProviderOllama:
+		return NewOllamaProvider(cfg.BaseURL, cfg.Model), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+}
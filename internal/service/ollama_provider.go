@@ -0,0 +1,290 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults for a local Ollama server
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "llama3.2"
+)
+
+// ollamaPromptTweaks are system prompts for model families that need extra
+// steering to reply with bare JSON matching the requested schema, matched
+// by model name prefix.
+var ollamaPromptTweaks = []struct {
+	prefix string
+	system string
+}{
+	{"llama", "Reply with only the JSON object, without an introduction, closing remarks or code fences."},
+	{"mistral", "Reply with only the JSON object. Do not wrap it in a Markdown code fence or add any text around it."},
+	{"gemma", "Reply with only the JSON object, no prose or code fences. Use \\n inside strings for line breaks; never leave a string unterminated."},
+	{"phi", "Reply with only the JSON object. Keep answers short. Never add notes or explanations after the closing brace."},
+	{"qwen", "Reply with only the JSON object, no prose, reasoning or code fences."},
+}
+
+// OllamaProvider generates text with a model served by Ollama, so lagbaja
+// can run without network access.
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	system     string
+	httpClient *http.Client
+}
+
+// NewOllamaProvider creates a provider for model on the Ollama server at
+// baseURL. Empty values use DefaultOllamaBaseURL and DefaultOllamaModel.
+func NewOllamaProvider(baseURL, model string) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+
+	return &OllamaProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		system:  ollamaSystemPrompt(model),
+		httpClient: &http.Client{
+			// Local models can be slow, especially on the first request
+			// while the model loads
+			Timeout: 10 * time.Minute,
+		},
+	}
+}
+
+// ollamaSystemPrompt returns the prompt tweak for model's family, if any.
+func ollamaSystemPrompt(model string) string {
+	name := strings.ToLower(model)
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	for _, tweak := range ollamaPromptTweaks {
+		if strings.HasPrefix(name, tweak.prefix) {
+			return tweak.system
+		}
+	}
+	return ""
+}
+
+type ollamaGenerateRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	System string `json:"system,omitempty"`
+	// Format is "json" or a JSON Schema object.
+	Format interface{} `json:"format,omitempty"`
+	Stream bool        `json:"stream"`
+}
+
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+	Error    string `json:"error"`
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	resp, err := p.post(ctx, "/api/generate", p.generateRequest(req, false))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result ollamaGenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding Ollama response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama: %s", result.Error)
+	}
+	if result.Response == "" {
+		return "", fmt.Errorf("no response generated from %s", p.Model())
+	}
+	return result.Response, nil
+}
+
+// GenerateStream reads Ollama's newline-delimited JSON stream until the
+// object marked done.
+func (p *OllamaProvider) GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error {
+	resp, err := p.post(ctx, "/api/generate", p.generateRequest(req, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaGenerateResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("decoding Ollama stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			onChunk(chunk.Response)
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("ollama stream ended before the response was done")
+}
+
+func (p *OllamaProvider) Model() string {
+	return ProviderOllama + "/" + p.model
+}
+
+// CheckHealth reports whether the Ollama server is reachable and has the
+// model available.
+func (p *OllamaProvider) CheckHealth(ctx context.Context) error {
+	available, err := p.hasModel(ctx)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return fmt.Errorf("model %s is not available; run `ollama pull %s`", p.model, p.model)
+	}
+	return nil
+}
+
+// PrepareModel checks that the model is available and, if pull is set,
+// downloads it when it isn't.
+func (p *OllamaProvider) PrepareModel(ctx context.Context, pull bool) error {
+	available, err := p.hasModel(ctx)
+	if err != nil {
+		return err
+	}
+	if available {
+		return nil
+	}
+	if !pull {
+		return fmt.Errorf("model %s is not available; run `ollama pull %s`", p.model, p.model)
+	}
+
+	resp, err := p.post(ctx, "/api/pull", map[string]interface{}{"model": p.model, "stream": false})
+	if err != nil {
+		return fmt.Errorf("pulling model %s: %w", p.model, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding pull response: %w", err)
+	}
+	if result.Error != "" {
+		return fmt.Errorf("pulling model %s: %s", p.model, result.Error)
+	}
+	return nil
+}
+
+// hasModel lists the server's local models and looks for p.model. A name
+// without a tag matches the "latest" tag.
+func (p *OllamaProvider) hasModel(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("ollama is not reachable at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("listing Ollama models failed with status %d", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return false, fmt.Errorf("decoding Ollama models: %w", err)
+	}
+
+	want := withDefaultTag(p.model)
+	for _, model := range tags.Models {
+		if withDefaultTag(model.Name) == want || withDefaultTag(model.Model) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func withDefaultTag(model string) string {
+	if model == "" || strings.Contains(model[strings.LastIndex(model, "/")+1:], ":") {
+		return model
+	}
+	return model + ":latest"
+}
+
+func (p *OllamaProvider) generateRequest(req GenerateRequest, stream bool) ollamaGenerateRequest {
+	body := ollamaGenerateRequest{
+		Model:  p.model,
+		Prompt: req.Prompt,
+		System: p.system,
+		Stream: stream,
+	}
+	switch {
+	case req.Schema != nil:
+		body.Format = req.Schema
+	case req.JSON:
+		body.Format = "json"
+	}
+	return body
+}
+
+func (p *OllamaProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama is not reachable at %s: %w", p.baseURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("ollama request failed with status %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return nil, fmt.Errorf("ollama request failed with status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllamaProvider_Generate(t *testing.T) {
+	var got ollamaGenerateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("Expected path /api/generate, got %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"model":"llama3.2","response":"Q: What is 2+2?\nA: 4\nT: Math","done":true}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(server.URL, "llama3.2")
+	text, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Make flashcards", JSON: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if text != "Q: What is 2+2?\nA: 4\nT: Math" {
+		t.Errorf("Unexpected response text %q", text)
+	}
+
+	if got.Model != "llama3.2" || got.Prompt != "Make flashcards" || got.Stream {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if got.Format != "json" {
+		t.Errorf("Expected json format, got %q", got.Format)
+	}
+	if got.System == "" {
+		t.Error("Expected the llama prompt tweak as the system prompt")
+	}
+	if provider.Model() != "ollama/llama3.2" {
+		t.Errorf("Expected model ollama/llama3.2, got %s", provider.Model())
+	}
+}
+
+func TestOllamaProvider_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaGenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("Expected a streaming request")
+		}
+
+		for _, chunk := range []string{"Q: What is ", "2+2?\nA: 4", "\nT: Math\n\n"} {
+			line, _ := json.Marshal(ollamaGenerateResponse{Response: chunk})
+			fmt.Fprintf(w, "%s\n", line)
+		}
+		fmt.Fprint(w, `{"response":"","done":true}`+"\n")
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(server.URL, "mistral")
+	var chunks []string
+	err := provider.GenerateStream(context.Background(), GenerateRequest{Prompt: "Make flashcards"}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Join(chunks, "") != "Q: What is 2+2?\nA: 4\nT: Math\n\n" {
+		t.Errorf("Unexpected chunks %q", chunks)
+	}
+}
+
+func TestOllamaProvider_GenerateStream_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"response":"Q: What","done":false}`+"\n")
+		fmt.Fprint(w, `{"error":"model runner crashed"}`+"\n")
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(server.URL, "llama3.2")
+	err := provider.GenerateStream(context.Background(), GenerateRequest{Prompt: "Make flashcards"}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "model runner crashed") {
+		t.Errorf("Expected stream error, got %v", err)
+	}
+}
+
+func TestOllamaProvider_PrepareModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		pull       bool
+		wantErr    bool
+		wantPulled bool
+	}{
+		{name: "Untagged name matches latest", model: "llama3.2"},
+		{name: "Tagged name", model: "qwen2.5:7b"},
+		{name: "Missing model", model: "phi3", wantErr: true},
+		{name: "Missing model is pulled", model: "phi3", pull: true, wantPulled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulled := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/tags":
+					fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest","model":"llama3.2:latest"},{"name":"qwen2.5:7b","model":"qwen2.5:7b"}]}`)
+				case "/api/pull":
+					var req struct {
+						Model string `json:"model"`
+					}
+					json.NewDecoder(r.Body).Decode(&req)
+					pulled = req.Model
+					fmt.Fprint(w, `{"status":"success"}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			err := NewOllamaProvider(server.URL, tt.model).PrepareModel(context.Background(), tt.pull)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+			if tt.wantPulled && pulled != tt.model {
+				t.Errorf("Expected %s to be pulled, got %q", tt.model, pulled)
+			}
+			if !tt.wantPulled && pulled != "" {
+				t.Errorf("Expected no pull, got %q", pulled)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_CheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest"}]}`)
+	}))
+
+	service := NewFlashcardService(NewPDFService(), NewOllamaProvider(server.URL, "llama3.2"))
+	if err := service.CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected healthy model, got: %v", err)
+	}
+
+	server.Close()
+	if err := service.CheckHealth(context.Background()); err == nil {
+		t.Error("Expected error when Ollama is unreachable")
+	}
+
+	// Providers that can't be probed are assumed healthy
+	if err := newTestFlashcardService(t, NewPDFService()).CheckHealth(context.Background()); err != nil {
+		t.Errorf("Expected no error for a provider without a health check, got: %v", err)
+	}
+}
+
+func TestOllamaSystemPrompt(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected bool
+	}{
+		{"llama3.2", true},
+		{"library/Mistral:7b", true},
+		{"gemma2:2b", true},
+		{"phi3", true},
+		{"deepseek-r1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ollamaSystemPrompt(tt.model) != ""; got != tt.expected {
+				t.Errorf("Expected tweak %v for %s, got %v", tt.expected, tt.model, got)
+			}
+		})
+	}
+
+	// Requests carry a JSON schema, so the tweaks must not describe any
+	// other format, and the language option decides the language
+	for _, tweak := range ollamaPromptTweaks {
+		if !strings.Contains(tweak.system, "JSON") || strings.Contains(tweak.system, "blank line") {
+			t.Errorf("Expected the %s tweak to ask for JSON only, got %q", tweak.prefix, tweak.system)
+		}
+		if strings.Contains(strings.ToLower(tweak.system), "language") {
+			t.Errorf("Expected the %s tweak to leave the language to the prompt, got %q", tweak.prefix, tweak.system)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com"
+
+// OpenAIProvider generates text through an OpenAI-compatible
+// /v1/chat/completions endpoint, such as OpenAI itself, vLLM, LiteLLM or
+// the llama.cpp server.
+type OpenAIProvider struct {
+	endpoint   string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates a provider for model served at baseURL, with or
+// without the trailing /v1. apiKey may be empty for gateways that don't
+// need one.
+func NewOpenAIProvider(baseURL, apiKey, model string) (*OpenAIProvider, error) {
+	if model == "" {
+		return nil, errors.New("an OpenAI-compatible provider needs a model")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+
+	endpoint := strings.TrimRight(baseURL, "/")
+	if !strings.HasSuffix(endpoint, "/v1") {
+		endpoint += "/v1"
+	}
+
+	return &OpenAIProvider{
+		endpoint: endpoint + "/chat/completions",
+		apiKey:   apiKey,
+		model:    model,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *chatJSONSchema `json:"json_schema,omitempty"`
+}
+
+type chatJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type chatCompletionRequest struct {
+	Model          string              `json:"model"`
+	Messages       []chatMessage       `json:"messages"`
+	Stream         bool                `json:"stream,omitempty"`
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+type chatErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	resp, err := p.post(ctx, req, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var completion chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("decoding chat completion: %w", err)
+	}
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no response generated from %s", p.Model())
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// GenerateStream reads the server-sent events of a streamed completion
+// until the "[DONE]" event.
+func (p *OpenAIProvider) GenerateStream(ctx context.Context, req GenerateRequest, onChunk func(string)) error {
+	resp, err := p.post(ctx, req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decoding chat completion chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				onChunk(choice.Delta.Content)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *OpenAIProvider) Model() string {
+	return ProviderOpenAI + "/" + p.model
+}
+
+func (p *OpenAIProvider) post(ctx context.Context, req GenerateRequest, stream bool) (*http.Response, error) {
+	body := chatCompletionRequest{
+		Model:    p.model,
+		Messages: []chatMessage{{Role: "user", Content: req.Prompt}},
+		Stream:   stream,
+	}
+	switch {
+	case req.Schema != nil:
+		body.ResponseFormat = &chatResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &chatJSONSchema{Name: "response", Schema: req.Schema},
+		}
+	case req.JSON:
+		body.ResponseFormat = &chatResponseFormat{Type: "json_object"}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var apiErr chatErrorResponse
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("chat completion failed with status %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completion failed with status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
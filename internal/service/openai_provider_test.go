@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestOpenAIProvider_Generate(t *testing.T) {
+	var got chatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected path /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"Q: What is 2+2?\nA: 4\nT: Math"}}]}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(server.URL, "test-key", "llama-3")
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	text, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Make flashcards", JSON: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if text != "Q: What is 2+2?\nA: 4\nT: Math" {
+		t.Errorf("Unexpected response text %q", text)
+	}
+
+	if got.Model != "llama-3" || got.Stream {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content != "Make flashcards" {
+		t.Errorf("Unexpected messages %+v", got.Messages)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_object" {
+		t.Errorf("Expected JSON response format, got %+v", got.ResponseFormat)
+	}
+
+	if provider.Model() != "openai/llama-3" {
+		t.Errorf("Expected model openai/llama-3, got %s", provider.Model())
+	}
+}
+
+func TestOpenAIProvider_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("Expected a streaming request")
+		}
+		if req.ResponseFormat != nil {
+			t.Errorf("Expected no response format, got %+v", req.ResponseFormat)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Q: What is ", "2+2?\nA: 4", "\nT: Math\n\n"} {
+			delta, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%s}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, ": keep-alive\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	// A base URL that already ends in /v1 is used as is
+	provider, err := NewOpenAIProvider(server.URL+"/v1/", "", "llama-3")
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	var chunks []string
+	err = provider.GenerateStream(context.Background(), GenerateRequest{Prompt: "Make flashcards"}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Join(chunks, "") != "Q: What is 2+2?\nA: 4\nT: Math\n\n" {
+		t.Errorf("Unexpected chunks %q", chunks)
+	}
+}
+
+func TestOpenAIProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "API error message",
+			status:  http.StatusUnauthorized,
+			body:    `{"error":{"message":"invalid api key","type":"invalid_request_error"}}`,
+			wantErr: "invalid api key",
+		},
+		{
+			name:    "Plain error",
+			status:  http.StatusBadGateway,
+			body:    "upstream unavailable",
+			wantErr: "status 502",
+		},
+		{
+			name:    "No choices",
+			status:  http.StatusOK,
+			body:    `{"choices":[]}`,
+			wantErr: "no response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			provider, _ := NewOpenAIProvider(server.URL, "test-key", "llama-3")
+			_, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Make flashcards"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_OpenAIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Q: What is ATP?\nA: Energy currency\nT: Biology\n\nQ: What is DNA?\nA: Genetic material\nT: Biology"}}]}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewLLMProvider(context.Background(), ProviderConfig{Provider: "openai", BaseURL: server.URL, Model: "llama-3"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	set, err := service.GenerateFromText(context.Background(), "Cells store energy in ATP and information in DNA.", models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if set.TotalCards != 2 || set.Flashcards[1].Question != "What is DNA?" {
+		t.Errorf("Unexpected flashcards %+v", set.Flashcards)
+	}
+}
+
+func TestOpenAIProvider_Generate_Schema(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{}"}}]}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(server.URL, "", "llama-3")
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if _, err := provider.Generate(context.Background(), GenerateRequest{Prompt: "Make flashcards", Schema: flashcardResponseSchema}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	format, _ := got["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" {
+		t.Fatalf("Expected a json_schema response format, got %v", got["response_format"])
+	}
+	jsonSchema, _ := format["json_schema"].(map[string]interface{})
+	schema, _ := jsonSchema["schema"].(map[string]interface{})
+	if schema["type"] != "object" || schema["properties"] == nil {
+		t.Errorf("Expected the flashcard schema, got %v", jsonSchema)
+	}
+}
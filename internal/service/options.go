@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
+)
+
+// MaxCardCount is the largest deck a caller can ask for.
+const MaxCardCount = maxDeckSize
+
+// difficultyGuidance explains each difficulty level to the model.
+var difficultyGuidance = map[string]string{
+	models.DifficultyEasy:   "easy (recall of basic facts and definitions)",
+	models.DifficultyMedium: "medium (understanding and connecting concepts)",
+	models.DifficultyHard:   "hard (application, analysis and edge cases)",
+}
+
+// ValidateOptions normalizes opts in place, trimming text and dropping
+// empty topics, and returns an InvalidParams error for values that can't
+// be used.
+func ValidateOptions(opts *models.GenerationOptions) error {
+	opts.Difficulty = strings.ToLower(strings.TrimSpace(opts.Difficulty))
+	opts.Audience = strings.TrimSpace(opts.Audience)
+	opts.Language = strings.TrimSpace(opts.Language)
+	opts.FocusTopics = cleanTopics(opts.FocusTopics)
+	opts.ExcludeTopics = cleanTopics(opts.ExcludeTopics)
+	opts.CardTypes = cleanTopics(opts.CardTypes)
+	for i, cardType := range opts.CardTypes {
+		opts.CardTypes[i] = strings.ToLower(cardType)
+	}
+
+	var problem string
+	switch {
+	case opts.CardCount < 0 || opts.CardCount > MaxCardCount:
+		problem = fmt.Sprintf("cardCount must be between 1 and %d", MaxCardCount)
+	case opts.Difficulty != "" && difficultyGuidance[opts.Difficulty] == "":
+		problem = fmt.Sprintf("difficulty must be %s, %s or %s", models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard)
+	default:
+		for _, cardType := range opts.CardTypes {
+			if !isCardType(cardType) {
+				problem = fmt.Sprintf("unknown card type %q, expected one of %s", cardType, strings.Join(models.CardTypes, ", "))
+				break
+			}
+		}
+	}
+	if problem != "" {
+		return apperrors.NewAppError(
+			models.InvalidParams,
+			"invalid generation options: "+problem,
+			nil,
+		)
+	}
+	return nil
+}
+
+func cleanTopics(topics []string) []string {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		if topic == "" || seen[strings.ToLower(topic)] {
+			continue
+		}
+		seen[strings.ToLower(topic)] = true
+		cleaned = append(cleaned, topic)
+	}
+	return cleaned
+}
+
+// cardCountText is the number of cards a prompt asks for: the requested
+// count, or a range scaled to a document of tokens tokens.
+func cardCountText(tokens int, opts models.GenerationOptions) string {
+	if opts.CardCount > 0 {
+		return fmt.Sprintf("exactly %d", opts.CardCount)
+	}
+	return cardRange(deckSize(tokens))
+}
+
+// optionsPrompt renders every option except the card count as prompt
+// requirements, followed by the formats of any card types requested. It
+// returns "" when there are none.
+func optionsPrompt(opts models.GenerationOptions) string {
+	var lines []string
+	if opts.Difficulty != "" {
+		lines = append(lines, "- Difficulty: "+difficultyGuidance[opts.Difficulty])
+	}
+	if opts.Audience != "" {
+		lines = append(lines, "- Audience: write for "+opts.Audience)
+	}
+	if opts.Language != "" {
+		lines = append(lines, fmt.Sprintf("- Write every question, answer and topic in %s", opts.Language))
+	}
+	if len(opts.FocusTopics) > 0 {
+		lines = append(lines, "- Focus on these topics, with at least one card each: "+strings.Join(opts.FocusTopics, ", "))
+	}
+	if len(opts.ExcludeTopics) > 0 {
+		lines = append(lines, "- Leave out anything about these topics: "+strings.Join(opts.ExcludeTopics, ", "))
+	}
+	types := cardTypesPrompt(opts.CardTypes)
+
+	var builder strings.Builder
+	if len(lines) > 0 {
+		builder.WriteString("Requirements:\n" + strings.Join(lines, "\n") + "\n\n")
+	}
+	if types != "" {
+		builder.WriteString(types + "\n")
+	}
+	return builder.String()
+}
+
+// applyOptions removes cards about excluded topics and any cards past the
+// requested count, then returns the constraints the remaining cards still
+// don't meet. Difficulty, audience and language can't be checked and are
+// left to the prompt.
+func applyOptions(cards []models.Flashcard, opts models.GenerationOptions) ([]models.Flashcard, []string) {
+	var kept []models.Flashcard
+	for _, card := range cards {
+		if opts.CardCount > 0 && len(kept) == opts.CardCount {
+			break
+		}
+		if mentionsAny(card, opts.ExcludeTopics) {
+			continue
+		}
+		kept = append(kept, card)
+	}
+
+	var unmet []string
+	if opts.CardCount > 0 && len(kept) < opts.CardCount {
+		unmet = append(unmet, fmt.Sprintf("requested %d cards, generated %d", opts.CardCount, len(kept)))
+	}
+	for _, topic := range opts.FocusTopics {
+		covered := false
+		for _, card := range kept {
+			if mentions(card, topic) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			unmet = append(unmet, fmt.Sprintf("no card covers focus topic %q", topic))
+		}
+	}
+	for _, cardType := range opts.CardTypes {
+		found := false
+		for _, card := range kept {
+			if card.CardType() == cardType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmet = append(unmet, fmt.Sprintf("no cards of type %q", cardType))
+		}
+	}
+	return kept, unmet
+}
+
+// filterProgress drops card events for cards applyOptions will remove, so
+// streamed cards match the final deck.
+func filterProgress(progress ProgressFunc, opts models.GenerationOptions) ProgressFunc {
+	if progress == nil || (opts.CardCount == 0 && len(opts.ExcludeTopics) == 0) {
+		return progress
+	}
+
+	reported := 0
+	return func(p Progress) {
+		if p.Stage == StageCard {
+			if mentionsAny(*p.Card, opts.ExcludeTopics) || (opts.CardCount > 0 && reported == opts.CardCount) {
+				return
+			}
+			reported++
+		}
+		progress(p)
+	}
+}
+
+func mentionsAny(card models.Flashcard, topics []string) bool {
+	for _, topic := range topics {
+		if mentions(card, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether topic appears in the card's topic, question or
+// answer, ignoring case.
+func mentions(card models.Flashcard, topic string) bool {
+	topic = strings.ToLower(topic)
+	for _, field := range []string{card.Topic, card.Question, card.Answer} {
+		if strings.Contains(strings.ToLower(field), topic) {
+			return true
+		}
+	}
+	return false
+}
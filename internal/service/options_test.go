@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    models.GenerationOptions
+		wantErr string
+	}{
+		{"Empty", models.GenerationOptions{}, ""},
+		{"Valid", models.GenerationOptions{CardCount: 20, Difficulty: " Medium ", Language: "Yoruba"}, ""},
+		{"Negative count", models.GenerationOptions{CardCount: -1}, "cardCount"},
+		{"Count too large", models.GenerationOptions{CardCount: MaxCardCount + 1}, "cardCount"},
+		{"Unknown difficulty", models.GenerationOptions{Difficulty: "expert"}, "difficulty"},
+		{"Card types", models.GenerationOptions{CardTypes: []string{"MCQ", "cloze"}}, ""},
+		{"Unknown card type", models.GenerationOptions{CardTypes: []string{"essay"}}, "unknown card type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(&tt.opts)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateOptions_Normalizes(t *testing.T) {
+	opts := models.GenerationOptions{
+		Difficulty:  " HARD",
+		Audience:    " medical residents ",
+		FocusTopics: []string{"Krebs cycle", " ", "krebs cycle", " ATP "},
+	}
+	if err := ValidateOptions(&opts); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if opts.Difficulty != models.DifficultyHard {
+		t.Errorf("Expected difficulty %q, got %q", models.DifficultyHard, opts.Difficulty)
+	}
+	if opts.Audience != "medical residents" {
+		t.Errorf("Expected trimmed audience, got %q", opts.Audience)
+	}
+	if strings.Join(opts.FocusTopics, "|") != "Krebs cycle|ATP" {
+		t.Errorf("Expected cleaned focus topics, got %q", opts.FocusTopics)
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	cards := []models.Flashcard{
+		{Question: "What is glycolysis?", Answer: "Splitting glucose", Topic: "Metabolism"},
+		{Question: "Where is DNA stored?", Answer: "In the nucleus", Topic: "Genetics"},
+		{Question: "What does ATP store?", Answer: "Energy", Topic: "Metabolism"},
+	}
+
+	tests := []struct {
+		name      string
+		opts      models.GenerationOptions
+		wantCards int
+		wantUnmet []string
+	}{
+		{"No options", models.GenerationOptions{}, 3, nil},
+		{"Trim to count", models.GenerationOptions{CardCount: 2}, 2, nil},
+		{"Too few cards", models.GenerationOptions{CardCount: 5}, 3, []string{"requested 5 cards, generated 3"}},
+		{"Exclude topic", models.GenerationOptions{ExcludeTopics: []string{"genetics"}}, 2, nil},
+		{"Focus covered", models.GenerationOptions{FocusTopics: []string{"glucose"}}, 3, nil},
+		{"Focus missed", models.GenerationOptions{FocusTopics: []string{"Mitosis"}}, 3, []string{`no card covers focus topic "Mitosis"`}},
+		{
+			name:      "Exclusion leaves too few",
+			opts:      models.GenerationOptions{CardCount: 3, ExcludeTopics: []string{"DNA"}},
+			wantCards: 2,
+			wantUnmet: []string{"requested 3 cards, generated 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unmet := applyOptions(cards, tt.opts)
+			if len(got) != tt.wantCards {
+				t.Errorf("Expected %d cards, got %d", tt.wantCards, len(got))
+			}
+			if strings.Join(unmet, "|") != strings.Join(tt.wantUnmet, "|") {
+				t.Errorf("Expected unmet %q, got %q", tt.wantUnmet, unmet)
+			}
+		})
+	}
+}
+
+func TestFlashcardService_GenerateFromText_Options(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: `{"flashcards": [
+  {"question": "What is glycolysis?", "answer": "Splitting glucose", "topic": "Metabolism"},
+  {"question": "Where is DNA stored?", "answer": "In the nucleus", "topic": "Genetics"},
+  {"question": "What does ATP store?", "answer": "Energy", "topic": "Metabolism"}
+]}`})
+	service := NewFlashcardService(NewPDFService(), provider)
+	opts := models.GenerationOptions{
+		CardCount:     2,
+		Audience:      "high school students",
+		ExcludeTopics: []string{"Genetics"},
+	}
+
+	var streamed []string
+	progress := func(p Progress) {
+		if p.Stage == StageCard {
+			streamed = append(streamed, p.Card.Question)
+		}
+	}
+
+	set, err := service.GenerateFromText(context.Background(), "Cell biology notes", opts, progress)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := strings.Join(questions(set.Flashcards), "|"); got != "What is glycolysis?|What does ATP store?" {
+		t.Errorf("Expected the genetics card removed, got %q", got)
+	}
+	if strings.Join(streamed, "|") != strings.Join(questions(set.Flashcards), "|") {
+		t.Errorf("Expected the streamed cards to match the deck, got %q", streamed)
+	}
+	if set.Options == nil || set.Options.CardCount != 2 || len(set.UnmetConstraints) != 0 {
+		t.Errorf("Expected the options and no unmet constraints, got %+v %v", set.Options, set.UnmetConstraints)
+	}
+
+	prompt := provider.Requests()[0].Prompt
+	for _, want := range []string{"exactly 2 high-quality flashcards", "Audience: write for high school students", "Leave out anything about these topics: Genetics"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestFlashcardService_GenerateFromText_InvalidOptions(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	_, err := service.GenerateFromText(context.Background(), "notes", models.GenerationOptions{Difficulty: "expert"}, nil)
+	if err == nil {
+		t.Fatal("Expected an error for an unknown difficulty")
+	}
+	if len(provider.Requests()) != 0 {
+		t.Error("Expected no model request for invalid options")
+	}
+}
@@ -2,34 +2,108 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"time"
 
 	"github.com/ledongthuc/pdf"
 	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
 	apperrors "github.com/tobey0x/lagbaja/pkg/errors"
 )
 
+// DefaultMaxPDFSize is the largest PDF accepted unless MaxSize is changed.
+const DefaultMaxPDFSize = 10 * 1024 * 1024
+
+// PageBreak separates the text of consecutive pages in extracted text.
+const PageBreak = "\f"
+
 type PDFService struct {
 	httpClient *http.Client
+
+	// MaxSize is the largest PDF, in bytes, that is downloaded or validated.
+	MaxSize int64
+
+	// DownloadTimeout bounds each download, including redirects and
+	// reading the body. Zero leaves it to the caller's context.
+	DownloadTimeout time.Duration
+
+	// AllowedNetworks are private networks that downloads may reach
+	// anyway, such as a document server on the same network. All other
+	// loopback, private and link-local addresses are refused so that
+	// user-supplied URLs can't probe internal services.
+	AllowedNetworks []netip.Prefix
 }
 
 func NewPDFService() *PDFService {
-	return &PDFService{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	s := &PDFService{
+		MaxSize:         DefaultMaxPDFSize,
+		DownloadTimeout: DefaultDownloadTimeout,
 	}
+	s.httpClient = s.newDownloadClient()
+	return s
 }
 
-func (s *PDFService) DownloadPDF(url string) ([]byte, error) {
-	log.Printf("Downloading PDF from URL: %s", url)
+// DownloadPDF fetches the PDF at rawURL, following at most MaxRedirects
+// redirects. URLs that resolve to private addresses, responses that aren't
+// PDFs and bodies over MaxSize are refused.
+func (s *PDFService) DownloadPDF(ctx context.Context, rawURL string) ([]byte, error) {
+	log.Printf("Downloading PDF from URL: %s", rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		err = netguard.CheckURL(u)
+	}
+	if err != nil {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			"Invalid PDF URL",
+			err,
+		)
+	}
+
+	if s.DownloadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.DownloadTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			"Invalid PDF URL",
+			err,
+		)
+	}
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
+		switch {
+		case errors.Is(err, netguard.ErrPrivateAddress):
+			return nil, apperrors.NewAppError(
+				models.InvalidParams,
+				"PDF URL must not point to a private network address",
+				err,
+			)
+		case errors.Is(err, netguard.ErrTooManyRedirects):
+			return nil, apperrors.NewAppError(
+				models.InvalidParams,
+				"PDF URL redirects too many times",
+				err,
+			)
+		case errors.Is(err, netguard.ErrUnsupportedScheme):
+			return nil, apperrors.NewAppError(
+				models.InvalidParams,
+				"PDF URL redirects to an unsupported URL",
+				err,
+			)
+		}
 		return nil, apperrors.NewAppError(
 			models.InternalError,
 			"Failed to download PDF",
@@ -45,8 +119,24 @@ func (s *PDFService) DownloadPDF(url string) ([]byte, error) {
 			nil,
 		)
 	}
+	if err := checkContentType(resp); err != nil {
+		return nil, apperrors.NewAppError(
+			models.InvalidParams,
+			"URL does not point to a PDF",
+			err,
+		)
+	}
+	// A declared length over the limit is refused before reading
+	if err := s.CheckSize(resp.ContentLength); err != nil {
+		return nil, err
+	}
 
-	data, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit so oversized bodies can be detected
+	var body io.Reader = resp.Body
+	if s.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, s.MaxSize+1)
+	}
+	data, err := io.ReadAll(body)
 	if err != nil {
 		return nil, apperrors.NewAppError(
 			models.InternalError,
@@ -54,11 +144,21 @@ func (s *PDFService) DownloadPDF(url string) ([]byte, error) {
 			err,
 		)
 	}
+	if err := s.CheckSize(int64(len(data))); err != nil {
+		return nil, err
+	}
 
 	return data, nil
 }
 
 func (s *PDFService) ExtractText(pdfData []byte) (string, error) {
+	return s.ExtractTextWithProgress(context.Background(), pdfData, nil)
+}
+
+// ExtractTextWithProgress extracts text like ExtractText, stopping early if
+// ctx is canceled, and calls onPage after each page is read. onPage may be nil.
+// Pages are separated by PageBreak, including empty ones.
+func (s *PDFService) ExtractTextWithProgress(ctx context.Context, pdfData []byte, onPage func(page, total int)) (string, error) {
 	log.Printf("Extracting text from PDF (%d bytes)", len(pdfData))
 
 	// Create a reader from the PDF data
@@ -78,6 +178,20 @@ func (s *PDFService) ExtractText(pdfData []byte) (string, error) {
 	totalPages := pdfReader.NumPage()
 
 	for pageNum := 1; pageNum <= totalPages; pageNum++ {
+		if err := ctx.Err(); err != nil {
+			return "", apperrors.NewAppError(
+				models.InternalError,
+				"PDF extraction canceled",
+				err,
+			)
+		}
+
+		// Every page gets a break, even an empty one, so page N is always
+		// the Nth piece of the text
+		if pageNum > 1 {
+			textBuilder.WriteString(PageBreak)
+		}
+
 		page := pdfReader.Page(pageNum)
 		if page.V.IsNull() {
 			continue
@@ -93,6 +207,10 @@ func (s *PDFService) ExtractText(pdfData []byte) (string, error) {
 		}
 
 		textBuilder.WriteString(text)
+
+		if onPage != nil {
+			onPage(pageNum, totalPages)
+		}
 	}
 
 	return textBuilder.String(), nil
@@ -116,5 +234,18 @@ func (s *PDFService) ValidatePDF(data []byte) error {
 		)
 	}
 
+	return s.CheckSize(int64(len(data)))
+}
+
+// CheckSize returns an error if a PDF of size bytes exceeds MaxSize. A
+// MaxSize of zero means no limit.
+func (s *PDFService) CheckSize(size int64) error {
+	if s.MaxSize > 0 && size > s.MaxSize {
+		return apperrors.NewAppError(
+			models.InvalidParams,
+			fmt.Sprintf("PDF exceeds the maximum size of %d bytes", s.MaxSize),
+			nil,
+		)
+	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,31 @@
+package service
+
+import "github.com/tobey0x/lagbaja/internal/models"
+
+// Progress stages reported while generating flashcards
+const (
+	StageDownloading = "downloading"
+	StageExtracting  = "extracting"
+	StageGenerating  = "generating"
+	StageCard        = "card"
+)
+
+// Progress describes one step of a generation run. Card is only set for
+// StageCard events, Page and TotalPages only for StageExtracting.
+type Progress struct {
+	Stage      string
+	Message    string
+	Page       int
+	TotalPages int
+	Card       *models.Flashcard
+}
+
+// ProgressFunc receives progress updates. A nil ProgressFunc is valid and
+// discards them.
+type ProgressFunc func(Progress)
+
+func (f ProgressFunc) report(p Progress) {
+	if f != nil {
+		f(p)
+	}
+}
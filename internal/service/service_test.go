@@ -1,15 +1,33 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/tobey0x/lagbaja/internal/models"
 )
 
+// testDeck is the scripted model reply used by newTestFlashcardService.
+const testDeck = "Q: What is photosynthesis?\nA: The conversion of light energy into chemical energy\nT: Biology\n\n" +
+	"Q: Where does photosynthesis take place?\nA: In the chloroplasts\nT: Biology\n\n"
+
+// newTestFlashcardService returns a service backed by a FakeProvider that
+// always replies with testDeck.
+func newTestFlashcardService(t *testing.T, pdfService *PDFService) *FlashcardService {
+	t.Helper()
+	return NewFlashcardService(pdfService, NewFakeProvider(FakeResponse{Text: testDeck}))
+}
+
 func TestFlashcardService_ExtractPDFURL(t *testing.T) {
 	pdfService := NewPDFService()
-	service := NewFlashcardService(pdfService, "test-api-key")
+	service := newTestFlashcardService(t, pdfService)
 
 	tests := []struct {
 		name     string
@@ -55,7 +73,7 @@ func TestFlashcardService_ExtractPDFURL(t *testing.T) {
 
 func TestFlashcardService_GenerateTitle(t *testing.T) {
 	pdfService := NewPDFService()
-	service := NewFlashcardService(pdfService, "test-api-key")
+	service := newTestFlashcardService(t, pdfService)
 
 	tests := []struct {
 		name     string
@@ -96,7 +114,7 @@ func TestFlashcardService_GenerateTitle(t *testing.T) {
 
 func TestFlashcardService_Truncate(t *testing.T) {
 	pdfService := NewPDFService()
-	service := NewFlashcardService(pdfService, "test-api-key")
+	service := newTestFlashcardService(t, pdfService)
 
 	tests := []struct {
 		name     string
@@ -142,7 +160,7 @@ func TestFlashcardService_Truncate(t *testing.T) {
 
 func TestFlashcardService_FormatAsText(t *testing.T) {
 	pdfService := NewPDFService()
-	service := NewFlashcardService(pdfService, "test-api-key")
+	service := newTestFlashcardService(t, pdfService)
 
 	flashcardSet := &models.FlashcardSet{
 		Title:      "Test Flashcards",
@@ -232,3 +250,250 @@ func TestPDFService_ValidatePDF(t *testing.T) {
 		})
 	}
 }
+
+func TestCardStreamParser(t *testing.T) {
+	chunks := []string{
+		"Q: What is 2+2?\nA: 4\nT: Ma",
+		"th\n\nQ: What is the capital",
+		" of France?\nA: Paris\n",
+		"T: Geography\n\nSome trailing chatter",
+		"\n\nQ: Unanswered question?",
+	}
+
+	var streamed []models.Flashcard
+	parser := newCardStreamParser(func(card models.Flashcard) {
+		streamed = append(streamed, card)
+	})
+
+	for i, chunk := range chunks {
+		parser.Write(chunk)
+		if i == 0 && len(streamed) != 0 {
+			t.Fatal("Expected no card before its block is complete")
+		}
+	}
+	cards := parser.Flush()
+
+	if len(cards) != 2 {
+		t.Fatalf("Expected 2 cards, got %d: %+v", len(cards), cards)
+	}
+	if len(streamed) != len(cards) {
+		t.Errorf("Expected %d streamed cards, got %d", len(cards), len(streamed))
+	}
+	if cards[0].Topic != "Math" {
+		t.Errorf("Expected topic %q, got %q", "Math", cards[0].Topic)
+	}
+	if cards[1].Question != "What is the capital of France?" || cards[1].Answer != "Paris" {
+		t.Errorf("Unexpected second card: %+v", cards[1])
+	}
+}
+
+func TestPDFService_DownloadPDF_Canceled(t *testing.T) {
+	service := NewPDFService()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := service.DownloadPDF(ctx, "http://127.0.0.1:1/document.pdf")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPDFService_DownloadPDF_TooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(append([]byte("%PDF-1.4"), bytes.Repeat([]byte("x"), 100)...))
+	}))
+	defer server.Close()
+
+	service := NewPDFService()
+	service.AllowedNetworks = loopback
+	service.MaxSize = 64
+
+	if _, err := service.DownloadPDF(context.Background(), server.URL+"/big.pdf"); err == nil {
+		t.Error("Expected error for PDF over the size limit")
+	}
+
+	service.MaxSize = 1024
+	data, err := service.DownloadPDF(context.Background(), server.URL+"/big.pdf")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(data) != 108 {
+		t.Errorf("Expected 108 bytes, got %d", len(data))
+	}
+}
+
+func TestFlashcardService_GenerateFromFiles_InvalidFile(t *testing.T) {
+	service := newTestFlashcardService(t, NewPDFService())
+
+	files := []PDFFile{
+		{Name: "bad.pdf", Data: []byte("not a pdf")},
+		{Name: "other.pdf", Data: []byte("not a pdf either")},
+	}
+	_, err := service.GenerateFromFiles(context.Background(), files, models.GenerationOptions{}, nil)
+	if err == nil {
+		t.Fatal("Expected error for invalid file")
+	}
+	if !strings.Contains(err.Error(), "bad.pdf") {
+		t.Errorf("Expected error to name the failing file, got: %v", err)
+	}
+
+	if _, err := service.GenerateFromFiles(context.Background(), nil, models.GenerationOptions{}, nil); err == nil {
+		t.Error("Expected error for no files")
+	}
+}
+
+func TestParseFlashcardBlock_Source(t *testing.T) {
+	card, ok := parseFlashcardBlock("Q: What is ATP?\nA: The cell's energy currency\nT: Biology\nS: cells.pdf")
+	if !ok {
+		t.Fatal("Expected block to parse")
+	}
+	if card.Source != "cells.pdf" {
+		t.Errorf("Expected source %q, got %q", "cells.pdf", card.Source)
+	}
+}
+
+func TestNewLLMProvider(t *testing.T) {
+	provider, err := NewLLMProvider(context.Background(), ProviderConfig{Provider: "gemini", APIKey: "test-api-key"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if provider.Model() != "gemini/"+DefaultGeminiModel {
+		t.Errorf("Expected model %q, got %q", "gemini/"+DefaultGeminiModel, provider.Model())
+	}
+
+	provider, err = NewLLMProvider(context.Background(), ProviderConfig{Provider: "Gemini", Model: "gemini-1.5-pro", APIKey: "test-api-key"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if provider.Model() != "gemini/gemini-1.5-pro" {
+		t.Errorf("Expected model %q, got %q", "gemini/gemini-1.5-pro", provider.Model())
+	}
+
+	_, err = NewLLMProvider(context.Background(), ProviderConfig{Provider: "gemini"})
+	if err == nil || !strings.Contains(err.Error(), "API key") {
+		t.Errorf("Expected an error for gemini provider without an API key, got %v", err)
+	}
+
+	if _, err := NewLLMProvider(context.Background(), ProviderConfig{Provider: "openai"}); err == nil {
+		t.Error("Expected error for openai provider without a model")
+	}
+
+	if _, err := NewLLMProvider(context.Background(), ProviderConfig{Provider: "unknown"}); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
+
+func TestFakeProvider(t *testing.T) {
+	injected := errors.New("quota exceeded")
+	provider := NewFakeProvider(
+		FakeResponse{Text: "first"},
+		FakeResponse{Chunks: []string{"sec", "ond"}},
+		FakeResponse{Err: injected},
+	)
+	ctx := context.Background()
+
+	if text, _ := provider.Generate(ctx, GenerateRequest{Prompt: "1"}); text != "first" {
+		t.Errorf("Expected %q, got %q", "first", text)
+	}
+
+	var chunks []string
+	provider.GenerateStream(ctx, GenerateRequest{Prompt: "2"}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if strings.Join(chunks, "|") != "sec|ond" {
+		t.Errorf("Expected chunks sec|ond, got %q", chunks)
+	}
+
+	// The last response repeats once the script runs out
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Generate(ctx, GenerateRequest{Prompt: "3"}); !errors.Is(err, injected) {
+			t.Errorf("Expected injected error, got %v", err)
+		}
+	}
+
+	if requests := provider.Requests(); len(requests) != 4 || requests[1].Prompt != "2" {
+		t.Errorf("Unexpected recorded requests %+v", requests)
+	}
+}
+
+func TestFakeProvider_LatencyHonorsContext(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: "slow", Latency: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := provider.Generate(ctx, GenerateRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestFlashcardService_RefineFlashcards_KeepsDeck(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	conversation := &models.Conversation{
+		ContextID: "ctx-001",
+		Deck: &models.FlashcardSet{
+			Title:  "Photosynthesis",
+			DeckID: "deck-001",
+			Flashcards: []models.Flashcard{
+				{ID: "card-001", Question: "What is photosynthesis?", Answer: "Making sugar from light", Topic: "Biology"},
+			},
+			TotalCards: 1,
+		},
+	}
+
+	set, err := service.RefineFlashcards(context.Background(), conversation, "Add one more", models.GenerationOptions{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if set.DeckID != "deck-001" {
+		t.Errorf("Expected the refined set to keep deck ID %q, got %q", "deck-001", set.DeckID)
+	}
+	if set.Flashcards[0].ID != "card-001" {
+		t.Errorf("Expected the kept card to keep its ID, got %q", set.Flashcards[0].ID)
+	}
+	if set.Flashcards[1].ID != "" {
+		t.Errorf("Expected the new card to have no ID yet, got %q", set.Flashcards[1].ID)
+	}
+	if strings.Contains(provider.Requests()[0].Prompt, "card-001") {
+		t.Error("Expected card IDs to be left out of the prompt")
+	}
+}
+
+func TestFlashcardService_RefineFlashcards_CapsEarlierTurns(t *testing.T) {
+	provider := NewFakeProvider(FakeResponse{Text: testDeck})
+	service := NewFlashcardService(NewPDFService(), provider)
+
+	conversation := &models.Conversation{
+		ContextID: "ctx-001",
+		Deck: &models.FlashcardSet{
+			Flashcards: []models.Flashcard{
+				{Question: "What is photosynthesis?", Answer: "Making sugar from light", Topic: "Biology"},
+			},
+			TotalCards: 1,
+		},
+	}
+	for i := 1; i <= maxRefineTurns+5; i++ {
+		conversation.History = append(conversation.History,
+			models.Message{Role: models.RoleUser, Parts: []models.MessagePart{{Kind: models.KindText, Text: fmt.Sprintf("request #%d.", i)}}},
+			models.Message{Role: models.RoleAgent, Parts: []models.MessagePart{{Kind: models.KindText, Text: fmt.Sprintf("reply #%d.", i)}}},
+		)
+	}
+
+	if _, err := service.RefineFlashcards(context.Background(), conversation, "Add one more", models.GenerationOptions{}, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	prompt := provider.Requests()[0].Prompt
+	for i := 1; i <= maxRefineTurns+5; i++ {
+		request := fmt.Sprintf("request #%d.", i)
+		if want := i > 5; strings.Contains(prompt, request) != want {
+			t.Errorf("Expected %q in the prompt: %v", request, want)
+		}
+		if reply := fmt.Sprintf("reply #%d.", i); strings.Contains(prompt, reply) {
+			t.Errorf("Expected agent reply %q to be left out of the prompt", reply)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// ErrConversationNotFound is returned when no conversation exists for a context ID.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// maxConversationHistory bounds the messages kept per conversation.
+const maxConversationHistory = 50
+
+// ConversationStore keeps multi-turn conversation state keyed by context ID.
+// Callers check a conversation's Owner before continuing it.
+type ConversationStore interface {
+	Save(conversation *models.Conversation) error
+	Get(contextID string) (*models.Conversation, error)
+}
+
+// MemoryConversationStore is the default ConversationStore.
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[string]*models.Conversation
+}
+
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[string]*models.Conversation),
+	}
+}
+
+// Save stores the conversation, keeping only its most recent messages.
+func (s *MemoryConversationStore) Save(conversation *models.Conversation) error {
+	if conversation == nil || conversation.ContextID == "" {
+		return errors.New("conversation must have a context ID")
+	}
+
+	clone := *conversation
+	clone.History = append([]models.Message(nil), conversation.History...)
+	if len(clone.History) > maxConversationHistory {
+		clone.History = clone.History[len(clone.History)-maxConversationHistory:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversations[conversation.ContextID] = &clone
+	return nil
+}
+
+func (s *MemoryConversationStore) Get(contextID string) (*models.Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, ok := s.conversations[contextID]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+
+	clone := *conversation
+	clone.History = append([]models.Message(nil), conversation.History...)
+	return &clone, nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestMemoryConversationStore_SaveAndGet(t *testing.T) {
+	conversations := NewMemoryConversationStore()
+
+	if _, err := conversations.Get("ctx-001"); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("Expected ErrConversationNotFound, got %v", err)
+	}
+
+	conversation := &models.Conversation{
+		ContextID: "ctx-001",
+		Owner:     "alice",
+		Deck:      &models.FlashcardSet{Title: "Deck", TotalCards: 1},
+	}
+	for i := 0; i < maxConversationHistory+10; i++ {
+		conversation.History = append(conversation.History, models.Message{MessageID: fmt.Sprintf("msg-%d", i)})
+	}
+	if err := conversations.Save(conversation); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	got, err := conversations.Get("ctx-001")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(got.History) != maxConversationHistory {
+		t.Errorf("Expected history capped at %d, got %d", maxConversationHistory, len(got.History))
+	}
+	if got.History[len(got.History)-1].MessageID != fmt.Sprintf("msg-%d", maxConversationHistory+9) {
+		t.Errorf("Expected most recent messages to be kept, last is %s", got.History[len(got.History)-1].MessageID)
+	}
+	if got.Owner != "alice" {
+		t.Errorf("Expected owner alice, got %q", got.Owner)
+	}
+	if got.Deck == nil || got.Deck.Title != "Deck" {
+		t.Errorf("Expected deck to be stored, got %+v", got.Deck)
+	}
+
+	if err := conversations.Save(&models.Conversation{}); err == nil {
+		t.Error("Expected error when saving a conversation without a context ID")
+	}
+}
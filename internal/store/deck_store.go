@@ -0,0 +1,202 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// ErrDeckNotFound is returned when no deck exists for the requested ID.
+var ErrDeckNotFound = errors.New("deck not found")
+
+// ErrCardNotFound is returned when none of a user's decks has the card.
+var ErrCardNotFound = errors.New("card not found")
+
+// DeckStore is the library of saved flashcard sets.
+type DeckStore interface {
+	// Create saves a deck made with NewDeck.
+	Create(deck *models.Deck) error
+	Get(id string) (*models.Deck, error)
+	// List summarizes the owner's decks, newest first.
+	List(owner string) ([]models.DeckSummary, error)
+	// Update replaces a deck's set, giving new cards IDs and bumping
+	// UpdatedAt.
+	Update(deck *models.Deck) error
+	Delete(id string) error
+}
+
+// NewDeck makes a deck of set for owner with a new ID. It records the ID
+// on set and gives every card without an ID one, so the caller's set
+// matches the saved deck.
+func NewDeck(owner string, set *models.FlashcardSet) *models.Deck {
+	now := time.Now().UTC().Format(time.RFC3339)
+	set.DeckID = uuid.New().String()
+	assignCardIDs(set)
+
+	deck := &models.Deck{
+		ID:        set.DeckID,
+		Owner:     owner,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Set:       *set,
+	}
+	// Whether the set came from the cache only matters to the response
+	// that returned it
+	deck.Set.Cached = false
+	return deck
+}
+
+// SaveSet saves set in decks for owner and records the deck ID on it. A
+// set that already has a deck ID, such as a refined one, replaces that
+// deck if owner owns it and is saved as a new deck otherwise.
+func SaveSet(decks DeckStore, owner string, set *models.FlashcardSet) error {
+	if set.DeckID != "" {
+		deck, err := decks.Get(set.DeckID)
+		if err != nil && !errors.Is(err, ErrDeckNotFound) {
+			return err
+		}
+		if err == nil && deck.Owner == owner {
+			deck.Set = *set
+			if err := decks.Update(deck); err != nil {
+				return err
+			}
+			*set = deck.Set
+			return nil
+		}
+	}
+	return decks.Create(NewDeck(owner, set))
+}
+
+// FindCard returns the owner's deck holding the card with cardID and the
+// card's index in it.
+func FindCard(decks DeckStore, owner, cardID string) (*models.Deck, int, error) {
+	summaries, err := decks.List(owner)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, summary := range summaries {
+		deck, err := decks.Get(summary.ID)
+		if errors.Is(err, ErrDeckNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if i := deck.Card(cardID); i >= 0 {
+			return deck, i, nil
+		}
+	}
+	return nil, 0, ErrCardNotFound
+}
+
+// assignCardIDs gives every card without an ID one and recounts the set.
+func assignCardIDs(set *models.FlashcardSet) {
+	for i := range set.Flashcards {
+		if set.Flashcards[i].ID == "" {
+			set.Flashcards[i].ID = uuid.New().String()
+		}
+	}
+	set.TotalCards = len(set.Flashcards)
+}
+
+// touch prepares deck to replace the stored version.
+func touch(deck *models.Deck) {
+	deck.Set.DeckID = deck.ID
+	assignCardIDs(&deck.Set)
+	deck.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// MemoryDeckStore keeps decks for the lifetime of the process. It is the
+// default DeckStore; SQLiteDeckStore persists them.
+type MemoryDeckStore struct {
+	mu    sync.RWMutex
+	decks map[string]*models.Deck
+	order []string
+}
+
+func NewMemoryDeckStore() *MemoryDeckStore {
+	return &MemoryDeckStore{
+		decks: make(map[string]*models.Deck),
+	}
+}
+
+func (s *MemoryDeckStore) Create(deck *models.Deck) error {
+	if deck == nil || deck.ID == "" {
+		return errors.New("deck must have an ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.decks[deck.ID]; ok {
+		return errors.New("deck already exists")
+	}
+	s.decks[deck.ID] = cloneDeck(deck)
+	s.order = append(s.order, deck.ID)
+	return nil
+}
+
+func (s *MemoryDeckStore) Get(id string) (*models.Deck, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deck, ok := s.decks[id]
+	if !ok {
+		return nil, ErrDeckNotFound
+	}
+	return cloneDeck(deck), nil
+}
+
+func (s *MemoryDeckStore) List(owner string) ([]models.DeckSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summaries := []models.DeckSummary{}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if deck := s.decks[s.order[i]]; deck.Owner == owner {
+			summaries = append(summaries, deck.Summary())
+		}
+	}
+	return summaries, nil
+}
+
+func (s *MemoryDeckStore) Update(deck *models.Deck) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.decks[deck.ID]; !ok {
+		return ErrDeckNotFound
+	}
+	touch(deck)
+	s.decks[deck.ID] = cloneDeck(deck)
+	return nil
+}
+
+func (s *MemoryDeckStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.decks[id]; !ok {
+		return ErrDeckNotFound
+	}
+	delete(s.decks, id)
+	for i, ordered := range s.order {
+		if ordered == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// cloneDeck deep copies deck, whose cards hold pointers and slices, so
+// callers can't mutate stored state after Create, Get or Update returns.
+func cloneDeck(deck *models.Deck) *models.Deck {
+	data, _ := json.Marshal(deck)
+	var clone models.Deck
+	json.Unmarshal(data, &clone)
+	return &clone
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// deckStores returns each DeckStore implementation, empty.
+func deckStores(t *testing.T) map[string]DeckStore {
+	t.Helper()
+
+	sqlite, err := NewSQLiteDeckStore(filepath.Join(t.TempDir(), "decks.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	return map[string]DeckStore{
+		"memory": NewMemoryDeckStore(),
+		"sqlite": sqlite,
+	}
+}
+
+func testDeckSet(title string) *models.FlashcardSet {
+	isTrue := false
+	return &models.FlashcardSet{
+		Title: title,
+		Flashcards: []models.Flashcard{
+			{Question: "Q1", Answer: "A1", Topic: "Topic1"},
+			{Type: models.CardTypeTrueFalse, Question: "Q2", Answer: "False", IsTrue: &isTrue},
+		},
+		TotalCards: 2,
+	}
+}
+
+func TestNewDeck(t *testing.T) {
+	set := testDeckSet("Biology")
+	deck := NewDeck("alice", set)
+
+	if deck.ID == "" || set.DeckID != deck.ID {
+		t.Errorf("Expected the deck ID on the set, got %q and %q", deck.ID, set.DeckID)
+	}
+	if deck.Owner != "alice" || deck.CreatedAt == "" || deck.UpdatedAt != deck.CreatedAt {
+		t.Errorf("Expected owner and timestamps, got %+v", deck)
+	}
+	for i, card := range set.Flashcards {
+		if card.ID == "" || deck.Set.Flashcards[i].ID != card.ID {
+			t.Errorf("Expected card %d to get an ID on the set and deck, got %q and %q", i, card.ID, deck.Set.Flashcards[i].ID)
+		}
+	}
+	if set.Flashcards[0].ID == set.Flashcards[1].ID {
+		t.Error("Expected card IDs to be unique")
+	}
+
+	cached := testDeckSet("Biology")
+	cached.Cached = true
+	if deck := NewDeck("alice", cached); deck.Set.Cached || !cached.Cached {
+		t.Error("Expected the saved deck not to be marked as cached")
+	}
+}
+
+func TestDeckStore_CRUD(t *testing.T) {
+	for name, decks := range deckStores(t) {
+		t.Run(name, func(t *testing.T) {
+			deck := NewDeck("alice", testDeckSet("Biology"))
+			if err := decks.Create(deck); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			// Mutating the caller's copy must not leak into the store
+			deck.Set.Flashcards[0].Answer = "changed"
+			*deck.Set.Flashcards[1].IsTrue = true
+
+			got, err := decks.Get(deck.ID)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if got.Owner != "alice" || got.Set.Title != "Biology" || got.Set.DeckID != deck.ID {
+				t.Errorf("Expected the saved deck, got %+v", got)
+			}
+			if got.Set.Flashcards[0].Answer != "A1" || *got.Set.Flashcards[1].IsTrue {
+				t.Errorf("Expected stored cards to be unchanged, got %+v", got.Set.Flashcards)
+			}
+
+			got.Set.Flashcards[0].Answer = "A1 edited"
+			got.Set.Flashcards = append(got.Set.Flashcards, models.Flashcard{Question: "Q3", Answer: "A3"})
+			got.UpdatedAt = ""
+			if err := decks.Update(got); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if got.UpdatedAt == "" || got.Set.Flashcards[2].ID == "" || got.Set.TotalCards != 3 {
+				t.Errorf("Expected update to set UpdatedAt, card IDs and the count, got %+v", got)
+			}
+
+			updated, _ := decks.Get(deck.ID)
+			if updated.Set.Flashcards[0].Answer != "A1 edited" || updated.Set.TotalCards != 3 {
+				t.Errorf("Expected the updated deck, got %+v", updated.Set)
+			}
+			if updated.CreatedAt != deck.CreatedAt {
+				t.Errorf("Expected CreatedAt %q to be kept, got %q", deck.CreatedAt, updated.CreatedAt)
+			}
+
+			if err := decks.Delete(deck.ID); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if _, err := decks.Get(deck.ID); !errors.Is(err, ErrDeckNotFound) {
+				t.Errorf("Expected ErrDeckNotFound after delete, got %v", err)
+			}
+			if err := decks.Delete(deck.ID); !errors.Is(err, ErrDeckNotFound) {
+				t.Errorf("Expected ErrDeckNotFound deleting twice, got %v", err)
+			}
+			if err := decks.Update(deck); !errors.Is(err, ErrDeckNotFound) {
+				t.Errorf("Expected ErrDeckNotFound updating a deleted deck, got %v", err)
+			}
+			if err := decks.Create(&models.Deck{}); err == nil {
+				t.Error("Expected error when creating a deck without an ID")
+			}
+		})
+	}
+}
+
+func TestDeckStore_List(t *testing.T) {
+	for name, decks := range deckStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var ids []string
+			for _, title := range []string{"First", "Second", "Third"} {
+				deck := NewDeck("alice", testDeckSet(title))
+				decks.Create(deck)
+				ids = append(ids, deck.ID)
+			}
+			decks.Create(NewDeck("bob", testDeckSet("Bob's")))
+
+			summaries, err := decks.List("alice")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(summaries) != 3 {
+				t.Fatalf("Expected 3 decks, got %d", len(summaries))
+			}
+			for i, want := range []string{"Third", "Second", "First"} {
+				if summaries[i].Title != want {
+					t.Errorf("Expected %q, got %q", want, summaries[i].Title)
+				}
+			}
+			if summaries[0].ID != ids[2] || summaries[0].TotalCards != 2 || summaries[0].Owner != "alice" {
+				t.Errorf("Expected the newest deck's summary, got %+v", summaries[0])
+			}
+
+			if summaries, _ := decks.List("carol"); summaries == nil || len(summaries) != 0 {
+				t.Errorf("Expected an empty list, got %#v", summaries)
+			}
+		})
+	}
+}
+
+func TestSQLiteDeckStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decks.db")
+
+	decks, err := NewSQLiteDeckStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck := NewDeck("alice", testDeckSet("Biology"))
+	decks.Create(deck)
+	decks.Close()
+
+	reopened, err := NewSQLiteDeckStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	got, err := reopened.Get(deck.ID)
+	if err != nil {
+		t.Fatalf("Expected the deck after reopening, got: %v", err)
+	}
+	if got.Set.Flashcards[1].ID != deck.Set.Flashcards[1].ID {
+		t.Errorf("Expected card IDs to persist, got %+v", got.Set.Flashcards)
+	}
+}
+
+func TestFindCard(t *testing.T) {
+	decks := NewMemoryDeckStore()
+	first := NewDeck("alice", testDeckSet("Biology"))
+	second := NewDeck("alice", testDeckSet("Chemistry"))
+	decks.Create(first)
+	decks.Create(second)
+
+	cardID := second.Set.Flashcards[1].ID
+	deck, index, err := FindCard(decks, "alice", cardID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if deck.ID != second.ID || index != 1 {
+		t.Errorf("Expected card 1 of deck %s, got card %d of deck %s", second.ID, index, deck.ID)
+	}
+
+	for _, owner := range []string{"bob", ""} {
+		if _, _, err := FindCard(decks, owner, cardID); !errors.Is(err, ErrCardNotFound) {
+			t.Errorf("Expected ErrCardNotFound for %q, got %v", owner, err)
+		}
+	}
+	if _, _, err := FindCard(decks, "alice", "missing"); !errors.Is(err, ErrCardNotFound) {
+		t.Errorf("Expected ErrCardNotFound, got %v", err)
+	}
+}
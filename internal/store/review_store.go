@@ -0,0 +1,69 @@
+package store
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// ErrReviewStateNotFound is returned for a card the user hasn't reviewed.
+var ErrReviewStateNotFound = errors.New("review state not found")
+
+// ReviewStore keeps each user's review state for each card.
+type ReviewStore interface {
+	Get(owner, cardID string) (*models.ReviewState, error)
+	// List returns the owner's states keyed by card ID.
+	List(owner string) (map[string]*models.ReviewState, error)
+	Save(state *models.ReviewState) error
+}
+
+// MemoryReviewStore keeps review states for the lifetime of the process.
+// It is the default ReviewStore; SQLiteReviewStore persists them.
+type MemoryReviewStore struct {
+	mu     sync.RWMutex
+	states map[string]map[string]models.ReviewState
+}
+
+func NewMemoryReviewStore() *MemoryReviewStore {
+	return &MemoryReviewStore{
+		states: make(map[string]map[string]models.ReviewState),
+	}
+}
+
+func (s *MemoryReviewStore) Get(owner, cardID string) (*models.ReviewState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.states[owner][cardID]
+	if !ok {
+		return nil, ErrReviewStateNotFound
+	}
+	return &state, nil
+}
+
+func (s *MemoryReviewStore) List(owner string) (map[string]*models.ReviewState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make(map[string]*models.ReviewState, len(s.states[owner]))
+	for cardID, state := range s.states[owner] {
+		state := state
+		states[cardID] = &state
+	}
+	return states, nil
+}
+
+func (s *MemoryReviewStore) Save(state *models.ReviewState) error {
+	if state == nil || state.CardID == "" {
+		return errors.New("review state must have a card ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states[state.Owner] == nil {
+		s.states[state.Owner] = make(map[string]models.ReviewState)
+	}
+	s.states[state.Owner][state.CardID] = *state
+	return nil
+}
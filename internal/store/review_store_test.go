@@ -0,0 +1,132 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// reviewStores returns each ReviewStore implementation, empty.
+func reviewStores(t *testing.T) map[string]ReviewStore {
+	t.Helper()
+
+	sqlite, err := NewSQLiteReviewStore(filepath.Join(t.TempDir(), "reviews.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	return map[string]ReviewStore{
+		"memory": NewMemoryReviewStore(),
+		"sqlite": sqlite,
+	}
+}
+
+func testReviewState(owner, cardID string) *models.ReviewState {
+	review := time.Date(2025, 1, 6, 9, 30, 0, 123, time.UTC)
+	return &models.ReviewState{
+		Owner:      owner,
+		CardID:     cardID,
+		DeckID:     "deck-1",
+		Scheduler:  "fsrs",
+		Stability:  3.7145,
+		Difficulty: 5.1618,
+		Interval:   4,
+		Reps:       1,
+		LastGrade:  3,
+		LastReview: review,
+		Due:        review.AddDate(0, 0, 4),
+	}
+}
+
+func TestReviewStore_SaveAndGet(t *testing.T) {
+	for name, states := range reviewStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := states.Get("alice", "card-1"); !errors.Is(err, ErrReviewStateNotFound) {
+				t.Errorf("Expected ErrReviewStateNotFound, got %v", err)
+			}
+
+			state := testReviewState("alice", "card-1")
+			if err := states.Save(state); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			got, err := states.Get("alice", "card-1")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got.Stability != state.Stability || got.Interval != 4 || !got.Due.Equal(state.Due) || !got.LastReview.Equal(state.LastReview) {
+				t.Errorf("Expected %+v, got %+v", state, got)
+			}
+
+			// Saving again replaces the state
+			state.Reps, state.Lapses = 2, 1
+			states.Save(state)
+			got, _ = states.Get("alice", "card-1")
+			if got.Reps != 2 || got.Lapses != 1 {
+				t.Errorf("Expected the saved state to be replaced, got %+v", got)
+			}
+
+			if _, err := states.Get("bob", "card-1"); !errors.Is(err, ErrReviewStateNotFound) {
+				t.Errorf("Expected bob to have no state for alice's card, got %v", err)
+			}
+			if err := states.Save(&models.ReviewState{Owner: "alice"}); err == nil {
+				t.Error("Expected an error saving a state without a card ID")
+			}
+		})
+	}
+}
+
+func TestReviewStore_List(t *testing.T) {
+	for name, states := range reviewStores(t) {
+		t.Run(name, func(t *testing.T) {
+			states.Save(testReviewState("alice", "card-1"))
+			states.Save(testReviewState("alice", "card-2"))
+			states.Save(testReviewState("bob", "card-3"))
+
+			list, err := states.List("alice")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(list) != 2 || list["card-1"] == nil || list["card-2"].CardID != "card-2" {
+				t.Errorf("Expected alice's two states, got %+v", list)
+			}
+
+			list, _ = states.List("carol")
+			if len(list) != 0 {
+				t.Errorf("Expected no states for carol, got %+v", list)
+			}
+		})
+	}
+}
+
+func TestSQLiteReviewStore_SharesDeckDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lagbaja.db")
+
+	decks, err := NewSQLiteDeckStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer decks.Close()
+	states, err := NewSQLiteReviewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states.Save(testReviewState("alice", "card-1"))
+	states.Close()
+
+	if err := decks.Create(NewDeck("alice", testDeckSet("Biology"))); err != nil {
+		t.Errorf("Expected the deck store to keep working, got: %v", err)
+	}
+
+	reopened, err := NewSQLiteReviewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.Get("alice", "card-1"); err != nil {
+		t.Errorf("Expected the state after reopening, got: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSQLite opens the SQLite database at path and creates the tables in
+// schema if they don't exist. Stores may share a file, each with its own
+// connection; writers wait for each other rather than failing.
+func openSQLite(path, schema string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
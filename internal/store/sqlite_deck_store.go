@@ -0,0 +1,135 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// deckSchema stores each set as JSON next to the columns decks are
+// listed by.
+const deckSchema = `
+CREATE TABLE IF NOT EXISTS decks (
+	id          TEXT PRIMARY KEY,
+	owner       TEXT NOT NULL,
+	title       TEXT NOT NULL,
+	total_cards INTEGER NOT NULL,
+	created_at  TEXT NOT NULL,
+	updated_at  TEXT NOT NULL,
+	set_json    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS decks_owner ON decks (owner, created_at);
+`
+
+// SQLiteDeckStore persists decks in an SQLite database file.
+type SQLiteDeckStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteDeckStore opens the database at path, creating it if needed.
+func NewSQLiteDeckStore(path string) (*SQLiteDeckStore, error) {
+	db, err := openSQLite(path, deckSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open deck database: %w", err)
+	}
+	return &SQLiteDeckStore{db: db}, nil
+}
+
+func (s *SQLiteDeckStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteDeckStore) Create(deck *models.Deck) error {
+	if deck == nil || deck.ID == "" {
+		return errors.New("deck must have an ID")
+	}
+
+	data, err := json.Marshal(deck.Set)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO decks (id, owner, title, total_cards, created_at, updated_at, set_json) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		deck.ID, deck.Owner, deck.Set.Title, deck.Set.TotalCards, deck.CreatedAt, deck.UpdatedAt, string(data),
+	)
+	return err
+}
+
+func (s *SQLiteDeckStore) Get(id string) (*models.Deck, error) {
+	deck := models.Deck{ID: id}
+	var data string
+	err := s.db.QueryRow(`SELECT owner, created_at, updated_at, set_json FROM decks WHERE id = ?`, id).
+		Scan(&deck.Owner, &deck.CreatedAt, &deck.UpdatedAt, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeckNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &deck.Set); err != nil {
+		return nil, fmt.Errorf("deck %s is corrupt: %w", id, err)
+	}
+	return &deck, nil
+}
+
+func (s *SQLiteDeckStore) List(owner string) ([]models.DeckSummary, error) {
+	rows, err := s.db.Query(
+		`SELECT id, title, total_cards, created_at, updated_at FROM decks WHERE owner = ? ORDER BY created_at DESC, rowid DESC`,
+		owner,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.DeckSummary{}
+	for rows.Next() {
+		summary := models.DeckSummary{Owner: owner}
+		if err := rows.Scan(&summary.ID, &summary.Title, &summary.TotalCards, &summary.CreatedAt, &summary.UpdatedAt); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+func (s *SQLiteDeckStore) Update(deck *models.Deck) error {
+	touch(deck)
+	data, err := json.Marshal(deck.Set)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(
+		`UPDATE decks SET title = ?, total_cards = ?, updated_at = ?, set_json = ? WHERE id = ?`,
+		deck.Set.Title, deck.Set.TotalCards, deck.UpdatedAt, string(data), deck.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+func (s *SQLiteDeckStore) Delete(id string) error {
+	result, err := s.db.Exec(`DELETE FROM decks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// expectRow returns ErrDeckNotFound if the statement changed no rows.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDeckNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+const reviewSchema = `
+CREATE TABLE IF NOT EXISTS review_states (
+	owner         TEXT NOT NULL,
+	card_id       TEXT NOT NULL,
+	deck_id       TEXT NOT NULL,
+	scheduler     TEXT NOT NULL,
+	ease          REAL NOT NULL,
+	stability     REAL NOT NULL,
+	difficulty    REAL NOT NULL,
+	interval_days INTEGER NOT NULL,
+	reps          INTEGER NOT NULL,
+	lapses        INTEGER NOT NULL,
+	last_grade    INTEGER NOT NULL,
+	last_review   TEXT NOT NULL,
+	due           TEXT NOT NULL,
+	PRIMARY KEY (owner, card_id)
+);
+`
+
+const reviewColumns = `card_id, deck_id, scheduler, ease, stability, difficulty, interval_days, reps, lapses, last_grade, last_review, due`
+
+// SQLiteReviewStore persists review states in an SQLite database file,
+// which can be the deck library's.
+type SQLiteReviewStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteReviewStore opens the database at path, creating it if needed.
+func NewSQLiteReviewStore(path string) (*SQLiteReviewStore, error) {
+	db, err := openSQLite(path, reviewSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open review database: %w", err)
+	}
+	return &SQLiteReviewStore{db: db}, nil
+}
+
+func (s *SQLiteReviewStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteReviewStore) Get(owner, cardID string) (*models.ReviewState, error) {
+	row := s.db.QueryRow(`SELECT `+reviewColumns+` FROM review_states WHERE owner = ? AND card_id = ?`, owner, cardID)
+	state, err := scanReviewState(row, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReviewStateNotFound
+	}
+	return state, err
+}
+
+func (s *SQLiteReviewStore) List(owner string) (map[string]*models.ReviewState, error) {
+	rows, err := s.db.Query(`SELECT `+reviewColumns+` FROM review_states WHERE owner = ?`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]*models.ReviewState)
+	for rows.Next() {
+		state, err := scanReviewState(rows, owner)
+		if err != nil {
+			return nil, err
+		}
+		states[state.CardID] = state
+	}
+	return states, rows.Err()
+}
+
+func (s *SQLiteReviewStore) Save(state *models.ReviewState) error {
+	if state == nil || state.CardID == "" {
+		return errors.New("review state must have a card ID")
+	}
+
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO review_states (owner, `+reviewColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		state.Owner, state.CardID, state.DeckID, state.Scheduler, state.Ease, state.Stability, state.Difficulty,
+		state.Interval, state.Reps, state.Lapses, state.LastGrade,
+		state.LastReview.UTC().Format(time.RFC3339Nano), state.Due.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// scanReviewState reads a row selected with reviewColumns.
+func scanReviewState(row interface{ Scan(...any) error }, owner string) (*models.ReviewState, error) {
+	state := models.ReviewState{Owner: owner}
+	var lastReview, due string
+	err := row.Scan(
+		&state.CardID, &state.DeckID, &state.Scheduler, &state.Ease, &state.Stability, &state.Difficulty,
+		&state.Interval, &state.Reps, &state.Lapses, &state.LastGrade, &lastReview, &due,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.LastReview, err = time.Parse(time.RFC3339Nano, lastReview); err != nil {
+		return nil, fmt.Errorf("review state of card %s is corrupt: %w", state.CardID, err)
+	}
+	if state.Due, err = time.Parse(time.RFC3339Nano, due); err != nil {
+		return nil, fmt.Errorf("review state of card %s is corrupt: %w", state.CardID, err)
+	}
+	return &state, nil
+}
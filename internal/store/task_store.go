@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+// ErrTaskNotFound is returned when no task exists for the requested ID.
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskStore persists A2A tasks keyed by task ID so they can be fetched
+// again after the original response has been written.
+type TaskStore interface {
+	Save(task *models.TaskResult) error
+	Get(id string) (*models.TaskResult, error)
+}
+
+// MemoryTaskStore is the default TaskStore. Tasks live for the lifetime
+// of the process.
+type MemoryTaskStore struct {
+	mu    sync.RWMutex
+	tasks map[string]*models.TaskResult
+}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{
+		tasks: make(map[string]*models.TaskResult),
+	}
+}
+
+func (s *MemoryTaskStore) Save(task *models.TaskResult) error {
+	if task == nil || task.ID == "" {
+		return errors.New("task must have an ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = cloneTask(task)
+	return nil
+}
+
+func (s *MemoryTaskStore) Get(id string) (*models.TaskResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return cloneTask(task), nil
+}
+
+// cloneTask copies the task and its slices so callers can't mutate
+// stored state after Save or Get returns.
+func cloneTask(task *models.TaskResult) *models.TaskResult {
+	clone := *task
+	clone.Artifacts = append([]models.Artifact(nil), task.Artifacts...)
+	clone.History = append([]models.Message(nil), task.History...)
+	return &clone
+}
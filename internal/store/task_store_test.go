@@ -0,0 +1,42 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tobey0x/lagbaja/internal/models"
+)
+
+func TestMemoryTaskStore_SaveAndGet(t *testing.T) {
+	taskStore := NewMemoryTaskStore()
+
+	task := &models.TaskResult{
+		ID:      "task-001",
+		Status:  models.Status{State: models.StateCompleted},
+		History: []models.Message{{MessageID: "msg-001"}},
+		Kind:    "task",
+	}
+	if err := taskStore.Save(task); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Mutating the caller's copy must not leak into the store
+	task.History[0].MessageID = "changed"
+	task.History = append(task.History, models.Message{MessageID: "msg-002"})
+
+	got, err := taskStore.Get("task-001")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(got.History) != 1 || got.History[0].MessageID != "msg-001" {
+		t.Errorf("Expected stored history to be unchanged, got %+v", got.History)
+	}
+
+	if _, err := taskStore.Get("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+
+	if err := taskStore.Save(&models.TaskResult{}); err == nil {
+		t.Error("Expected error when saving a task without an ID")
+	}
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned when the pool can't accept more jobs.
+	ErrQueueFull = errors.New("worker queue is full")
+	// ErrPoolClosed is returned when submitting to a pool that is shutting down.
+	ErrPoolClosed = errors.New("worker pool is closed")
+)
+
+// Pool runs submitted jobs on a fixed number of goroutines.
+type Pool struct {
+	jobs   chan func()
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool starts workers goroutines reading from a queue of queueSize jobs.
+func NewPool(workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Pool{
+		jobs: make(chan func(), queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+func (p *Pool) run() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		p.execute(job)
+	}
+}
+
+func (p *Pool) execute(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Worker job panicked: %v", r)
+		}
+	}()
+	job()
+}
+
+// Submit queues a job without blocking. It returns ErrQueueFull when the
+// queue has no room left.
+func (p *Pool) Submit(job func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for queued ones to finish or for
+// ctx to expire.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_RunsSubmittedJobs(t *testing.T) {
+	pool := NewPool(2, 10)
+
+	var count int32
+	for i := 0; i < 10; i++ {
+		if err := pool.Submit(func() { atomic.AddInt32(&count, 1) }); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got != 10 {
+		t.Errorf("Expected 10 jobs to run, got %d", got)
+	}
+
+	if err := pool.Submit(func() {}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	pool := NewPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	// Occupy the only worker, then fill the queue
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	if err := pool.Submit(func() {}); err != nil {
+		t.Fatalf("Expected queued job to be accepted, got: %v", err)
+	}
+
+	if err := pool.Submit(func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+}
+
+func TestPool_RecoversFromPanics(t *testing.T) {
+	pool := NewPool(1, 2)
+	done := make(chan struct{})
+
+	pool.Submit(func() { panic("boom") })
+	pool.Submit(func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected worker to keep running after a panic")
+	}
+	pool.Shutdown(context.Background())
+}
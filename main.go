@@ -1,20 +1,32 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tobey0x/lagbaja/internal/cache"
 	"github.com/tobey0x/lagbaja/internal/config"
+	"github.com/tobey0x/lagbaja/internal/export"
 	"github.com/tobey0x/lagbaja/internal/handler"
+	"github.com/tobey0x/lagbaja/internal/models"
+	"github.com/tobey0x/lagbaja/internal/netguard"
+	"github.com/tobey0x/lagbaja/internal/push"
+	"github.com/tobey0x/lagbaja/internal/review"
 	"github.com/tobey0x/lagbaja/internal/service"
+	"github.com/tobey0x/lagbaja/internal/store"
+	"github.com/tobey0x/lagbaja/internal/worker"
 )
 
 func main() {
@@ -28,21 +40,75 @@ func main() {
 
 	// Initialize services
 	pdfService := service.NewPDFService()
-	flashcardService := service.NewFlashcardService(pdfService, cfg.APIKey)
+	pdfService.MaxSize = cfg.MaxPDFSize
+	pdfService.DownloadTimeout = cfg.PDFTimeout
+	networks, err := netguard.ParseNetworks(cfg.PDFNetworks)
+	if err != nil {
+		log.Fatalf("Error reading PDF_ALLOWED_NETWORKS: %v", err)
+	}
+	pdfService.AllowedNetworks = networks
+	provider, err := service.NewLLMProvider(context.Background(), service.ProviderConfig{
+		Provider: cfg.LLMProvider,
+		Model:    cfg.LLMModel,
+		APIKey:   cfg.APIKey,
+		BaseURL:  cfg.LLMBaseURL,
+	})
+	if err != nil {
+		log.Fatalf("Error creating LLM provider: %v", err)
+	}
+	log.Printf("Generating flashcards with %s", provider.Model())
+	if ollama, ok := provider.(*service.OllamaProvider); ok {
+		// A missing local model is reported by /health rather than
+		// stopping the server, so Ollama can be started afterwards
+		if err := ollama.PrepareModel(context.Background(), cfg.LLMPullModel); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	flashcardService := service.NewFlashcardService(pdfService, provider)
+	flashcardService.ChunkTokens = cfg.ChunkTokens
+	flashcardService.Concurrency = cfg.Concurrency
+	flashcardService.Cache, err = cache.New(cfg.CacheSize, cfg.CacheDir, cfg.CacheTTL)
+	if err != nil {
+		log.Fatalf("Error creating cache: %v", err)
+	}
 
-	// Initialize handler
-	a2aHandler := handler.NewA2AHandler(flashcardService)
+	// Open the deck library
+	decks, err := store.NewSQLiteDeckStore(cfg.DeckDBPath)
+	if err != nil {
+		log.Fatalf("Error opening deck library: %v", err)
+	}
+	defer decks.Close()
+	reviewStates, err := store.NewSQLiteReviewStore(cfg.DeckDBPath)
+	if err != nil {
+		log.Fatalf("Error opening review states: %v", err)
+	}
+	defer reviewStates.Close()
+	scheduler, err := review.NewScheduler(cfg.ReviewScheduler)
+	if err != nil {
+		log.Fatalf("Error creating review scheduler: %v", err)
+	}
+	reviews := review.NewService(decks, reviewStates, scheduler)
+	reviews.Marker = flashcardService
 
-	// Setup routes
-	mux := http.NewServeMux()
-	mux.Handle("/a2a", a2aHandler)
-	mux.HandleFunc("/health", healthCheckHandler)
-	mux.HandleFunc("/upload", uploadHandler(flashcardService))
+	notifier := push.NewNotifier(push.NewMemoryConfigStore(), cfg.PushMaxAttempts, cfg.PushRetryBackoff)
+	notifier.AllowedNetworks, err = netguard.ParseNetworks(cfg.PushNetworks)
+	if err != nil {
+		log.Fatalf("Error reading PUSH_ALLOWED_NETWORKS: %v", err)
+	}
+
+	// Initialize handler
+	a2aHandler := handler.NewA2AHandler(
+		flashcardService,
+		handler.WithDeckStore(decks),
+		handler.WithReviewService(reviews),
+		handler.WithWorkerPool(worker.NewPool(cfg.WorkerCount, cfg.WorkerQueueSize)),
+		handler.WithNotifier(notifier),
+	)
 
 	// Create server
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      mux,
+		Handler:      newRouter(cfg, flashcardService, a2aHandler, decks, reviews),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -69,16 +135,58 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	if err := a2aHandler.Shutdown(ctx); err != nil {
+		log.Printf("Background tasks did not finish: %v", err)
+	}
+
 	log.Println("Server exited")
 }
 
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"healthy","service":"flashcard-generator"}`))
+// newRouter registers every HTTP endpoint.
+func newRouter(cfg *config.Config, flashcardService *service.FlashcardService, a2aHandler *handler.A2AHandler, decks store.DeckStore, reviews *review.Service) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/a2a", a2aHandler)
+	mux.HandleFunc("/.well-known/agent.json", a2aHandler.AgentCardHandler(handler.AgentInfo{
+		Name:        cfg.AgentName,
+		Description: cfg.AgentDescription,
+		URL:         cfg.AgentURL,
+		Version:     cfg.AgentVersion,
+	}))
+	mux.HandleFunc("/push/deliveries", a2aHandler.PushDeliveriesHandler())
+	mux.HandleFunc("/health", healthCheckHandler(flashcardService))
+	mux.HandleFunc("/upload", uploadHandler(flashcardService, decks))
+	mux.HandleFunc("/export/apkg", apkgExportHandler())
+	handler.NewDeckHandler(decks).Register(mux)
+	handler.NewReviewHandler(reviews).Register(mux)
+	return mux
+}
+
+func healthCheckHandler(flashcardService *service.FlashcardService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		health := map[string]interface{}{
+			"status":         "healthy",
+			"service":        "flashcard-generator",
+			"model":          flashcardService.Model(),
+			"modelReachable": true,
+		}
+		status := http.StatusOK
+		if err := flashcardService.CheckHealth(ctx); err != nil {
+			health["status"] = "degraded"
+			health["modelReachable"] = false
+			health["modelError"] = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(health)
+	}
 }
 
-func uploadHandler(flashcardService *service.FlashcardService) http.HandlerFunc {
+func uploadHandler(flashcardService *service.FlashcardService, decks store.DeckStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
@@ -92,6 +200,17 @@ func uploadHandler(flashcardService *service.FlashcardService) http.HandlerFunc
 			return
 		}
 
+		opts, err := uploadOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format, err := uploadFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Get the file from the form
 		file, header, err := r.FormFile("pdf")
 		if err != nil {
@@ -103,24 +222,169 @@ func uploadHandler(flashcardService *service.FlashcardService) http.HandlerFunc
 		log.Printf("Received uploaded file: %s (%d bytes)", header.Filename, header.Size)
 
 		// Read file content
-		pdfData := make([]byte, header.Size)
-		_, err = file.Read(pdfData)
+		pdfData, err := io.ReadAll(file)
 		if err != nil {
 			http.Error(w, "Failed to read PDF file", http.StatusInternalServerError)
 			return
 		}
 
 		// Generate flashcards from PDF
-		flashcards, err := flashcardService.GenerateFromPDFData(pdfData)
+		flashcards, err := flashcardService.GenerateFromPDFData(r.Context(), pdfData, opts, nil)
 		if err != nil {
 			log.Printf("Error generating flashcards: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to generate flashcards: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		w.Header().Set(cacheHeader, "MISS")
+		if flashcards.Cached {
+			w.Header().Set(cacheHeader, "HIT")
+		}
+
+		// Save to the library; the deck ID is returned in the set and,
+		// for downloads, in a header
+		if err := store.SaveSet(decks, handler.RequestOwner(r), flashcards); err != nil {
+			log.Printf("Error saving deck: %v", err)
+		} else {
+			w.Header().Set(deckIDHeader, flashcards.DeckID)
+		}
+
+		if format != nil {
+			writeExport(w, flashcards, *format)
+			return
+		}
+
 		// Return flashcards as JSON
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(flashcards)
 	}
-}
\ No newline at end of file
+}
+
+// deckIDHeader carries the ID of the deck an upload was saved as.
+const deckIDHeader = "X-Deck-ID"
+
+// cacheHeader reports whether an upload's flashcards were served from the
+// cache (HIT) or generated for it (MISS).
+const cacheHeader = "X-Cache"
+
+// uploadFormat reads the export format requested with ?format=, or nil
+// for the default JSON response. The quizlet format takes termSeparator
+// and cardSeparator, either literally or as one of separatorNames.
+func uploadFormat(r *http.Request) (*export.Format, error) {
+	name := strings.TrimSpace(r.FormValue("format"))
+	if name == "" || strings.EqualFold(name, "json") {
+		return nil, nil
+	}
+	format, ok := export.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("invalid format %q: must be json or one of %s", name, strings.Join(export.Names(), ", "))
+	}
+
+	if format.Name == "quizlet" {
+		term := separator(r.FormValue("termSeparator"), export.DefaultTermSeparator)
+		card := separator(r.FormValue("cardSeparator"), export.DefaultCardSeparator)
+		if term == card {
+			return nil, fmt.Errorf("termSeparator and cardSeparator must differ")
+		}
+		format.Encode = export.QuizletEncoder(term, card)
+	}
+	return &format, nil
+}
+
+// separatorNames are the separators that are awkward to put in a form.
+var separatorNames = map[string]string{
+	"tab":       "\t",
+	"comma":     ",",
+	"semicolon": ";",
+	"newline":   "\n",
+}
+
+func separator(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	if named, ok := separatorNames[strings.ToLower(value)]; ok {
+		return named
+	}
+	return value
+}
+
+// writeExport sends set as a download in format.
+func writeExport(w http.ResponseWriter, set *models.FlashcardSet, format export.Format) {
+	var buf bytes.Buffer
+	if err := format.Encode(&buf, set); err != nil {
+		log.Printf("Error exporting flashcards as %s: %v", format.Name, err)
+		http.Error(w, fmt.Sprintf("Failed to export flashcards as %s", format.Name), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename(set, format.Extension)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// apkgExportHandler converts a FlashcardSet posted as JSON, such as one
+// returned by /upload, into an Anki package download.
+func apkgExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var set models.FlashcardSet
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10<<20)).Decode(&set); err != nil {
+			http.Error(w, "Invalid flashcard set JSON", http.StatusBadRequest)
+			return
+		}
+		if len(set.Flashcards) == 0 {
+			http.Error(w, "Flashcard set has no flashcards", http.StatusBadRequest)
+			return
+		}
+
+		format, _ := export.Lookup("apkg")
+		writeExport(w, &set, format)
+	}
+}
+
+// uploadOptions reads GenerationOptions from the upload form. List fields
+// take comma-separated values and may be repeated.
+func uploadOptions(r *http.Request) (models.GenerationOptions, error) {
+	opts := models.GenerationOptions{
+		Difficulty:    r.FormValue("difficulty"),
+		Audience:      r.FormValue("audience"),
+		Language:      r.FormValue("language"),
+		FocusTopics:   formList(r, "focusTopics"),
+		ExcludeTopics: formList(r, "excludeTopics"),
+		CardTypes:     formList(r, "cardTypes"),
+	}
+	if count := strings.TrimSpace(r.FormValue("cardCount")); count != "" {
+		parsed, err := strconv.Atoi(count)
+		if err != nil {
+			return opts, fmt.Errorf("invalid cardCount %q", count)
+		}
+		opts.CardCount = parsed
+	}
+	if noCache := strings.TrimSpace(r.FormValue("noCache")); noCache != "" {
+		parsed, err := strconv.ParseBool(noCache)
+		if err != nil {
+			return opts, fmt.Errorf("invalid noCache %q", noCache)
+		}
+		opts.NoCache = parsed
+	}
+
+	if err := service.ValidateOptions(&opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func formList(r *http.Request, key string) []string {
+	var list []string
+	for _, value := range r.Form[key] {
+		list = append(list, strings.Split(value, ",")...)
+	}
+	return list
+}